@@ -0,0 +1,388 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "sort"
+    "strings"
+    "text/tabwriter"
+    "time"
+)
+
+// ageBands are the histogram buckets used by the analytics report; a Max of 0 means no upper limit
+var ageBands = []AgeBand{
+    {Label: "18-24", Min: 18, Max: 24},
+    {Label: "25-34", Min: 25, Max: 34},
+    {Label: "35-44", Min: 35, Max: 44},
+    {Label: "45-54", Min: 45, Max: 54},
+    {Label: "55+", Min: 55},
+}
+
+// AgeBand is one bucket of the age histogram
+type AgeBand struct {
+    Label string `json:"label"`
+    Min   int    `json:"min"`
+    Max   int    `json:"max,omitempty"`
+    Count int    `json:"count"`
+}
+
+// PeriodCount is a count for one calendar month
+type PeriodCount struct {
+    Period string `json:"period"`
+    Count  int    `json:"count"`
+}
+
+// DepartmentStats describes the workforce of one department over a reporting period
+type DepartmentStats struct {
+    Department string `json:"department"`
+    // Headcount is the number of employees still employed at the end of the period
+    Headcount  int       `json:"headcount"`
+    AverageAge float64   `json:"average_age"`
+    MedianAge  float64   `json:"median_age"`
+    AgeBands   []AgeBand `json:"age_bands"`
+    NewHires   int       `json:"new_hires"`
+    // HiresByMonth breaks NewHires down by calendar month, including months with none
+    HiresByMonth []PeriodCount `json:"hires_by_month"`
+    Leavers      int           `json:"leavers"`
+    // TransfersIn and TransfersOut count moves between departments during the period. They are
+    // not hires or leavers, and the organisation total has none
+    TransfersIn  int `json:"transfers_in"`
+    TransfersOut int `json:"transfers_out"`
+    // AttritionRate is leavers as a percentage of everyone who was in the department during the
+    // period: the opening headcount plus new hires and transfers in
+    AttritionRate float64 `json:"attrition_rate"`
+
+    openingHeadcount int
+    ages             []int
+}
+
+// AnalyticsReport holds per-department workforce statistics for a period, plus the organisation total
+type AnalyticsReport struct {
+    From        time.Time         `json:"from"`
+    To          time.Time         `json:"to"`
+    Departments []DepartmentStats `json:"departments"`
+    Total       DepartmentStats   `json:"total"`
+}
+
+// DepartmentAnalytics reports headcount, age distribution, hiring and attrition for every
+// department between two dates inclusive. Ages are as currently recorded. With an audit log
+// attached, hires count towards the department the employee joined and the opening and closing
+// headcounts towards the department they were in on each date; leavers count towards the
+// department they left from
+func (em *EmployeeManager) DepartmentAnalytics(from time.Time, to time.Time) (*AnalyticsReport, error) {
+    from, to = dateOnly(from), dateOnly(to)
+    if to.Before(from) {
+        return nil, &ValidationError{Field: "to", Value: to.Format("2006-01-02"), Err: ErrInvalidDateRange}
+    }
+
+    em.mu.RLock()
+    defer em.mu.RUnlock()
+
+    report := &AnalyticsReport{
+        From:        from,
+        To:          to,
+        Departments: make([]DepartmentStats, 0),
+        Total:       newDepartmentStats("ALL", from, to),
+    }
+    stats := make(map[string]*DepartmentStats)
+    for _, dept := range em.departments.List() {
+        s := newDepartmentStats(dept.Name, from, to)
+        stats[dept.Name] = &s
+    }
+    statsFor := func(department string) *DepartmentStats {
+        s, ok := stats[department]
+        if !ok {
+            created := newDepartmentStats(department, from, to)
+            s = &created
+            stats[department] = s
+        }
+        return s
+    }
+
+    timelines, err := em.departmentTimelines()
+    if err != nil {
+        return nil, err
+    }
+    for _, emp := range em.employees {
+        timeline := timelines[emp.ID]
+        timeline.resolve(emp, em.departments)
+        statsFor(timeline.at(from)).addOpening(emp, from)
+        statsFor(timeline.at(to.AddDate(0, 0, 1))).addClosing(emp, to)
+        statsFor(timeline.hired).addHire(emp, from, to)
+        statsFor(emp.Department).addLeaver(emp, from, to)
+        timeline.transfers(from, to.AddDate(0, 0, 1), func(out string, in string) {
+            statsFor(out).TransfersOut++
+            statsFor(in).TransfersIn++
+        })
+        report.Total.add(emp, from, to)
+    }
+
+    retired := make(map[string]bool)
+    for _, dept := range em.departments.List() {
+        retired[dept.Name] = dept.Retired
+    }
+    for _, s := range stats {
+        // Retired departments only matter if something happened in them during the period
+        if retired[s.Department] && s.Headcount == 0 && s.NewHires == 0 && s.Leavers == 0 && s.TransfersOut == 0 {
+            continue
+        }
+        s.finish()
+        report.Departments = append(report.Departments, *s)
+    }
+    report.Total.finish()
+
+    sort.Slice(report.Departments, func(i, j int) bool {
+        return report.Departments[i].Department < report.Departments[j].Department
+    })
+    return report, nil
+}
+
+// analyticsPeriod parses optional YYYY-MM-DD bounds for a report. The period
+// defaults to the 12 months ending today
+func analyticsPeriod(fromValue string, toValue string) (time.Time, time.Time, error) {
+    to := today()
+    if toValue != "" {
+        parsed, err := time.ParseInLocation("2006-01-02", toValue, time.Local)
+        if err != nil {
+            return time.Time{}, time.Time{}, fmt.Errorf("to must be YYYY-MM-DD: %q", toValue)
+        }
+        to = parsed
+    }
+
+    from := to.AddDate(-1, 0, 1)
+    if fromValue != "" {
+        parsed, err := time.ParseInLocation("2006-01-02", fromValue, time.Local)
+        if err != nil {
+            return time.Time{}, time.Time{}, fmt.Errorf("from must be YYYY-MM-DD: %q", fromValue)
+        }
+        from = parsed
+    }
+    return from, to, nil
+}
+
+// WriteText writes the report to w as aligned tables: a summary per department,
+// then the age histogram and the monthly hires
+func (r *AnalyticsReport) WriteText(w io.Writer) error {
+    rows := append(append([]DepartmentStats{}, r.Departments...), r.Total)
+
+    tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+    fmt.Fprintf(tw, "Department analytics %s to %s\n", r.From.Format("2006-01-02"), r.To.Format("2006-01-02"))
+    fmt.Fprintln(tw, "DEPARTMENT\tHEADCOUNT\tAVG AGE\tMEDIAN AGE\tNEW HIRES\tLEAVERS\tTRANSFERS IN\tTRANSFERS OUT\tATTRITION %\t")
+    for _, s := range rows {
+        fmt.Fprintf(tw, "%s\t%d\t%.1f\t%.1f\t%d\t%d\t%d\t%d\t%.1f\t\n",
+            s.Department, s.Headcount, s.AverageAge, s.MedianAge, s.NewHires, s.Leavers, s.TransfersIn, s.TransfersOut, s.AttritionRate)
+    }
+
+    fmt.Fprintln(tw)
+    header := []string{"AGE BAND"}
+    for _, band := range ageBands {
+        header = append(header, band.Label)
+    }
+    fmt.Fprintln(tw, strings.Join(header, "\t")+"\t")
+    for _, s := range rows {
+        fmt.Fprint(tw, s.Department)
+        for _, band := range s.AgeBands {
+            fmt.Fprintf(tw, "\t%d", band.Count)
+        }
+        fmt.Fprintln(tw, "\t")
+    }
+
+    fmt.Fprintln(tw)
+    header = []string{"HIRES BY MONTH"}
+    for _, month := range r.Total.HiresByMonth {
+        header = append(header, month.Period)
+    }
+    fmt.Fprintln(tw, strings.Join(header, "\t")+"\t")
+    for _, s := range rows {
+        fmt.Fprint(tw, s.Department)
+        for _, month := range s.HiresByMonth {
+            fmt.Fprintf(tw, "\t%d", month.Count)
+        }
+        fmt.Fprintln(tw, "\t")
+    }
+    return tw.Flush()
+}
+
+// WriteJSON writes the report to w as indented JSON
+func (r *AnalyticsReport) WriteJSON(w io.Writer) error {
+    encoder := json.NewEncoder(w)
+    encoder.SetIndent("", "  ")
+    return encoder.Encode(r)
+}
+
+// newDepartmentStats creates empty statistics with a histogram bucket for every age band
+// and a monthly hires entry for every month from one date to another
+func newDepartmentStats(department string, from time.Time, to time.Time) DepartmentStats {
+    s := DepartmentStats{
+        Department:   department,
+        AgeBands:     make([]AgeBand, len(ageBands)),
+        HiresByMonth: make([]PeriodCount, 0),
+    }
+    copy(s.AgeBands, ageBands)
+
+    last := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.Local)
+    for month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.Local); !month.After(last); month = month.AddDate(0, 1, 0) {
+        s.HiresByMonth = append(s.HiresByMonth, PeriodCount{Period: month.Format("2006-01")})
+    }
+    return s
+}
+
+// add counts emp towards the statistics for the period from one date to another
+func (s *DepartmentStats) add(emp Employee, from time.Time, to time.Time) {
+    s.addOpening(emp, from)
+    s.addClosing(emp, to)
+    s.addHire(emp, from, to)
+    s.addLeaver(emp, from, to)
+}
+
+// addOpening counts emp towards the opening headcount if they were employed on the first day,
+// including anyone leaving that day
+func (s *DepartmentStats) addOpening(emp Employee, from time.Time) {
+    left := leftOn(emp)
+    if !dateOnly(emp.HireDate).After(from) && (left.IsZero() || !left.Before(from)) {
+        s.openingHeadcount++
+    }
+}
+
+// addClosing counts emp towards the closing headcount and age figures if they were still
+// employed after the last day
+func (s *DepartmentStats) addClosing(emp Employee, to time.Time) {
+    left := leftOn(emp)
+    if !dateOnly(emp.HireDate).After(to) && (left.IsZero() || left.After(to)) {
+        s.Headcount++
+        s.ages = append(s.ages, emp.Age)
+        for i, band := range s.AgeBands {
+            if emp.Age >= band.Min && (band.Max == 0 || emp.Age <= band.Max) {
+                s.AgeBands[i].Count++
+                break
+            }
+        }
+    }
+}
+
+// addHire counts emp as a new hire if they joined during the period
+func (s *DepartmentStats) addHire(emp Employee, from time.Time, to time.Time) {
+    if hired := dateOnly(emp.HireDate); !hired.Before(from) && !hired.After(to) {
+        s.NewHires++
+        period := hired.Format("2006-01")
+        for i := range s.HiresByMonth {
+            if s.HiresByMonth[i].Period == period {
+                s.HiresByMonth[i].Count++
+            }
+        }
+    }
+}
+
+// addLeaver counts emp as a leaver if they left during the period
+func (s *DepartmentStats) addLeaver(emp Employee, from time.Time, to time.Time) {
+    if left := leftOn(emp); !left.IsZero() && !left.Before(from) && !left.After(to) {
+        s.Leavers++
+    }
+}
+
+// leftOn returns the date emp left, or the zero time if they are still employed
+func leftOn(emp Employee) time.Time {
+    if emp.TerminationDate == nil {
+        return time.Time{}
+    }
+    return dateOnly(*emp.TerminationDate)
+}
+
+// finish works out the averages and rates once every employee has been added
+func (s *DepartmentStats) finish() {
+    if len(s.ages) > 0 {
+        sort.Ints(s.ages)
+        total := 0
+        for _, age := range s.ages {
+            total += age
+        }
+        s.AverageAge = float64(total) / float64(len(s.ages))
+        if mid := len(s.ages) / 2; len(s.ages)%2 == 0 {
+            s.MedianAge = float64(s.ages[mid-1]+s.ages[mid]) / 2
+        } else {
+            s.MedianAge = float64(s.ages[mid])
+        }
+    }
+
+    if exposed := s.openingHeadcount + s.NewHires + s.TransfersIn; exposed > 0 {
+        s.AttritionRate = float64(s.Leavers) / float64(exposed) * 100
+    }
+}
+
+// departmentChange is the department an employee's audit history put them in from a moment on
+type departmentChange struct {
+    at         time.Time
+    department string
+}
+
+// departmentTimeline is the departments one employee has been in, replayed from the audit log
+type departmentTimeline struct {
+    // hired is the department the employee joined
+    hired   string
+    changes []departmentChange
+}
+
+// departmentTimelines replays the audit log into a department timeline for every employee
+// in it. Without an audit log every timeline is empty. Callers must hold em.mu
+func (em *EmployeeManager) departmentTimelines() (map[int]departmentTimeline, error) {
+    timelines := make(map[int]departmentTimeline)
+    if em.audit == nil {
+        return timelines, nil
+    }
+    events, err := em.audit.Events()
+    if err != nil {
+        return nil, fmt.Errorf("%w: %w", ErrAudit, err)
+    }
+
+    for _, event := range events {
+        if event.After == nil {
+            continue
+        }
+        timeline := timelines[event.EmployeeID]
+        if event.Action == AUDIT_CREATE || event.Action == AUDIT_BASELINE {
+            timeline.hired = event.After.Department
+        }
+        timeline.changes = append(timeline.changes, departmentChange{at: event.Timestamp, department: event.After.Department})
+        timelines[event.EmployeeID] = timeline
+    }
+    return timelines, nil
+}
+
+// resolve fills in what the audit log does not know from emp's current record. Departments
+// renamed since are not in the registry any more and are taken to be emp's current one
+func (dt *departmentTimeline) resolve(emp Employee, departments *DepartmentRegistry) {
+    if dt.hired == "" || departments.indexOf(dt.hired) < 0 {
+        dt.hired = emp.Department
+    }
+    for i := range dt.changes {
+        if departments.indexOf(dt.changes[i].department) < 0 {
+            dt.changes[i].department = emp.Department
+        }
+    }
+}
+
+// at returns the department the employee was in just before t: the one set by the last change
+// recorded before then, or the one they joined if the log starts later
+func (dt departmentTimeline) at(t time.Time) string {
+    department := dt.hired
+    for _, change := range dt.changes {
+        if !change.at.Before(t) {
+            break
+        }
+        department = change.department
+    }
+    return department
+}
+
+// transfers calls moved with the departments an employee left and joined for every move
+// recorded from one moment up to, but not including, another
+func (dt departmentTimeline) transfers(from time.Time, until time.Time, moved func(out string, in string)) {
+    department := dt.hired
+    for _, change := range dt.changes {
+        if change.department != department && !change.at.Before(from) && change.at.Before(until) {
+            moved(department, change.department)
+        }
+        department = change.department
+    }
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+    "testing"
+    "time"
+)
+
+// departmentRow returns the report's statistics for one department
+func departmentRow(t *testing.T, report *AnalyticsReport, department string) DepartmentStats {
+    t.Helper()
+    for _, s := range report.Departments {
+        if s.Department == department {
+            return s
+        }
+    }
+    t.Fatalf("report has no row for %s", department)
+    return DepartmentStats{}
+}
+
+func TestAnalyticsCountsDepartmentAtEvent(t *testing.T) {
+    em := NewEmployeeManager()
+    if err := em.SetAuditLog(NewMemoryAuditLog()); err != nil {
+        t.Fatal(err)
+    }
+    steps := []error{
+        em.AddEmployee(1, "Vishwa Ghuge", 25, HR_DEPT),
+        em.AddEmployee(2, "Abhishek Bodke", 28, FIN_DEPT),
+        em.TransferDepartment(1, FIN_DEPT),
+        em.RemoveEmployee(2, today()),
+    }
+    for _, err := range steps {
+        if err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    report, err := em.DepartmentAnalytics(today().AddDate(0, -1, 0), today())
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    hr, finance := departmentRow(t, report, HR_DEPT), departmentRow(t, report, FIN_DEPT)
+    if hr.NewHires != 1 || hr.Headcount != 0 {
+        t.Errorf("HR hired %d and holds %d, want the transferred hire counted in HR but not held", hr.NewHires, hr.Headcount)
+    }
+    if finance.NewHires != 1 || finance.Leavers != 1 || finance.Headcount != 1 {
+        t.Errorf("FINANCE hired %d, lost %d and holds %d, want 1 of each", finance.NewHires, finance.Leavers, finance.Headcount)
+    }
+    if hr.TransfersOut != 1 || hr.TransfersIn != 0 || finance.TransfersIn != 1 || finance.TransfersOut != 0 {
+        t.Errorf("HR moved %d in and %d out, FINANCE %d in and %d out; want one transfer from HR to FINANCE",
+            hr.TransfersIn, hr.TransfersOut, finance.TransfersIn, finance.TransfersOut)
+    }
+
+    // FINANCE held a new hire and a transfer, and one of the two left
+    if hr.AttritionRate != 0 || finance.AttritionRate != 50 || report.Total.AttritionRate != 50 {
+        t.Errorf("attrition is %.1f%% in HR, %.1f%% in FINANCE and %.1f%% overall, want 0%%, 50%% and 50%%",
+            hr.AttritionRate, finance.AttritionRate, report.Total.AttritionRate)
+    }
+}
+
+func TestAnalyticsAttritionRate(t *testing.T) {
+    em := NewEmployeeManager()
+    hired := time.Date(2022, time.April, 1, 0, 0, 0, 0, time.Local)
+    for id := 1; id <= 4; id++ {
+        if err := em.AddEmployeeRecord(Employee{ID: id, Name: "Asha Rao", Age: 20 + id, Department: IT_DEPT, HireDate: hired}); err != nil {
+            t.Fatal(err)
+        }
+    }
+    steps := []error{
+        em.AddEmployeeRecord(Employee{ID: 5, Name: "Ravi Nair", Age: 30, Department: IT_DEPT, HireDate: time.Date(2023, time.June, 1, 0, 0, 0, 0, time.Local)}),
+        em.RemoveEmployee(1, time.Date(2023, time.March, 31, 0, 0, 0, 0, time.Local)),
+        em.RemoveEmployee(5, time.Date(2023, time.September, 30, 0, 0, 0, 0, time.Local)),
+    }
+    for _, err := range steps {
+        if err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    tests := []struct {
+        name        string
+        from, to    time.Time
+        wantLeavers int
+        wantRate    float64
+    }{
+        // Four at the start, one hired, two of the five gone
+        {"2023", time.Date(2023, time.January, 1, 0, 0, 0, 0, time.Local), time.Date(2023, time.December, 31, 0, 0, 0, 0, time.Local), 2, 40},
+        // Only the first leaver falls in the first quarter
+        {"first quarter", time.Date(2023, time.January, 1, 0, 0, 0, 0, time.Local), time.Date(2023, time.March, 31, 0, 0, 0, 0, time.Local), 1, 25},
+        {"2024", time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local), time.Date(2024, time.December, 31, 0, 0, 0, 0, time.Local), 0, 0},
+    }
+    for _, tt := range tests {
+        report, err := em.DepartmentAnalytics(tt.from, tt.to)
+        if err != nil {
+            t.Fatal(err)
+        }
+        it := departmentRow(t, report, IT_DEPT)
+        if it.Leavers != tt.wantLeavers || it.AttritionRate != tt.wantRate {
+            t.Errorf("%s: IT lost %d at %.1f%%, want %d at %.1f%%", tt.name, it.Leavers, it.AttritionRate, tt.wantLeavers, tt.wantRate)
+        }
+    }
+}
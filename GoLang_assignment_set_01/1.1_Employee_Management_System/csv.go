@@ -0,0 +1,238 @@
+package main
+
+import (
+    "encoding/csv"
+    "errors"
+    "fmt"
+    "io"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Columns every imported CSV must have
+var csvColumns = []string{"id", "name", "age", "department"}
+
+// Columns an imported CSV may have; export always writes them
+var csvOptionalColumns = []string{"email", "phone", "hire_date", "salary", "title"}
+
+// ImportError records why a single CSV row was rejected
+type ImportError struct {
+    Row int
+    Err error
+}
+
+func (ie ImportError) Error() string {
+    return fmt.Sprintf("row %d: %v", ie.Row, ie.Err)
+}
+
+func (ie ImportError) Unwrap() error {
+    return ie.Err
+}
+
+// ImportReport summarises a CSV import
+type ImportReport struct {
+    Imported int
+    Errors   []ImportError
+}
+
+// importRow is a parsed CSV row waiting to be added to the roster
+type importRow struct {
+    row int
+    emp Employee
+}
+
+// ImportCSV reads employees from r and adds each valid one to the roster.
+// The first row must be a header naming the id, name, age and department columns in any order;
+// email, phone, hire_date (YYYY-MM-DD), salary and title columns are optional.
+// Invalid rows are collected in the report rather than stopping the import; row numbers are
+// the line each record starts on, counting the header as line 1. Every row is read and parsed
+// before the roster is locked, so a slow upload does not hold up other callers; the rows are
+// then checked against the roster and persisted together, so either all of them are saved or none are
+func (em *EmployeeManager) ImportCSV(r io.Reader) (*ImportReport, error) {
+    reader := csv.NewReader(r)
+    reader.FieldsPerRecord = -1
+    reader.TrimLeadingSpace = true
+
+    header, err := reader.Read()
+    if err != nil {
+        return nil, fmt.Errorf("failed to read CSV header: %w", err)
+    }
+
+    columns := make(map[string]int)
+    for i, name := range header {
+        columns[strings.ToLower(strings.TrimSpace(name))] = i
+    }
+    for _, name := range csvColumns {
+        if _, ok := columns[name]; !ok {
+            return nil, fmt.Errorf("CSV header is missing the %s column", name)
+        }
+    }
+
+    report := &ImportReport{}
+    var rows []importRow
+    for {
+        record, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            // A malformed line is reported like any other bad row; anything else is fatal
+            var parseErr *csv.ParseError
+            if errors.As(err, &parseErr) {
+                report.Errors = append(report.Errors, ImportError{Row: parseErr.StartLine, Err: parseErr.Err})
+                continue
+            }
+            return report, err
+        }
+
+        row, _ := reader.FieldPos(0)
+        emp, err := parseImportRecord(record, columns)
+        if err != nil {
+            report.Errors = append(report.Errors, ImportError{Row: row, Err: err})
+            continue
+        }
+        rows = append(rows, importRow{row: row, emp: emp})
+    }
+
+    em.mu.Lock()
+    defer em.mu.Unlock()
+
+    start := len(em.employees)
+    for _, row := range rows {
+        if err := em.stageImport(row.emp); err != nil {
+            if errors.Is(err, ErrAudit) {
+                report.Imported = 0
+                return report, em.unstageImport(start, err)
+            }
+            report.Errors = append(report.Errors, ImportError{Row: row.row, Err: err})
+            continue
+        }
+        report.Imported++
+    }
+    // Rows that failed to parse were reported first, so put the report back in file order
+    sort.SliceStable(report.Errors, func(i, j int) bool {
+        return report.Errors[i].Row < report.Errors[j].Row
+    })
+
+    if report.Imported > 0 {
+        if err := em.save(em.employees, em.departments); err != nil {
+            report.Imported = 0
+            return report, em.unstageImport(start, err)
+        }
+    }
+    return report, nil
+}
+
+// unstageImport removes the employees an import added from position start onwards,
+// recording a ROLLBACK event for each, and returns err. Callers must hold em.mu
+func (em *EmployeeManager) unstageImport(start int, err error) error {
+    for i := len(em.employees) - 1; i >= start; i-- {
+        if rollbackErr := em.recordChange(AUDIT_ROLLBACK, &em.employees[i], nil); rollbackErr != nil {
+            err = errors.Join(err, rollbackErr)
+        }
+        em.index.remove(i, em.employees[i])
+    }
+    em.employees = em.employees[:start]
+    return err
+}
+
+// parseImportRecord turns a single CSV record into an employee. It only checks that the
+// fields parse; the employee is validated against the roster when it is staged
+func parseImportRecord(record []string, columns map[string]int) (Employee, error) {
+    field := func(name string) string {
+        if i, ok := columns[name]; ok && i < len(record) {
+            return strings.TrimSpace(record[i])
+        }
+        return ""
+    }
+
+    id, err := strconv.Atoi(field("id"))
+    if err != nil {
+        return Employee{}, fmt.Errorf("invalid ID: %q", field("id"))
+    }
+    age, err := strconv.Atoi(field("age"))
+    if err != nil {
+        return Employee{}, fmt.Errorf("invalid age: %q", field("age"))
+    }
+
+    emp := Employee{
+        ID:         id,
+        Name:       field("name"),
+        Age:        age,
+        Department: field("department"),
+        Email:      field("email"),
+        Phone:      field("phone"),
+        Title:      field("title"),
+    }
+    if value := field("hire_date"); value != "" {
+        if emp.HireDate, err = time.ParseInLocation("2006-01-02", value, time.Local); err != nil {
+            return Employee{}, fmt.Errorf("invalid hire date: %q", value)
+        }
+    }
+    if value := field("salary"); value != "" {
+        if emp.Salary, err = strconv.ParseFloat(value, 64); err != nil {
+            return Employee{}, fmt.Errorf("invalid salary: %q", value)
+        }
+    }
+    return emp, nil
+}
+
+// stageImport validates an imported employee and adds it to the roster in memory.
+// ImportCSV persists the roster once every row is staged. Callers must hold em.mu
+func (em *EmployeeManager) stageImport(emp Employee) error {
+    if err := em.prepareNewEmployee(&emp); err != nil {
+        return err
+    }
+    if err := em.recordChange(AUDIT_CREATE, nil, &emp); err != nil {
+        return err
+    }
+    em.employees = append(em.employees, emp)
+    em.index.add(len(em.employees)-1, emp)
+    return nil
+}
+
+// ExportCSV writes every active employee to w, or only those in department when it is not empty
+func (em *EmployeeManager) ExportCSV(w io.Writer, department string) error {
+    var employees []*Employee
+    if department == "" {
+        employees = em.ListEmployees(false)
+    } else {
+        // An empty department simply exports the header
+        employees, _ = em.ListByDepartment(department)
+    }
+    return writeEmployeesCSV(w, employees)
+}
+
+// writeEmployeesCSV writes employees to w with the header row ImportCSV expects
+func writeEmployeesCSV(w io.Writer, employees []*Employee) error {
+    writer := csv.NewWriter(w)
+    if err := writer.Write(append(csvColumns, csvOptionalColumns...)); err != nil {
+        return err
+    }
+    for _, emp := range employees {
+        hireDate := ""
+        if !emp.HireDate.IsZero() {
+            hireDate = emp.HireDate.Format("2006-01-02")
+        }
+
+        record := []string{
+            strconv.Itoa(emp.ID),
+            emp.Name,
+            strconv.Itoa(emp.Age),
+            emp.Department,
+            emp.Email,
+            emp.Phone,
+            hireDate,
+            strconv.FormatFloat(emp.Salary, 'f', 2, 64),
+            emp.Title,
+        }
+        if err := writer.Write(record); err != nil {
+            return err
+        }
+    }
+
+    writer.Flush()
+    return writer.Error()
+}
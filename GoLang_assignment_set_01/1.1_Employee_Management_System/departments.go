@@ -0,0 +1,242 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+)
+
+// Department is an organisational unit employees can belong to
+type Department struct {
+    Name    string `json:"name"`
+    Parent  string `json:"parent,omitempty"`
+    Retired bool   `json:"retired,omitempty"`
+}
+
+// DepartmentRegistry holds the departments known to the system
+type DepartmentRegistry struct {
+    departments []Department
+}
+
+// NewDepartmentRegistry creates a registry seeded with the given departments
+func NewDepartmentRegistry(departments []Department) *DepartmentRegistry {
+    dr := &DepartmentRegistry{
+        departments: make([]Department, 0, len(departments)),
+    }
+    dr.departments = append(dr.departments, departments...)
+    return dr
+}
+
+// DefaultDepartments returns the departments a fresh system starts with
+func DefaultDepartments() []Department {
+    return []Department{
+        {Name: HR_DEPT},
+        {Name: IT_DEPT},
+        {Name: FIN_DEPT},
+    }
+}
+
+// normalizeDepartment returns the canonical form of a department name
+func normalizeDepartment(name string) string {
+    return strings.ToUpper(strings.TrimSpace(name))
+}
+
+// Create adds a new department, optionally under an existing active parent
+func (dr *DepartmentRegistry) Create(name string, parent string) error {
+    name = normalizeDepartment(name)
+    parent = normalizeDepartment(parent)
+
+    if name == "" {
+        return errors.New("department name cannot be empty")
+    }
+    if dr.indexOf(name) >= 0 {
+        return fmt.Errorf("department %s already exists", name)
+    }
+    if parent != "" && !dr.IsActive(parent) {
+        return fmt.Errorf("%w: %s", ErrInvalidDepartment, parent)
+    }
+
+    dr.departments = append(dr.departments, Department{Name: name, Parent: parent})
+    return nil
+}
+
+// Rename changes a department's name and re-points its sub-departments at the new name
+func (dr *DepartmentRegistry) Rename(oldName string, newName string) error {
+    oldName = normalizeDepartment(oldName)
+    newName = normalizeDepartment(newName)
+
+    if newName == "" {
+        return errors.New("department name cannot be empty")
+    }
+
+    i := dr.indexOf(oldName)
+    if i < 0 {
+        return fmt.Errorf("department %s %w", oldName, ErrNotFound)
+    }
+    if dr.indexOf(newName) >= 0 {
+        return fmt.Errorf("department %s already exists", newName)
+    }
+
+    dr.departments[i].Name = newName
+    for j := range dr.departments {
+        if dr.departments[j].Parent == oldName {
+            dr.departments[j].Parent = newName
+        }
+    }
+    return nil
+}
+
+// Retire marks a department as no longer accepting employees.
+// A department with active sub-departments cannot be retired
+func (dr *DepartmentRegistry) Retire(name string) error {
+    name = normalizeDepartment(name)
+
+    i := dr.indexOf(name)
+    if i < 0 {
+        return fmt.Errorf("department %s %w", name, ErrNotFound)
+    }
+    if dr.departments[i].Retired {
+        return fmt.Errorf("department %s is already retired", name)
+    }
+    for _, child := range dr.Children(name) {
+        if dr.IsActive(child) {
+            return fmt.Errorf("department %s still has active sub-department %s", name, child)
+        }
+    }
+
+    dr.departments[i].Retired = true
+    return nil
+}
+
+// Get returns the department with the given name
+func (dr *DepartmentRegistry) Get(name string) (Department, error) {
+    name = normalizeDepartment(name)
+
+    i := dr.indexOf(name)
+    if i < 0 {
+        return Department{}, fmt.Errorf("department %s %w", name, ErrNotFound)
+    }
+    return dr.departments[i], nil
+}
+
+// IsActive reports whether name is a known, non-retired department
+func (dr *DepartmentRegistry) IsActive(name string) bool {
+    i := dr.indexOf(normalizeDepartment(name))
+    return i >= 0 && !dr.departments[i].Retired
+}
+
+// Children returns the names of the direct sub-departments of name
+func (dr *DepartmentRegistry) Children(name string) []string {
+    name = normalizeDepartment(name)
+
+    var children []string
+    for _, dept := range dr.departments {
+        if dept.Parent == name {
+            children = append(children, dept.Name)
+        }
+    }
+    return children
+}
+
+// Tree returns name followed by all of its sub-departments, at any depth
+func (dr *DepartmentRegistry) Tree(name string) []string {
+    name = normalizeDepartment(name)
+
+    tree := []string{name}
+    for i := 0; i < len(tree); i++ {
+        tree = append(tree, dr.Children(tree[i])...)
+    }
+    return tree
+}
+
+// List returns a copy of every department, including retired ones
+func (dr *DepartmentRegistry) List() []Department {
+    departments := make([]Department, len(dr.departments))
+    copy(departments, dr.departments)
+    return departments
+}
+
+// clone returns an independent copy of the registry
+func (dr *DepartmentRegistry) clone() *DepartmentRegistry {
+    return NewDepartmentRegistry(dr.departments)
+}
+
+// indexOf returns the slice position of the named department, or -1
+func (dr *DepartmentRegistry) indexOf(name string) int {
+    for i := range dr.departments {
+        if dr.departments[i].Name == name {
+            return i
+        }
+    }
+    return -1
+}
+
+// CreateDepartment registers a new department, optionally under parent
+func (em *EmployeeManager) CreateDepartment(name string, parent string) error {
+    em.mu.Lock()
+    defer em.mu.Unlock()
+
+    departments := em.departments.clone()
+    if err := departments.Create(name, parent); err != nil {
+        return err
+    }
+    return em.replaceDepartments(em.employees, departments)
+}
+
+// RenameDepartment renames a department and moves every employee in it,
+// including terminated ones, to the new name
+func (em *EmployeeManager) RenameDepartment(oldName string, newName string) error {
+    em.mu.Lock()
+    defer em.mu.Unlock()
+
+    oldName = normalizeDepartment(oldName)
+    newName = normalizeDepartment(newName)
+
+    departments := em.departments.clone()
+    if err := departments.Rename(oldName, newName); err != nil {
+        return err
+    }
+
+    employees := make([]Employee, len(em.employees))
+    copy(employees, em.employees)
+    for i := range employees {
+        if employees[i].Department == oldName {
+            employees[i].Department = newName
+        }
+    }
+    return em.replaceDepartments(employees, departments)
+}
+
+// RetireDepartment retires a department that no longer has any active employees
+func (em *EmployeeManager) RetireDepartment(name string) error {
+    em.mu.Lock()
+    defer em.mu.Unlock()
+
+    if count := em.countByDepartments([]string{normalizeDepartment(name)}); count > 0 {
+        return fmt.Errorf("department %s still has %d active employees", normalizeDepartment(name), count)
+    }
+
+    departments := em.departments.clone()
+    if err := departments.Retire(name); err != nil {
+        return err
+    }
+    return em.replaceDepartments(em.employees, departments)
+}
+
+// replaceDepartments persists and then installs a new registry and roster. Callers must hold em.mu
+func (em *EmployeeManager) replaceDepartments(employees []Employee, departments *DepartmentRegistry) error {
+    if err := em.recordRosterChanges(AUDIT_UPDATE, em.employees, employees); err != nil {
+        return err
+    }
+    if err := em.save(employees, departments); err != nil {
+        if rollbackErr := em.recordRosterChanges(AUDIT_ROLLBACK, employees, em.employees); rollbackErr != nil {
+            return errors.Join(err, rollbackErr)
+        }
+        return err
+    }
+
+    em.employees = employees
+    em.index = newEmployeeIndex(employees)
+    em.departments = departments
+    return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+)
+
+// newDepartmentTestRegistry returns the default departments plus IT > SUPPORT > HELPDESK
+func newDepartmentTestRegistry(t *testing.T) *DepartmentRegistry {
+    t.Helper()
+    dr := NewDepartmentRegistry(DefaultDepartments())
+    for _, dept := range []struct{ name, parent string }{{"support", "it"}, {"Helpdesk", "SUPPORT"}} {
+        if err := dr.Create(dept.name, dept.parent); err != nil {
+            t.Fatal(err)
+        }
+    }
+    return dr
+}
+
+func TestDepartmentRegistryCreate(t *testing.T) {
+    tests := []struct {
+        name    string
+        dept    string
+        parent  string
+        wantErr bool
+        wantIs  error
+    }{
+        {name: "new top-level", dept: "sales"},
+        {name: "new child", dept: "Payroll", parent: "finance"},
+        {name: "empty name", dept: "  ", wantErr: true},
+        {name: "duplicate", dept: "hr", wantErr: true},
+        {name: "unknown parent", dept: "Legal", parent: "OPS", wantErr: true, wantIs: ErrInvalidDepartment},
+        // A department cannot be its own parent, as the parent must already exist
+        {name: "own parent", dept: "Ops", parent: "ops", wantErr: true, wantIs: ErrInvalidDepartment},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            dr := newDepartmentTestRegistry(t)
+            err := dr.Create(tt.dept, tt.parent)
+            if (err != nil) != tt.wantErr || (tt.wantIs != nil && !errors.Is(err, tt.wantIs)) {
+                t.Fatalf("Create(%q, %q) = %v, want error %v (%v)", tt.dept, tt.parent, err, tt.wantErr, tt.wantIs)
+            }
+            if !tt.wantErr && !dr.IsActive(tt.dept) {
+                t.Errorf("%s is not active after creating it", tt.dept)
+            }
+        })
+    }
+}
+
+func TestDepartmentRegistryTree(t *testing.T) {
+    dr := newDepartmentTestRegistry(t)
+    if got, want := dr.Tree(IT_DEPT), []string{IT_DEPT, "SUPPORT", "HELPDESK"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("Tree(IT) = %v, want %v", got, want)
+    }
+
+    // Renaming re-points the children, so the tree holds together under the new name
+    if err := dr.Rename("support", "service desk"); err != nil {
+        t.Fatal(err)
+    }
+    if got, want := dr.Tree(IT_DEPT), []string{IT_DEPT, "SERVICE DESK", "HELPDESK"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("Tree(IT) after rename = %v, want %v", got, want)
+    }
+    if err := dr.Rename("helpdesk", "it"); err == nil {
+        t.Error("renaming onto an existing department succeeded")
+    }
+    if err := dr.Rename("ops", "operations"); !errors.Is(err, ErrNotFound) {
+        t.Errorf("renaming an unknown department = %v, want %v", err, ErrNotFound)
+    }
+}
+
+func TestDepartmentRegistryRetire(t *testing.T) {
+    dr := newDepartmentTestRegistry(t)
+
+    if err := dr.Retire("support"); err == nil {
+        t.Error("retired SUPPORT while HELPDESK under it is still active")
+    }
+    if err := dr.Retire("helpdesk"); err != nil {
+        t.Fatal(err)
+    }
+    if dr.IsActive("HELPDESK") {
+        t.Error("HELPDESK is still active after retiring it")
+    }
+    if err := dr.Retire("helpdesk"); err == nil {
+        t.Error("retiring HELPDESK twice succeeded")
+    }
+    if err := dr.Retire("support"); err != nil {
+        t.Errorf("retiring SUPPORT once its only child is retired: %v", err)
+    }
+    if err := dr.Create("Tier 2", "HELPDESK"); !errors.Is(err, ErrInvalidDepartment) {
+        t.Errorf("creating under a retired department = %v, want %v", err, ErrInvalidDepartment)
+    }
+}
+
+func TestManagerDepartments(t *testing.T) {
+    em := NewEmployeeManager()
+    if err := em.CreateDepartment("SUPPORT", IT_DEPT); err != nil {
+        t.Fatal(err)
+    }
+    steps := []error{
+        em.AddEmployee(1, "Asha Rao", 30, IT_DEPT),
+        em.AddEmployee(2, "Ravi Nair", 28, "support"),
+        em.AddEmployee(3, "Meera Iyer", 35, HR_DEPT),
+    }
+    for _, err := range steps {
+        if err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    if got := em.CountByDepartment(IT_DEPT); got != 1 {
+        t.Errorf("CountByDepartment(IT) = %d, want 1", got)
+    }
+    if got := em.CountByDepartmentTree(IT_DEPT); got != 2 {
+        t.Errorf("CountByDepartmentTree(IT) = %d, want 2", got)
+    }
+    if tree, err := em.ListByDepartmentTree(IT_DEPT); err != nil || len(tree) != 2 {
+        t.Errorf("ListByDepartmentTree(IT) = %d employees, %v; want 2", len(tree), err)
+    }
+
+    if err := em.RetireDepartment("SUPPORT"); err == nil {
+        t.Error("retired SUPPORT while it still has an active employee")
+    }
+    if err := em.RemoveEmployee(2, today()); err != nil {
+        t.Fatal(err)
+    }
+    if err := em.RetireDepartment("SUPPORT"); err != nil {
+        t.Fatal(err)
+    }
+    if err := em.AddEmployee(4, "Kiran Das", 24, "SUPPORT"); !errors.Is(err, ErrInvalidDepartment) {
+        t.Errorf("adding to a retired department = %v, want %v", err, ErrInvalidDepartment)
+    }
+
+    // Renaming moves everyone, leavers included
+    if err := em.RenameDepartment(HR_DEPT, "PEOPLE"); err != nil {
+        t.Fatal(err)
+    }
+    if emp, err := em.SearchByID(3); err != nil || emp.Department != "PEOPLE" {
+        t.Errorf("employee 3 after renaming HR = %+v, %v; want PEOPLE", emp, err)
+    }
+}
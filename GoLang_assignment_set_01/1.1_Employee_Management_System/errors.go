@@ -0,0 +1,51 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+)
+
+// Sentinel errors wrapped by EmployeeManager so callers can use errors.Is
+var (
+    ErrDuplicateID           = errors.New("already exists")
+    ErrNotFound              = errors.New("not found")
+    ErrUnderage              = errors.New("employee must be at least 18 years old")
+    ErrInvalidDepartment     = errors.New("invalid department")
+    ErrInvalidEmail          = errors.New("invalid email address")
+    ErrDuplicateEmail        = errors.New("email address is already in use")
+    ErrInvalidPhone          = errors.New("invalid phone number")
+    ErrFutureHireDate        = errors.New("hire date cannot be in the future")
+    ErrTerminationBeforeHire = errors.New("termination date cannot be before the hire date")
+    ErrInvalidSalary         = errors.New("salary cannot be negative")
+    ErrInvalidStatus         = errors.New("invalid status")
+    ErrReportingCycle        = errors.New("reporting line would form a cycle")
+    ErrHasReports            = errors.New("still has direct reports")
+    ErrInvalidQuery          = errors.New("invalid query")
+    ErrInvalidLeaveType      = errors.New("invalid leave type")
+    ErrInvalidDateRange      = errors.New("invalid date range")
+    ErrLeaveOverlap          = errors.New("leave overlaps an existing request")
+    ErrInsufficientLeave     = errors.New("insufficient leave balance")
+    ErrInvalidAttendance     = errors.New("invalid attendance status")
+    ErrOnApprovedLeave       = errors.New("employee is on approved leave that day")
+    ErrStorage               = errors.New("failed to save employees")
+    ErrAudit                 = errors.New("failed to record change in audit log")
+)
+
+// ValidationError reports which field of an employee record failed validation.
+// It wraps one of the sentinel errors above, so both errors.Is and errors.As work
+type ValidationError struct {
+    Field string
+    Value string
+    Err   error
+}
+
+func (ve *ValidationError) Error() string {
+    if ve.Value == "" {
+        return ve.Err.Error()
+    }
+    return fmt.Sprintf("%v: %s", ve.Err, ve.Value)
+}
+
+func (ve *ValidationError) Unwrap() error {
+    return ve.Err
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/gorilla/mux"
+)
+
+// EmployeeHandler exposes an EmployeeManager over HTTP
+type EmployeeHandler struct {
+    manager *EmployeeManager
+}
+
+// NewEmployeeHandler creates a handler serving manager
+func NewEmployeeHandler(manager *EmployeeManager) *EmployeeHandler {
+    return &EmployeeHandler{manager: manager}
+}
+
+// countResponse is the JSON body returned by the department count endpoint
+type countResponse struct {
+    Department string `json:"department"`
+    Count      int    `json:"count"`
+}
+
+// errorResponse is the JSON body returned for every failed request
+type errorResponse struct {
+    Error string `json:"error"`
+}
+
+// RegisterRoutes adds the employee directory endpoints to router
+func (h *EmployeeHandler) RegisterRoutes(router *mux.Router) {
+    router.HandleFunc("/employees", h.listEmployees).Methods("GET")
+    router.HandleFunc("/employees", h.createEmployee).Methods("POST")
+    router.HandleFunc("/employees/{id}", h.getEmployee).Methods("GET")
+    router.HandleFunc("/employees/{id}", h.updateEmployee).Methods("PUT")
+    router.HandleFunc("/employees/{id}", h.deleteEmployee).Methods("DELETE")
+    router.HandleFunc("/employees/{id}/history", h.employeeHistory).Methods("GET")
+    router.HandleFunc("/roster", h.rosterAsOf).Methods("GET")
+    router.HandleFunc("/analytics/departments", h.departmentAnalytics).Methods("GET")
+    router.HandleFunc("/departments/{dept}/employees", h.listDepartmentEmployees).Methods("GET")
+    router.HandleFunc("/departments/{dept}/count", h.countDepartmentEmployees).Methods("GET")
+}
+
+// List employees, optionally filtered by ?name= and including leavers with ?include_terminated=true
+func (h *EmployeeHandler) listEmployees(w http.ResponseWriter, r *http.Request) {
+    if name := r.URL.Query().Get("name"); name != "" {
+        // SearchByName only fails when nothing matches
+        found, err := h.manager.SearchByName(name)
+        if err != nil {
+            found = []*Employee{}
+        }
+        writeJSON(w, http.StatusOK, found)
+        return
+    }
+
+    includeTerminated := r.URL.Query().Get("include_terminated") == "true"
+    writeJSON(w, http.StatusOK, h.manager.ListEmployees(includeTerminated))
+}
+
+// Create a new employee
+func (h *EmployeeHandler) createEmployee(w http.ResponseWriter, r *http.Request) {
+    var req Employee
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request payload"})
+        return
+    }
+
+    if err := h.manager.AddEmployeeRecord(req); err != nil {
+        writeError(w, err)
+        return
+    }
+
+    emp, err := h.manager.SearchByID(req.ID)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    writeJSON(w, http.StatusCreated, emp)
+}
+
+// Fetch a single employee, including leavers with ?include_terminated=true
+func (h *EmployeeHandler) getEmployee(w http.ResponseWriter, r *http.Request) {
+    id, ok := employeeID(w, r)
+    if !ok {
+        return
+    }
+
+    var emp *Employee
+    var err error
+    if r.URL.Query().Get("include_terminated") == "true" {
+        emp, err = h.manager.SearchByIDIncludingTerminated(id)
+    } else {
+        emp, err = h.manager.SearchByID(id)
+    }
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    writeJSON(w, http.StatusOK, emp)
+}
+
+// Update an existing employee's personal and job details
+func (h *EmployeeHandler) updateEmployee(w http.ResponseWriter, r *http.Request) {
+    id, ok := employeeID(w, r)
+    if !ok {
+        return
+    }
+
+    var req Employee
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request payload"})
+        return
+    }
+
+    req.ID = id
+    if err := h.manager.UpdateEmployeeRecord(req); err != nil {
+        writeError(w, err)
+        return
+    }
+
+    emp, err := h.manager.SearchByID(id)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    writeJSON(w, http.StatusOK, emp)
+}
+
+// Terminate an employee as of ?termination_date=YYYY-MM-DD, or today when omitted
+func (h *EmployeeHandler) deleteEmployee(w http.ResponseWriter, r *http.Request) {
+    id, ok := employeeID(w, r)
+    if !ok {
+        return
+    }
+
+    terminationDate := today()
+    if date := r.URL.Query().Get("termination_date"); date != "" {
+        parsed, err := time.ParseInLocation("2006-01-02", date, time.Local)
+        if err != nil {
+            writeJSON(w, http.StatusBadRequest, errorResponse{Error: "termination_date must be YYYY-MM-DD"})
+            return
+        }
+        terminationDate = parsed
+    }
+
+    if err := h.manager.RemoveEmployee(id, terminationDate); err != nil {
+        writeError(w, err)
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// List every recorded change to an employee, oldest first
+func (h *EmployeeHandler) employeeHistory(w http.ResponseWriter, r *http.Request) {
+    id, ok := employeeID(w, r)
+    if !ok {
+        return
+    }
+
+    history, err := h.manager.EmployeeHistory(id)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    writeJSON(w, http.StatusOK, history)
+}
+
+// Rebuild the roster as it stood at the end of ?as_of=YYYY-MM-DD from the audit log
+func (h *EmployeeHandler) rosterAsOf(w http.ResponseWriter, r *http.Request) {
+    asOf, err := time.ParseInLocation("2006-01-02", r.URL.Query().Get("as_of"), time.Local)
+    if err != nil {
+        writeJSON(w, http.StatusBadRequest, errorResponse{Error: "as_of must be YYYY-MM-DD"})
+        return
+    }
+
+    roster, err := h.manager.RosterAsOf(asOf.AddDate(0, 0, 1).Add(-time.Nanosecond))
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    writeJSON(w, http.StatusOK, roster)
+}
+
+// Report department analytics for ?from= to ?to= (YYYY-MM-DD), defaulting to the last 12 months
+func (h *EmployeeHandler) departmentAnalytics(w http.ResponseWriter, r *http.Request) {
+    from, to, err := analyticsPeriod(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+    if err != nil {
+        writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+        return
+    }
+
+    report, err := h.manager.DepartmentAnalytics(from, to)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    writeJSON(w, http.StatusOK, report)
+}
+
+// List the active employees of a department, including sub-departments with ?include_sub=true
+func (h *EmployeeHandler) listDepartmentEmployees(w http.ResponseWriter, r *http.Request) {
+    dept, ok := h.department(w, r)
+    if !ok {
+        return
+    }
+
+    var employees []*Employee
+    var err error
+    if r.URL.Query().Get("include_sub") == "true" {
+        employees, err = h.manager.ListByDepartmentTree(dept)
+    } else {
+        employees, err = h.manager.ListByDepartment(dept)
+    }
+
+    // The department exists, so an error only means it has nobody in it
+    if err != nil {
+        employees = []*Employee{}
+    }
+    writeJSON(w, http.StatusOK, employees)
+}
+
+// Count the active employees of a department, including sub-departments with ?include_sub=true
+func (h *EmployeeHandler) countDepartmentEmployees(w http.ResponseWriter, r *http.Request) {
+    dept, ok := h.department(w, r)
+    if !ok {
+        return
+    }
+
+    count := h.manager.CountByDepartment(dept)
+    if r.URL.Query().Get("include_sub") == "true" {
+        count = h.manager.CountByDepartmentTree(dept)
+    }
+    writeJSON(w, http.StatusOK, countResponse{Department: dept, Count: count})
+}
+
+// employeeID parses the {id} path variable, writing a 400 response if it is not a number
+func employeeID(w http.ResponseWriter, r *http.Request) (int, bool) {
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        writeJSON(w, http.StatusBadRequest, errorResponse{Error: "employee ID must be a number"})
+        return 0, false
+    }
+    return id, true
+}
+
+// department returns the normalized {dept} path variable, writing a 404 response if it is unknown
+func (h *EmployeeHandler) department(w http.ResponseWriter, r *http.Request) (string, bool) {
+    name := normalizeDepartment(mux.Vars(r)["dept"])
+    for _, dept := range h.manager.Departments() {
+        if dept.Name == name {
+            return name, true
+        }
+    }
+
+    writeJSON(w, http.StatusNotFound, errorResponse{Error: "department " + name + " not found"})
+    return "", false
+}
+
+// statusForError maps EmployeeManager errors to HTTP status codes
+func statusForError(err error) int {
+    var validationErr *ValidationError
+    switch {
+    case errors.Is(err, ErrDuplicateID), errors.Is(err, ErrDuplicateEmail), errors.Is(err, ErrHasReports):
+        return http.StatusConflict
+    case errors.Is(err, ErrNotFound):
+        return http.StatusNotFound
+    case errors.As(err, &validationErr), errors.Is(err, ErrReportingCycle):
+        return http.StatusUnprocessableEntity
+    case errors.Is(err, ErrStorage), errors.Is(err, ErrAudit):
+        return http.StatusInternalServerError
+    default:
+        return http.StatusBadRequest
+    }
+}
+
+// writeError writes err as a JSON error body with the matching status code
+func writeError(w http.ResponseWriter, err error) {
+    writeJSON(w, statusForError(err), errorResponse{Error: err.Error()})
+}
+
+// writeJSON writes v as the JSON response body with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+    "testing"
+)
+
+// Size of the roster the benchmarks search, and how many departments it is spread over
+const (
+    benchmarkRosterSize  = 50000
+    benchmarkDepartments = 50
+)
+
+var (
+    benchmarkFirstNames = []string{"Aarav", "Vivaan", "Aditya", "Vihaan", "Arjun", "Sai", "Reyansh", "Krishna",
+        "Ishaan", "Shaurya", "Ananya", "Diya", "Saanvi", "Aadhya", "Myra", "Pari", "Anika", "Navya", "Riya", "Kavya"}
+    benchmarkLastNames = []string{"Sharma", "Verma", "Gupta", "Patel", "Reddy", "Nair", "Iyer", "Mehta", "Joshi",
+        "Kulkarni", "Desai", "Chopra", "Malhotra", "Kapoor", "Bose", "Das", "Rao", "Pillai", "Menon", "Shetty",
+        "Bhat", "Naidu", "Saxena", "Agarwal", "Banerjee"}
+)
+
+// newBenchmarkRoster returns a manager holding n employees spread over the default departments
+// and enough new ones to make benchmarkDepartments, with every tenth employee terminated
+func newBenchmarkRoster(tb testing.TB, n int) (*EmployeeManager, []string) {
+    tb.Helper()
+
+    em := NewEmployeeManager()
+    departments := []string{HR_DEPT, IT_DEPT, FIN_DEPT}
+    for len(departments) < benchmarkDepartments {
+        name := fmt.Sprintf("DEPT%02d", len(departments))
+        if err := em.CreateDepartment(name, ""); err != nil {
+            tb.Fatalf("creating department %s: %v", name, err)
+        }
+        departments = append(departments, name)
+    }
+
+    for i := 1; i <= n; i++ {
+        first := benchmarkFirstNames[i%len(benchmarkFirstNames)]
+        last := benchmarkLastNames[(i/len(benchmarkFirstNames))%len(benchmarkLastNames)]
+        err := em.AddEmployeeRecord(Employee{
+            ID:         i,
+            Name:       first + " " + last,
+            Age:        22 + i%40,
+            Department: departments[i%len(departments)],
+            Email:      fmt.Sprintf("employee%d@example.com", i),
+        })
+        if err != nil {
+            tb.Fatalf("adding employee %d: %v", i, err)
+        }
+    }
+    for i := 10; i <= n; i += 10 {
+        if err := em.RemoveEmployee(i, today()); err != nil {
+            tb.Fatalf("terminating employee %d: %v", i, err)
+        }
+    }
+    return em, departments
+}
+
+// scanByID is the linear ID lookup the ID index replaced, used both to find an employee
+// and to reject a duplicate ID
+func (em *EmployeeManager) scanByID(id int) int {
+    for i := range em.employees {
+        if em.employees[i].ID == id {
+            return i
+        }
+    }
+    return -1
+}
+
+// scanByName is the linear name search the trigram index replaced
+func (em *EmployeeManager) scanByName(name string) []*Employee {
+    var found []*Employee
+    name = strings.ToLower(name)
+    for i := range em.employees {
+        if em.employees[i].IsActive() && strings.Contains(strings.ToLower(em.employees[i].Name), name) {
+            emp := em.employees[i]
+            found = append(found, &emp)
+        }
+    }
+    return found
+}
+
+// scanByEmail is the linear email lookup the email index replaced
+func (em *EmployeeManager) scanByEmail(email string) int {
+    for i := range em.employees {
+        if strings.EqualFold(em.employees[i].Email, email) {
+            return i
+        }
+    }
+    return -1
+}
+
+// scanByDepartment is the linear department listing the department index replaced
+func (em *EmployeeManager) scanByDepartment(department string) []*Employee {
+    var found []*Employee
+    for i := range em.employees {
+        if em.employees[i].Department == department && em.employees[i].IsActive() {
+            emp := em.employees[i]
+            found = append(found, &emp)
+        }
+    }
+    return found
+}
+
+// scanCountByDepartment is the linear head count the active count index replaced
+func (em *EmployeeManager) scanCountByDepartment(department string) int {
+    count := 0
+    for i := range em.employees {
+        if em.employees[i].Department == department && em.employees[i].IsActive() {
+            count++
+        }
+    }
+    return count
+}
+
+func TestIndexMatchesScan(t *testing.T) {
+    em, departments := newBenchmarkRoster(t, 5000)
+
+    for _, query := range []string{"riya", "Kavya Menon", "SHARMA", "ab"} {
+        indexed, _ := em.SearchByName(query)
+        if scanned := em.scanByName(query); !sameEmployees(indexed, scanned) {
+            t.Errorf("SearchByName(%q) found %d employees, scan found %d", query, len(indexed), len(scanned))
+        }
+    }
+
+    for _, id := range []int{1, 2500, 5000, 5001} {
+        if indexed, scanned := em.indexOf(id), em.scanByID(id); indexed != scanned {
+            t.Errorf("ID %d indexed at %d, scan found %d", id, indexed, scanned)
+        }
+    }
+
+    for _, email := range []string{"employee1234@example.com", "EMPLOYEE77@example.com", "nobody@example.com"} {
+        if indexed, scanned := em.index.emailPosition(email), em.scanByEmail(email); indexed != scanned {
+            t.Errorf("email %q indexed at %d, scan found %d", email, indexed, scanned)
+        }
+    }
+
+    for _, dept := range departments {
+        indexed, _ := em.ListByDepartment(dept)
+        if scanned := em.scanByDepartment(dept); !sameEmployees(indexed, scanned) {
+            t.Errorf("ListByDepartment(%s) found %d employees, scan found %d", dept, len(indexed), len(scanned))
+        }
+        if indexed, scanned := em.CountByDepartment(dept), em.scanCountByDepartment(dept); indexed != scanned {
+            t.Errorf("CountByDepartment(%s) = %d, scan counted %d", dept, indexed, scanned)
+        }
+    }
+}
+
+// sameEmployees reports whether two results hold the same employees in the same order
+func sameEmployees(a []*Employee, b []*Employee) bool {
+    if len(a) != len(b) {
+        return false
+    }
+    for i := range a {
+        if a[i].ID != b[i].ID {
+            return false
+        }
+    }
+    return true
+}
+
+func BenchmarkSearchByID(b *testing.B) {
+    em, _ := newBenchmarkRoster(b, benchmarkRosterSize)
+    id := benchmarkRosterSize - 1
+    b.Run("index", func(b *testing.B) {
+        for i := 0; i < b.N; i++ {
+            em.SearchByID(id)
+        }
+    })
+    b.Run("scan", func(b *testing.B) {
+        for i := 0; i < b.N; i++ {
+            em.scanByID(id)
+        }
+    })
+}
+
+// BenchmarkDuplicateIDCheck measures rejecting a new hire whose ID is already taken
+func BenchmarkDuplicateIDCheck(b *testing.B) {
+    em, _ := newBenchmarkRoster(b, benchmarkRosterSize)
+    duplicate := Employee{ID: benchmarkRosterSize - 1, Name: "Duplicate", Age: 30, Department: IT_DEPT}
+    b.Run("index", func(b *testing.B) {
+        for i := 0; i < b.N; i++ {
+            candidate := duplicate
+            if err := em.prepareNewEmployee(&candidate); !errors.Is(err, ErrDuplicateID) {
+                b.Fatalf("duplicate ID was not rejected: %v", err)
+            }
+        }
+    })
+    b.Run("scan", func(b *testing.B) {
+        for i := 0; i < b.N; i++ {
+            if em.scanByID(duplicate.ID) < 0 {
+                b.Fatal("duplicate ID was not found")
+            }
+        }
+    })
+}
+
+func BenchmarkSearchByName(b *testing.B) {
+    em, _ := newBenchmarkRoster(b, benchmarkRosterSize)
+    b.Run("index", func(b *testing.B) {
+        for i := 0; i < b.N; i++ {
+            em.SearchByName("Kavya Menon")
+        }
+    })
+    b.Run("scan", func(b *testing.B) {
+        for i := 0; i < b.N; i++ {
+            em.scanByName("Kavya Menon")
+        }
+    })
+}
+
+func BenchmarkEmailLookup(b *testing.B) {
+    em, _ := newBenchmarkRoster(b, benchmarkRosterSize)
+    email := fmt.Sprintf("employee%d@example.com", benchmarkRosterSize-1)
+    b.Run("index", func(b *testing.B) {
+        for i := 0; i < b.N; i++ {
+            em.index.emailPosition(email)
+        }
+    })
+    b.Run("scan", func(b *testing.B) {
+        for i := 0; i < b.N; i++ {
+            em.scanByEmail(email)
+        }
+    })
+}
+
+func BenchmarkListByDepartment(b *testing.B) {
+    em, _ := newBenchmarkRoster(b, benchmarkRosterSize)
+    b.Run("index", func(b *testing.B) {
+        for i := 0; i < b.N; i++ {
+            em.ListByDepartment(FIN_DEPT)
+        }
+    })
+    b.Run("scan", func(b *testing.B) {
+        for i := 0; i < b.N; i++ {
+            em.scanByDepartment(FIN_DEPT)
+        }
+    })
+}
+
+func BenchmarkCountByDepartment(b *testing.B) {
+    em, _ := newBenchmarkRoster(b, benchmarkRosterSize)
+    b.Run("index", func(b *testing.B) {
+        for i := 0; i < b.N; i++ {
+            em.CountByDepartment(FIN_DEPT)
+        }
+    })
+    b.Run("scan", func(b *testing.B) {
+        for i := 0; i < b.N; i++ {
+            em.scanCountByDepartment(FIN_DEPT)
+        }
+    })
+}
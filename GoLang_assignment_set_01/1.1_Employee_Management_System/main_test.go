@@ -0,0 +1,264 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "path/filepath"
+    "sync"
+    "testing"
+    "time"
+)
+
+// Run these under the race detector with: go test -race -run Concurrent
+const (
+    concurrentWriters = 8
+    concurrentReaders = 8
+    concurrentAdds    = 200
+    concurrentReads   = 2000
+)
+
+func TestConcurrentAddAndSearch(t *testing.T) {
+    em := NewEmployeeManager()
+    if err := em.SetAuditLog(NewMemoryAuditLog()); err != nil {
+        t.Fatal(err)
+    }
+    departments := []string{HR_DEPT, IT_DEPT, FIN_DEPT}
+
+    var wg sync.WaitGroup
+
+    for w := 0; w < concurrentWriters; w++ {
+        wg.Add(1)
+        go func(w int) {
+            defer wg.Done()
+            for i := 0; i < concurrentAdds; i++ {
+                id := w*concurrentAdds + i + 1
+                err := em.AddEmployeeRecord(Employee{
+                    ID:         id,
+                    Name:       fmt.Sprintf("Writer%d Employee%d", w, i),
+                    Age:        30,
+                    Department: departments[id%len(departments)],
+                    Email:      fmt.Sprintf("employee%d@example.com", id),
+                })
+                if err != nil {
+                    t.Errorf("adding employee %d: %v", id, err)
+                    return
+                }
+                // Change some of what was just added while readers are looking at it
+                if i%3 == 0 {
+                    if err := em.TransferDepartment(id, departments[(id+1)%len(departments)]); err != nil {
+                        t.Errorf("transferring employee %d: %v", id, err)
+                    }
+                }
+            }
+        }(w)
+    }
+
+    for r := 0; r < concurrentReaders; r++ {
+        wg.Add(1)
+        go func(r int) {
+            defer wg.Done()
+            for i := 0; i < concurrentReads; i++ {
+                em.SearchByName(fmt.Sprintf("Writer%d Employee%d", i%concurrentWriters, i%concurrentAdds))
+                em.SearchByID(i%(concurrentWriters*concurrentAdds) + 1)
+                em.CountByDepartment(departments[i%len(departments)])
+                // Copying whole departments and the roster is slow, so do it now and then
+                if i%50 == r {
+                    em.ListByDepartment(departments[r%len(departments)])
+                    em.ListEmployees(false)
+                }
+            }
+        }(r)
+    }
+
+    wg.Wait()
+
+    total := concurrentWriters * concurrentAdds
+    if got := len(em.ListEmployees(false)); got != total {
+        t.Fatalf("roster holds %d employees, want %d", got, total)
+    }
+    counted := 0
+    for _, dept := range departments {
+        count := em.CountByDepartment(dept)
+        if scanned := em.scanCountByDepartment(dept); count != scanned {
+            t.Errorf("CountByDepartment(%s) = %d but %d employees are in it", dept, count, scanned)
+        }
+        counted += count
+    }
+    if counted != total {
+        t.Errorf("department counts add up to %d, want %d", counted, total)
+    }
+    for w := 0; w < concurrentWriters; w++ {
+        found, err := em.SearchByName(fmt.Sprintf("Writer%d ", w))
+        if err != nil || len(found) != concurrentAdds {
+            t.Errorf("found %d employees added by writer %d, want %d (%v)", len(found), w, concurrentAdds, err)
+        }
+    }
+}
+
+func TestConcurrentDuplicateAdds(t *testing.T) {
+    em := NewEmployeeManager()
+
+    // Every writer races to add the same IDs; exactly one of them may win each
+    var wg sync.WaitGroup
+    var mu sync.Mutex
+    added := make(map[int]int)
+    for w := 0; w < concurrentWriters; w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for id := 1; id <= concurrentAdds; id++ {
+                if em.AddEmployee(id, fmt.Sprintf("Employee %d", id), 30, IT_DEPT) == nil {
+                    mu.Lock()
+                    added[id]++
+                    mu.Unlock()
+                }
+            }
+        }()
+    }
+    wg.Wait()
+
+    for id := 1; id <= concurrentAdds; id++ {
+        if added[id] != 1 {
+            t.Errorf("employee %d was added %d times", id, added[id])
+        }
+    }
+    if got := em.CountByDepartment(IT_DEPT); got != concurrentAdds {
+        t.Errorf("IT has %d employees, want %d", got, concurrentAdds)
+    }
+}
+
+func TestRemoveEmployeeBeforeHireDate(t *testing.T) {
+    em := NewEmployeeManager()
+    hired := time.Date(2023, time.March, 1, 0, 0, 0, 0, time.Local)
+    if err := em.AddEmployeeRecord(Employee{ID: 1, Name: "Asha", Age: 30, Department: IT_DEPT, HireDate: hired}); err != nil {
+        t.Fatal(err)
+    }
+
+    err := em.RemoveEmployee(1, hired.AddDate(0, 0, -1))
+    var validationErr *ValidationError
+    if !errors.As(err, &validationErr) || !errors.Is(err, ErrTerminationBeforeHire) {
+        t.Fatalf("terminating before the hire date returned %v, want %v", err, ErrTerminationBeforeHire)
+    }
+    if emp, _ := em.SearchByID(1); emp == nil || !emp.IsActive() {
+        t.Fatal("employee was terminated despite the error")
+    }
+
+    if err := em.RemoveEmployee(1, hired); err != nil {
+        t.Fatalf("terminating on the hire date: %v", err)
+    }
+}
+
+// newStoredManager returns a manager saving to a JSON store in a temporary directory,
+// and a function that reopens the same store as a fresh manager
+func newStoredManager(t *testing.T) (*EmployeeManager, func() *EmployeeManager) {
+    t.Helper()
+    path := filepath.Join(t.TempDir(), "employees.json")
+    reopen := func() *EmployeeManager {
+        em, err := NewEmployeeManagerWithStore(NewJSONStore(path))
+        if err != nil {
+            t.Fatal(err)
+        }
+        return em
+    }
+    return reopen(), reopen
+}
+
+func TestUpdateEmployeePersists(t *testing.T) {
+    em, reopen := newStoredManager(t)
+    if err := em.AddEmployee(1, "Asha", 30, IT_DEPT); err != nil {
+        t.Fatal(err)
+    }
+
+    if err := em.UpdateEmployee(1, "Asha Rao", 31, HR_DEPT); err != nil {
+        t.Fatalf("UpdateEmployee: %v", err)
+    }
+
+    for name, m := range map[string]*EmployeeManager{"in memory": em, "reloaded": reopen()} {
+        emp, err := m.SearchByID(1)
+        if err != nil {
+            t.Fatalf("%s: %v", name, err)
+        }
+        if emp.Name != "Asha Rao" || emp.Age != 31 || emp.Department != HR_DEPT {
+            t.Errorf("%s: employee = %+v, want Asha Rao, 31, HR", name, *emp)
+        }
+    }
+    if err := em.UpdateEmployee(2, "Nobody", 30, IT_DEPT); !errors.Is(err, ErrNotFound) {
+        t.Errorf("updating an unknown employee returned %v, want %v", err, ErrNotFound)
+    }
+}
+
+func TestTransferDepartmentMovesIndex(t *testing.T) {
+    em, reopen := newStoredManager(t)
+    for id, name := range map[int]string{1: "Asha", 2: "Ravi"} {
+        if err := em.AddEmployee(id, name, 30, IT_DEPT); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    if err := em.TransferDepartment(1, "finance"); err != nil {
+        t.Fatalf("TransferDepartment: %v", err)
+    }
+    if err := em.TransferDepartment(1, FIN_DEPT); err == nil {
+        t.Error("transferring to the current department succeeded")
+    }
+
+    for name, m := range map[string]*EmployeeManager{"in memory": em, "reloaded": reopen()} {
+        if got := m.CountByDepartment(IT_DEPT); got != 1 {
+            t.Errorf("%s: IT has %d employees, want 1", name, got)
+        }
+        finance, err := m.ListByDepartment(FIN_DEPT)
+        if err != nil || len(finance) != 1 || finance[0].ID != 1 {
+            t.Errorf("%s: FINANCE lists %v (%v), want employee 1", name, finance, err)
+        }
+        it, err := m.ListByDepartment(IT_DEPT)
+        if err != nil || len(it) != 1 || it[0].ID != 2 {
+            t.Errorf("%s: IT lists %v (%v), want employee 2", name, it, err)
+        }
+    }
+}
+
+func TestRemoveEmployeeHidesFromActiveLookups(t *testing.T) {
+    em, reopen := newStoredManager(t)
+    hired := time.Date(2023, time.March, 1, 0, 0, 0, 0, time.Local)
+    for id, name := range map[int]string{1: "Asha", 2: "Ravi"} {
+        if err := em.AddEmployeeRecord(Employee{ID: id, Name: name, Age: 30, Department: IT_DEPT, HireDate: hired}); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    left := time.Date(2024, time.June, 30, 0, 0, 0, 0, time.Local)
+    if err := em.RemoveEmployee(1, left); err != nil {
+        t.Fatalf("RemoveEmployee: %v", err)
+    }
+    if err := em.RemoveEmployee(1, left); !errors.Is(err, ErrNotFound) {
+        t.Errorf("removing a terminated employee again returned %v, want %v", err, ErrNotFound)
+    }
+
+    for name, m := range map[string]*EmployeeManager{"in memory": em, "reloaded": reopen()} {
+        if _, err := m.SearchByID(1); !errors.Is(err, ErrNotFound) {
+            t.Errorf("%s: SearchByID found a terminated employee (%v)", name, err)
+        }
+        emp, err := m.SearchByIDIncludingTerminated(1)
+        if err != nil {
+            t.Fatalf("%s: SearchByIDIncludingTerminated: %v", name, err)
+        }
+        if emp.IsActive() || emp.Status != STATUS_TERMINATED || emp.TerminationDate == nil || !emp.TerminationDate.Equal(left) {
+            t.Errorf("%s: terminated employee = %+v, want TERMINATED on %v", name, *emp, left)
+        }
+
+        active, err := m.ListByDepartment(IT_DEPT)
+        if err != nil || len(active) != 1 || active[0].ID != 2 {
+            t.Errorf("%s: ListByDepartment = %v (%v), want only employee 2", name, active, err)
+        }
+        all, err := m.ListByDepartmentIncludingTerminated(IT_DEPT)
+        if err != nil || len(all) != 2 {
+            t.Errorf("%s: ListByDepartmentIncludingTerminated = %v (%v), want both employees", name, all, err)
+        }
+        if got := m.CountByDepartment(IT_DEPT); got != 1 {
+            t.Errorf("%s: CountByDepartment = %d, want 1", name, got)
+        }
+        if got := len(m.ListEmployees(false)); got != 1 {
+            t.Errorf("%s: ListEmployees(false) returned %d employees, want 1", name, got)
+        }
+    }
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+    "encoding/csv"
+    "fmt"
+    "io"
+    "math"
+    "sort"
+    "strconv"
+    "time"
+)
+
+// Payslip line item kinds
+const (
+    LINE_EARNING   = "EARNING"
+    LINE_DEDUCTION = "DEDUCTION"
+    LINE_TAX       = "TAX"
+)
+
+// PayComponent is an allowance or deduction worked out from basic pay.
+// Its amount is Percent of basic plus Fixed
+type PayComponent struct {
+    Name    string  `json:"name"`
+    Percent float64 `json:"percent,omitempty"`
+    Fixed   float64 `json:"fixed,omitempty"`
+}
+
+// SalaryStructure splits an employee's monthly salary into basic pay, allowances and deductions.
+// Whatever the salary leaves after basic and allowances is paid as a special allowance
+type SalaryStructure struct {
+    BasicPercent float64        `json:"basic_percent"`
+    Allowances   []PayComponent `json:"allowances"`
+    Deductions   []PayComponent `json:"deductions"`
+}
+
+// TaxSlab taxes the part of annual taxable income up to UpTo at Rate percent.
+// The last slab should have UpTo 0, meaning no upper limit
+type TaxSlab struct {
+    UpTo float64 `json:"up_to"`
+    Rate float64 `json:"rate"`
+}
+
+// PayrollConfig holds the salary structure per department and the income tax slabs
+type PayrollConfig struct {
+    // DefaultStructure applies to departments without an entry in Structures
+    DefaultStructure SalaryStructure            `json:"default_structure"`
+    Structures       map[string]SalaryStructure `json:"structures"`
+    TaxSlabs         []TaxSlab                  `json:"tax_slabs"`
+}
+
+// PayLineItem is one row of a payslip
+type PayLineItem struct {
+    Kind   string  `json:"kind"`
+    Name   string  `json:"name"`
+    Amount float64 `json:"amount"`
+}
+
+// Payslip is one employee's pay for one month
+type Payslip struct {
+    EmployeeID      int           `json:"employee_id"`
+    Name            string        `json:"name"`
+    Department      string        `json:"department"`
+    Period          string        `json:"period"`
+    DaysInMonth     int           `json:"days_in_month"`
+    DaysPayable     int           `json:"days_payable"`
+    Items           []PayLineItem `json:"items"`
+    Gross           float64       `json:"gross"`
+    TotalDeductions float64       `json:"total_deductions"`
+    Tax             float64       `json:"tax"`
+    Net             float64       `json:"net"`
+}
+
+// PayrollRun is the payroll for every employee paid in one month
+type PayrollRun struct {
+    Period          string    `json:"period"`
+    Payslips        []Payslip `json:"payslips"`
+    TotalGross      float64   `json:"total_gross"`
+    TotalDeductions float64   `json:"total_deductions"`
+    TotalTax        float64   `json:"total_tax"`
+    TotalNet        float64   `json:"total_net"`
+}
+
+// PayrollEngine computes monthly payroll from an EmployeeManager's roster
+type PayrollEngine struct {
+    manager *EmployeeManager
+    config  PayrollConfig
+}
+
+// NewPayrollEngine creates a payroll engine for manager's roster using config
+func NewPayrollEngine(manager *EmployeeManager, config PayrollConfig) *PayrollEngine {
+    return &PayrollEngine{
+        manager: manager,
+        config:  config,
+    }
+}
+
+// DefaultPayrollConfig returns a typical structure: 50% basic, 40% house rent allowance,
+// 12% provident fund, a fixed professional tax and progressive income tax slabs
+func DefaultPayrollConfig() PayrollConfig {
+    standard := SalaryStructure{
+        BasicPercent: 50,
+        Allowances: []PayComponent{
+            {Name: "House Rent Allowance", Percent: 40},
+        },
+        Deductions: []PayComponent{
+            {Name: "Provident Fund", Percent: 12},
+            {Name: "Professional Tax", Fixed: 200},
+        },
+    }
+
+    return PayrollConfig{
+        DefaultStructure: standard,
+        Structures:       make(map[string]SalaryStructure),
+        TaxSlabs: []TaxSlab{
+            {UpTo: 300000, Rate: 0},
+            {UpTo: 700000, Rate: 5},
+            {UpTo: 1000000, Rate: 10},
+            {UpTo: 1200000, Rate: 15},
+            {UpTo: 1500000, Rate: 20},
+            {UpTo: 0, Rate: 30},
+        },
+    }
+}
+
+// Run computes the payroll for the given month. Everyone employed for at least one day of the
+// month is paid; joiners and leavers are pro-rated by the calendar days they were employed
+func (pe *PayrollEngine) Run(year int, month time.Month) (*PayrollRun, error) {
+    if month < time.January || month > time.December {
+        return nil, fmt.Errorf("invalid payroll month: %d", month)
+    }
+
+    run := &PayrollRun{
+        Period:   fmt.Sprintf("%04d-%02d", year, month),
+        Payslips: make([]Payslip, 0),
+    }
+    for _, emp := range pe.manager.ListEmployees(true) {
+        slip, ok := pe.Payslip(*emp, year, month)
+        if !ok {
+            continue
+        }
+        run.Payslips = append(run.Payslips, slip)
+        run.TotalGross += slip.Gross
+        run.TotalDeductions += slip.TotalDeductions
+        run.TotalTax += slip.Tax
+        run.TotalNet += slip.Net
+    }
+
+    sort.Slice(run.Payslips, func(i, j int) bool {
+        return run.Payslips[i].EmployeeID < run.Payslips[j].EmployeeID
+    })
+    run.TotalGross = roundMoney(run.TotalGross)
+    run.TotalDeductions = roundMoney(run.TotalDeductions)
+    run.TotalTax = roundMoney(run.TotalTax)
+    run.TotalNet = roundMoney(run.TotalNet)
+    return run, nil
+}
+
+// Payslip computes emp's pay for the given month. The second result is false
+// when emp was not employed on any day of the month
+func (pe *PayrollEngine) Payslip(emp Employee, year int, month time.Month) (Payslip, bool) {
+    monthStart := time.Date(year, month, 1, 0, 0, 0, 0, time.Local)
+    monthEnd := monthStart.AddDate(0, 1, -1)
+    daysInMonth := monthEnd.Day()
+
+    // Work out the first and last employed day within the month
+    first, last := monthStart, monthEnd
+    if hired := dateOnly(emp.HireDate); !emp.HireDate.IsZero() && hired.After(first) {
+        first = hired
+    }
+    if emp.TerminationDate != nil {
+        if left := dateOnly(*emp.TerminationDate); left.Before(last) {
+            last = left
+        }
+    }
+    if last.Before(first) {
+        return Payslip{}, false
+    }
+    daysPayable := int(math.Round(last.Sub(first).Hours()/24)) + 1
+    factor := float64(daysPayable) / float64(daysInMonth)
+
+    structure, ok := pe.config.Structures[emp.Department]
+    if !ok {
+        structure = pe.config.DefaultStructure
+    }
+
+    slip := Payslip{
+        EmployeeID:  emp.ID,
+        Name:        emp.Name,
+        Department:  emp.Department,
+        Period:      fmt.Sprintf("%04d-%02d", year, month),
+        DaysInMonth: daysInMonth,
+        DaysPayable: daysPayable,
+        Items:       make([]PayLineItem, 0),
+    }
+
+    // Earnings: basic, configured allowances, then special allowance up to the full salary
+    salary := emp.Salary * factor
+    basic := roundMoney(salary * structure.BasicPercent / 100)
+    slip.addItem(LINE_EARNING, "Basic", basic)
+
+    earned := basic
+    for _, allowance := range structure.Allowances {
+        amount := roundMoney(allowance.Percent/100*basic + allowance.Fixed*factor)
+        slip.addItem(LINE_EARNING, allowance.Name, amount)
+        earned += amount
+    }
+    if special := roundMoney(salary - earned); special > 0 {
+        slip.addItem(LINE_EARNING, "Special Allowance", special)
+    }
+
+    // Deductions never take pay below zero
+    for _, deduction := range structure.Deductions {
+        amount := roundMoney(deduction.Percent/100*basic + deduction.Fixed*factor)
+        amount = math.Min(amount, roundMoney(slip.Gross-slip.TotalDeductions))
+        slip.addItem(LINE_DEDUCTION, deduction.Name, amount)
+    }
+
+    // Income tax on the annualised taxable pay, spread evenly over the year
+    taxable := slip.Gross - slip.TotalDeductions
+    tax := roundMoney(annualTax(taxable/factor*12, pe.config.TaxSlabs) / 12 * factor)
+    slip.addItem(LINE_TAX, "Income Tax", tax)
+
+    slip.Net = roundMoney(slip.Gross - slip.TotalDeductions - slip.Tax)
+    return slip, true
+}
+
+// addItem appends a line item and updates the payslip totals
+func (p *Payslip) addItem(kind string, name string, amount float64) {
+    p.Items = append(p.Items, PayLineItem{Kind: kind, Name: name, Amount: amount})
+    switch kind {
+    case LINE_EARNING:
+        p.Gross = roundMoney(p.Gross + amount)
+    case LINE_DEDUCTION:
+        p.TotalDeductions = roundMoney(p.TotalDeductions + amount)
+    case LINE_TAX:
+        p.Tax = roundMoney(p.Tax + amount)
+    }
+}
+
+// WriteText writes the payslip to w as a printable statement
+func (p Payslip) WriteText(w io.Writer) error {
+    _, err := fmt.Fprintf(w, "Payslip for %s\n%s (ID: %d, %s)\nDays payable: %d of %d\n",
+        p.Period, p.Name, p.EmployeeID, p.Department, p.DaysPayable, p.DaysInMonth)
+    if err != nil {
+        return err
+    }
+
+    for _, kind := range []string{LINE_EARNING, LINE_DEDUCTION, LINE_TAX} {
+        for _, item := range p.Items {
+            if item.Kind == kind {
+                if _, err := fmt.Fprintf(w, "  %-10s %-24s Rs. %12.2f\n", kind, item.Name, item.Amount); err != nil {
+                    return err
+                }
+            }
+        }
+    }
+
+    _, err = fmt.Fprintf(w, "  Gross: Rs. %.2f  Deductions: Rs. %.2f  Tax: Rs. %.2f  Net pay: Rs. %.2f\n",
+        p.Gross, p.TotalDeductions, p.Tax, p.Net)
+    return err
+}
+
+// WriteText writes every payslip in the run to w, followed by the run totals
+func (r *PayrollRun) WriteText(w io.Writer) error {
+    for _, slip := range r.Payslips {
+        if err := slip.WriteText(w); err != nil {
+            return err
+        }
+        if _, err := fmt.Fprintln(w); err != nil {
+            return err
+        }
+    }
+
+    _, err := fmt.Fprintf(w, "Payroll %s: %d payslips, gross Rs. %.2f, deductions Rs. %.2f, tax Rs. %.2f, net Rs. %.2f\n",
+        r.Period, len(r.Payslips), r.TotalGross, r.TotalDeductions, r.TotalTax, r.TotalNet)
+    return err
+}
+
+// WriteCSV writes one row per payslip line item to w, plus a NET row per employee
+func (r *PayrollRun) WriteCSV(w io.Writer) error {
+    writer := csv.NewWriter(w)
+    header := []string{"period", "employee_id", "name", "department", "days_payable", "kind", "component", "amount"}
+    if err := writer.Write(header); err != nil {
+        return err
+    }
+
+    for _, slip := range r.Payslips {
+        items := append(append([]PayLineItem{}, slip.Items...), PayLineItem{Kind: "NET", Name: "Net Pay", Amount: slip.Net})
+        for _, item := range items {
+            record := []string{
+                slip.Period,
+                strconv.Itoa(slip.EmployeeID),
+                slip.Name,
+                slip.Department,
+                strconv.Itoa(slip.DaysPayable),
+                item.Kind,
+                item.Name,
+                strconv.FormatFloat(item.Amount, 'f', 2, 64),
+            }
+            if err := writer.Write(record); err != nil {
+                return err
+            }
+        }
+    }
+
+    writer.Flush()
+    return writer.Error()
+}
+
+// annualTax applies the progressive tax slabs to an annual income
+func annualTax(income float64, slabs []TaxSlab) float64 {
+    tax := 0.0
+    lower := 0.0
+    for _, slab := range slabs {
+        if income <= lower {
+            break
+        }
+        upper := income
+        if slab.UpTo > 0 && slab.UpTo < income {
+            upper = slab.UpTo
+        }
+        tax += (upper - lower) * slab.Rate / 100
+        if slab.UpTo == 0 {
+            break
+        }
+        lower = slab.UpTo
+    }
+    return tax
+}
+
+// dateOnly drops the time of day from t, keeping its calendar date in local time
+func dateOnly(t time.Time) time.Time {
+    year, month, day := t.Date()
+    return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
+
+// roundMoney rounds an amount to whole paise, halves away from zero
+func roundMoney(amount float64) float64 {
+    return math.Round(amount*100) / 100
+}
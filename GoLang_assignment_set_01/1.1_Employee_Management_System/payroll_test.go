@@ -0,0 +1,200 @@
+package main
+
+import (
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestAnnualTaxSlabBoundaries(t *testing.T) {
+    slabs := DefaultPayrollConfig().TaxSlabs
+
+    tests := []struct {
+        income float64
+        want   float64
+    }{
+        {-5000, 0},
+        {0, 0},
+        {300000, 0},
+        {300100, 5},
+        {700000, 20000},
+        {1000000, 50000},
+        {1200000, 80000},
+        {1500000, 140000},
+        {2000000, 290000},
+    }
+    for _, tt := range tests {
+        if got := annualTax(tt.income, slabs); roundMoney(got) != tt.want {
+            t.Errorf("annualTax(%.2f) = %.2f, want %.2f", tt.income, got, tt.want)
+        }
+    }
+}
+
+// Amounts are float64 rupees rounded half away from zero to whole paise. The rounding applies to
+// amount*100 as computed in floating point, so 1.005 rounds down while 2.675 rounds up; these
+// cases pin that behaviour
+func TestRoundMoney(t *testing.T) {
+    tests := []struct {
+        amount float64
+        want   float64
+    }{
+        {0.125, 0.13},
+        {-0.125, -0.13},
+        {0.124, 0.12},
+        {2.675, 2.68},
+        {1.005, 1.00},
+        {5736.666666, 5736.67},
+        {100000.004, 100000.00},
+    }
+    for _, tt := range tests {
+        if got := roundMoney(tt.amount); got != tt.want {
+            t.Errorf("roundMoney(%v) = %v, want %v", tt.amount, got, tt.want)
+        }
+    }
+}
+
+func TestPayslipProRating(t *testing.T) {
+    june := func(day int) time.Time {
+        return time.Date(2024, time.June, day, 0, 0, 0, 0, time.Local)
+    }
+    leftOn := func(date time.Time) *time.Time {
+        return &date
+    }
+    engine := NewPayrollEngine(NewEmployeeManager(), DefaultPayrollConfig())
+
+    tests := []struct {
+        name        string
+        emp         Employee
+        wantDays    int
+        wantGross   float64
+        wantDeducts float64
+        wantTax     float64
+        wantNet     float64
+    }{
+        {"full month", Employee{ID: 1, Salary: 100000, HireDate: time.Date(2020, time.May, 4, 0, 0, 0, 0, time.Local)},
+            30, 100000, 6200, 5736.67, 88063.33},
+        {"joined mid-month", Employee{ID: 2, Salary: 100000, HireDate: june(16)},
+            15, 50000, 3100, 2868.33, 44031.67},
+        {"left mid-month", Employee{ID: 3, Salary: 100000, HireDate: june(1).AddDate(-1, 0, 0), TerminationDate: leftOn(june(10))},
+            10, 33333.33, 2066.67, 1912.22, 29354.44},
+        {"joined and left", Employee{ID: 4, Salary: 60000, HireDate: june(11), TerminationDate: leftOn(june(20).Add(17 * time.Hour))},
+            10, 20000, 1266.67, 520, 18213.33},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            slip, ok := engine.Payslip(tt.emp, 2024, time.June)
+            if !ok {
+                t.Fatal("Payslip() was not issued")
+            }
+            if slip.DaysInMonth != 30 || slip.DaysPayable != tt.wantDays {
+                t.Errorf("days payable = %d of %d, want %d of 30", slip.DaysPayable, slip.DaysInMonth, tt.wantDays)
+            }
+            if slip.Gross != tt.wantGross || slip.TotalDeductions != tt.wantDeducts || slip.Tax != tt.wantTax || slip.Net != tt.wantNet {
+                t.Errorf("gross %.2f, deductions %.2f, tax %.2f, net %.2f; want %.2f, %.2f, %.2f, %.2f",
+                    slip.Gross, slip.TotalDeductions, slip.Tax, slip.Net, tt.wantGross, tt.wantDeducts, tt.wantTax, tt.wantNet)
+            }
+            if got := roundMoney(slip.Gross - slip.TotalDeductions - slip.Tax); got != slip.Net {
+                t.Errorf("net %.2f does not equal gross less deductions and tax (%.2f)", slip.Net, got)
+            }
+        })
+    }
+
+    for name, emp := range map[string]Employee{
+        "joins next month": {ID: 5, Salary: 100000, HireDate: time.Date(2024, time.July, 1, 0, 0, 0, 0, time.Local)},
+        "left last month":  {ID: 6, Salary: 100000, HireDate: june(1).AddDate(-1, 0, 0), TerminationDate: leftOn(june(1).AddDate(0, 0, -1))},
+    } {
+        if _, ok := engine.Payslip(emp, 2024, time.June); ok {
+            t.Errorf("%s: Payslip() was issued for June", name)
+        }
+    }
+}
+
+func TestPayslipDeductionsStopAtZero(t *testing.T) {
+    config := DefaultPayrollConfig()
+    config.DefaultStructure = SalaryStructure{
+        BasicPercent: 100,
+        Deductions: []PayComponent{
+            {Name: "Loan Recovery", Fixed: 2000},
+            {Name: "Canteen", Fixed: 2000},
+        },
+    }
+    engine := NewPayrollEngine(NewEmployeeManager(), config)
+
+    slip, ok := engine.Payslip(Employee{ID: 1, Salary: 3000, HireDate: time.Date(2020, time.May, 4, 0, 0, 0, 0, time.Local)}, 2024, time.June)
+    if !ok {
+        t.Fatal("Payslip() was not issued")
+    }
+
+    wantItems := []PayLineItem{
+        {Kind: LINE_EARNING, Name: "Basic", Amount: 3000},
+        {Kind: LINE_DEDUCTION, Name: "Loan Recovery", Amount: 2000},
+        {Kind: LINE_DEDUCTION, Name: "Canteen", Amount: 1000},
+        {Kind: LINE_TAX, Name: "Income Tax", Amount: 0},
+    }
+    if len(slip.Items) != len(wantItems) {
+        t.Fatalf("items = %+v, want %+v", slip.Items, wantItems)
+    }
+    for i, item := range slip.Items {
+        if item != wantItems[i] {
+            t.Errorf("item %d = %+v, want %+v", i, item, wantItems[i])
+        }
+    }
+    if slip.Net != 0 {
+        t.Errorf("net = %.2f, want 0", slip.Net)
+    }
+}
+
+func TestPayrollRunCSV(t *testing.T) {
+    em := NewEmployeeManager()
+    hired := time.Date(2023, time.January, 9, 0, 0, 0, 0, time.Local)
+    for _, emp := range []Employee{
+        {ID: 2, Name: "Ravi Nair", Age: 28, Department: HR_DEPT, Salary: 10000, HireDate: hired},
+        {ID: 1, Name: "Asha Rao", Age: 30, Department: IT_DEPT, Salary: 30000, HireDate: hired},
+        {ID: 3, Name: "Meera Iyer", Age: 35, Department: IT_DEPT, Salary: 50000, HireDate: hired},
+    } {
+        if err := em.AddEmployeeRecord(emp); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := em.RemoveEmployee(3, time.Date(2024, time.May, 31, 0, 0, 0, 0, time.Local)); err != nil {
+        t.Fatal(err)
+    }
+
+    config := PayrollConfig{
+        DefaultStructure: SalaryStructure{
+            BasicPercent: 100,
+            Deductions:   []PayComponent{{Name: "Provident Fund", Percent: 12}},
+        },
+        TaxSlabs: []TaxSlab{{UpTo: 0, Rate: 10}},
+    }
+    run, err := NewPayrollEngine(em, config).Run(2024, time.June)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if run.TotalGross != 40000 || run.TotalDeductions != 4800 || run.TotalTax != 3520 || run.TotalNet != 31680 {
+        t.Errorf("run totals = %.2f, %.2f, %.2f, %.2f; want 40000, 4800, 3520, 31680",
+            run.TotalGross, run.TotalDeductions, run.TotalTax, run.TotalNet)
+    }
+
+    var sb strings.Builder
+    if err := run.WriteCSV(&sb); err != nil {
+        t.Fatal(err)
+    }
+    want := "period,employee_id,name,department,days_payable,kind,component,amount\n" +
+        "2024-06,1,Asha Rao,IT,30,EARNING,Basic,30000.00\n" +
+        "2024-06,1,Asha Rao,IT,30,DEDUCTION,Provident Fund,3600.00\n" +
+        "2024-06,1,Asha Rao,IT,30,TAX,Income Tax,2640.00\n" +
+        "2024-06,1,Asha Rao,IT,30,NET,Net Pay,23760.00\n" +
+        "2024-06,2,Ravi Nair,HR,30,EARNING,Basic,10000.00\n" +
+        "2024-06,2,Ravi Nair,HR,30,DEDUCTION,Provident Fund,1200.00\n" +
+        "2024-06,2,Ravi Nair,HR,30,TAX,Income Tax,880.00\n" +
+        "2024-06,2,Ravi Nair,HR,30,NET,Net Pay,7920.00\n"
+    if sb.String() != want {
+        t.Errorf("WriteCSV wrote\n%s\nwant\n%s", sb.String(), want)
+    }
+
+    if _, err := NewPayrollEngine(em, config).Run(2024, 13); err == nil {
+        t.Error("Run() accepted month 13")
+    }
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+    "errors"
+    "testing"
+    "time"
+)
+
+// newQueryTestManager returns five employees across three departments, with employee 5 terminated
+func newQueryTestManager(t *testing.T) *EmployeeManager {
+    t.Helper()
+    hired := func(year int, month time.Month) time.Time {
+        return time.Date(year, month, 1, 0, 0, 0, 0, time.Local)
+    }
+
+    em := NewEmployeeManager()
+    for _, emp := range []Employee{
+        {ID: 1, Name: "Asha Rao", Age: 30, Department: IT_DEPT, Salary: 90000, HireDate: hired(2021, time.April)},
+        {ID: 2, Name: "Ravi Nair", Age: 45, Department: HR_DEPT, Salary: 60000, HireDate: hired(2019, time.June)},
+        {ID: 3, Name: "Meera Iyer", Age: 28, Department: IT_DEPT, Salary: 75000, HireDate: hired(2023, time.January)},
+        {ID: 4, Name: "Kiran Rao", Age: 38, Department: FIN_DEPT, Salary: 75000, HireDate: hired(2020, time.September)},
+        {ID: 5, Name: "Sneha Patil", Age: 33, Department: IT_DEPT, Salary: 80000, HireDate: hired(2018, time.March)},
+    } {
+        if err := em.AddEmployeeRecord(emp); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := em.RemoveEmployee(5, hired(2024, time.May)); err != nil {
+        t.Fatal(err)
+    }
+    return em
+}
+
+func TestQueryFilters(t *testing.T) {
+    em := newQueryTestManager(t)
+
+    tests := []struct {
+        name  string
+        query *EmployeeQuery
+        want  string
+    }{
+        {"everyone active", em.Query(), "[1 2 3 4]"},
+        {"including leavers", em.Query().IncludeTerminated(), "[1 2 3 4 5]"},
+        {"name contains", em.Query().NameContains("RAO"), "[1 4]"},
+        {"name prefix on last name", em.Query().NamePrefix("iy"), "[3]"},
+        {"name prefix mid-word", em.Query().NamePrefix("ao"), "[]"},
+        {"age range", em.Query().AgeBetween(29, 40), "[1 4]"},
+        {"minimum age only", em.Query().AgeBetween(40, 0), "[2]"},
+        {"departments", em.Query().InDepartments("it", "finance"), "[1 3 4]"},
+        {"repeated department", em.Query().InDepartments("IT", "it").InDepartments(IT_DEPT), "[1 3]"},
+        {"leaver in department", em.Query().InDepartments(IT_DEPT).IncludeTerminated(), "[1 3 5]"},
+        {"hired between", em.Query().HiredBetween(time.Date(2020, time.January, 1, 0, 0, 0, 0, time.Local), time.Date(2021, time.April, 1, 0, 0, 0, 0, time.Local)), "[1 4]"},
+        {"combined", em.Query().InDepartments(IT_DEPT).AgeBetween(29, 0), "[1]"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            result, err := tt.query.Run()
+            if err != nil {
+                t.Fatal(err)
+            }
+            if got := employeeIDs(result.Employees); got != tt.want {
+                t.Errorf("Run() = %s, want %s", got, tt.want)
+            }
+            if result.Total != len(result.Employees) {
+                t.Errorf("Total = %d, want %d", result.Total, len(result.Employees))
+            }
+        })
+    }
+}
+
+func TestQuerySortAndPaginate(t *testing.T) {
+    em := newQueryTestManager(t)
+
+    tests := []struct {
+        name      string
+        query     *EmployeeQuery
+        want      string
+        wantTotal int
+    }{
+        {"by name", em.Query().SortBy(SORT_BY_NAME, false), "[1 4 3 2]", 4},
+        {"by age descending", em.Query().SortBy(SORT_BY_AGE, true), "[2 4 1 3]", 4},
+        {"ties broken by ID", em.Query().SortBy(SORT_BY_SALARY, false), "[2 3 4 1]", 4},
+        {"ties broken by ID descending", em.Query().SortBy(SORT_BY_SALARY, true), "[1 3 4 2]", 4},
+        {"field name ignores case", em.Query().SortBy("Hire_Date", false), "[2 4 1 3]", 4},
+        {"first page", em.Query().Limit(3), "[1 2 3]", 4},
+        {"second page", em.Query().Offset(3).Limit(3), "[4]", 4},
+        {"past the end", em.Query().Offset(10), "[]", 4},
+        {"page of a repeated department", em.Query().InDepartments(IT_DEPT, "it", "FINANCE").Limit(2), "[1 3]", 3},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            result, err := tt.query.Run()
+            if err != nil {
+                t.Fatal(err)
+            }
+            if got := employeeIDs(result.Employees); got != tt.want || result.Total != tt.wantTotal {
+                t.Errorf("Run() = %s of %d, want %s of %d", got, result.Total, tt.want, tt.wantTotal)
+            }
+        })
+    }
+}
+
+func TestQueryRejectsInvalidOptions(t *testing.T) {
+    em := newQueryTestManager(t)
+
+    for name, query := range map[string]*EmployeeQuery{
+        "sort":   em.Query().SortBy("shoe_size", false),
+        "offset": em.Query().Offset(-1),
+        "limit":  em.Query().Limit(-1),
+    } {
+        _, err := query.Run()
+        var validationErr *ValidationError
+        if !errors.Is(err, ErrInvalidQuery) || !errors.As(err, &validationErr) || validationErr.Field != name {
+            t.Errorf("invalid %s: Run() = %v, want ErrInvalidQuery on %s", name, err, name)
+        }
+    }
+}
+
+func TestQueryNameFuzzy(t *testing.T) {
+    em := newQueryTestManager(t)
+
+    tests := []struct {
+        query       string
+        maxDistance int
+        want        string
+    }{
+        {"meera", 0, "[3]"},
+        {"mera", 1, "[3]"},
+        {"mera", 0, "[]"},
+        {"rav nar", 2, "[2]"},
+        {"roa", 2, "[1 4]"},
+        {"kiran rao", 0, "[4]"},
+    }
+
+    for _, tt := range tests {
+        result, err := em.Query().NameFuzzy(tt.query, tt.maxDistance).Run()
+        if err != nil {
+            t.Fatal(err)
+        }
+        if got := employeeIDs(result.Employees); got != tt.want {
+            t.Errorf("NameFuzzy(%q, %d) = %s, want %s", tt.query, tt.maxDistance, got, tt.want)
+        }
+    }
+}
+
+func TestLevenshtein(t *testing.T) {
+    tests := []struct {
+        a, b string
+        want int
+    }{
+        {"", "", 0},
+        {"", "abc", 3},
+        {"abc", "", 3},
+        {"kitten", "sitting", 3},
+        {"flaw", "lawn", 2},
+        {"rao", "roa", 2},
+        {"meera", "mera", 1},
+        {"ganesh", "gaṇesh", 1},
+    }
+
+    for _, tt := range tests {
+        if got := levenshtein(tt.a, tt.b); got != tt.want {
+            t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+        }
+        if got := levenshtein(tt.b, tt.a); got != tt.want {
+            t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+
+    _ "github.com/mattn/go-sqlite3"
+)
+
+// Storage backend names accepted by OpenStore
+const (
+    JSON_STORE   = "json"
+    SQLITE_STORE = "sqlite"
+)
+
+// Roster is everything a Store persists
+type Roster struct {
+    Departments []Department `json:"departments"`
+    Employees   []Employee   `json:"employees"`
+}
+
+// Store persists the employee roster between runs
+type Store interface {
+    // Load returns the roster held by the store
+    Load() (Roster, error)
+    // Save replaces the stored roster as a single transaction
+    Save(roster Roster) error
+    // Close releases any resources held by the store
+    Close() error
+}
+
+// OpenStore opens the storage backend of the given kind at path
+func OpenStore(kind string, path string) (Store, error) {
+    switch kind {
+    case JSON_STORE:
+        if path == "" {
+            path = "employees.json"
+        }
+        return NewJSONStore(path), nil
+    case SQLITE_STORE:
+        if path == "" {
+            path = "employees.db"
+        }
+        return NewSQLiteStore(path)
+    default:
+        return nil, fmt.Errorf("unknown storage backend: %s", kind)
+    }
+}
+
+// JSONStore keeps the roster in a single JSON file
+type JSONStore struct {
+    path string
+}
+
+// NewJSONStore creates a JSONStore that reads and writes the file at path
+func NewJSONStore(path string) *JSONStore {
+    return &JSONStore{path: path}
+}
+
+// Load reads the roster from the JSON file, returning an empty roster if the file does not exist yet
+func (js *JSONStore) Load() (Roster, error) {
+    var roster Roster
+
+    data, err := os.ReadFile(js.path)
+    if errors.Is(err, os.ErrNotExist) {
+        return roster, nil
+    }
+    if err != nil {
+        return roster, err
+    }
+
+    if err := json.Unmarshal(data, &roster); err != nil {
+        return roster, fmt.Errorf("invalid employee file %s: %w", js.path, err)
+    }
+    return roster, nil
+}
+
+// Save writes the roster to a temporary file and renames it over the old one,
+// so a crash mid-write never leaves a half-written roster behind. The file keeps
+// its permissions; a new one is created readable by everyone
+func (js *JSONStore) Save(roster Roster) error {
+    data, err := json.MarshalIndent(roster, "", "  ")
+    if err != nil {
+        return err
+    }
+
+    mode := os.FileMode(0644)
+    if info, err := os.Stat(js.path); err == nil {
+        mode = info.Mode().Perm()
+    }
+
+    tmp, err := os.CreateTemp(filepath.Dir(js.path), filepath.Base(js.path)+".tmp*")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+
+    // CreateTemp makes the file private to its owner
+    if err := tmp.Chmod(mode); err != nil {
+        tmp.Close()
+        return err
+    }
+    if _, err := tmp.Write(data); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Sync(); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+    return os.Rename(tmp.Name(), js.path)
+}
+
+// Close is a no-op for JSONStore
+func (js *JSONStore) Close() error {
+    return nil
+}
+
+// SQLiteStore keeps the roster in an SQLite database. It remembers the roster it last
+// loaded or saved, so a save only writes the rows that changed
+type SQLiteStore struct {
+    db          *sql.DB
+    synced      bool
+    employees   map[int]Employee
+    departments []Department
+}
+
+// NewSQLiteStore opens the SQLite database at path and creates the tables if needed.
+// Dates are read back in local time, as they were written
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+    separator := "?"
+    if strings.Contains(path, "?") {
+        separator = "&"
+    }
+    db, err := sql.Open("sqlite3", path+separator+"_loc=auto")
+    if err != nil {
+        return nil, err
+    }
+
+    // Create tables if they don't exist
+    query := `
+    CREATE TABLE IF NOT EXISTS employees (
+        id INTEGER PRIMARY KEY,
+        name TEXT NOT NULL,
+        age INTEGER NOT NULL,
+        department TEXT NOT NULL,
+        termination_date DATETIME,
+        manager_id INTEGER NOT NULL DEFAULT 0,
+        email TEXT NOT NULL DEFAULT '',
+        phone TEXT NOT NULL DEFAULT '',
+        hire_date DATETIME,
+        salary REAL NOT NULL DEFAULT 0,
+        title TEXT NOT NULL DEFAULT '',
+        status TEXT NOT NULL DEFAULT ''
+    );
+    CREATE TABLE IF NOT EXISTS departments (
+        position INTEGER PRIMARY KEY,
+        name TEXT NOT NULL UNIQUE,
+        parent TEXT NOT NULL DEFAULT '',
+        retired BOOLEAN NOT NULL DEFAULT 0
+    );`
+    if _, err := db.Exec(query); err != nil {
+        db.Close()
+        return nil, err
+    }
+
+    return &SQLiteStore{db: db}, nil
+}
+
+// Load reads every department in creation order and every employee ordered by ID
+func (ss *SQLiteStore) Load() (Roster, error) {
+    var roster Roster
+
+    deptRows, err := ss.db.Query("SELECT name, parent, retired FROM departments ORDER BY position")
+    if err != nil {
+        return roster, err
+    }
+    defer deptRows.Close()
+
+    for deptRows.Next() {
+        var dept Department
+        if err := deptRows.Scan(&dept.Name, &dept.Parent, &dept.Retired); err != nil {
+            return roster, err
+        }
+        roster.Departments = append(roster.Departments, dept)
+    }
+    if err := deptRows.Err(); err != nil {
+        return roster, err
+    }
+
+    query := `
+    SELECT id, name, age, department, termination_date, manager_id,
+        email, phone, hire_date, salary, title, status
+    FROM employees ORDER BY id`
+    rows, err := ss.db.Query(query)
+    if err != nil {
+        return roster, err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var emp Employee
+        var terminationDate, hireDate sql.NullTime
+        err := rows.Scan(&emp.ID, &emp.Name, &emp.Age, &emp.Department, &terminationDate, &emp.ManagerID,
+            &emp.Email, &emp.Phone, &hireDate, &emp.Salary, &emp.Title, &emp.Status)
+        if err != nil {
+            return roster, err
+        }
+        if terminationDate.Valid {
+            emp.TerminationDate = &terminationDate.Time
+        }
+        if hireDate.Valid {
+            emp.HireDate = hireDate.Time
+        }
+        roster.Employees = append(roster.Employees, emp)
+    }
+    if err := rows.Err(); err != nil {
+        return roster, err
+    }
+
+    ss.remember(roster)
+    return roster, nil
+}
+
+// Save brings both tables in line with roster inside a single transaction. Only employees
+// that were added, changed or removed since the last load or save are written; the first
+// save after opening rewrites everything, as the store does not yet know what is there
+func (ss *SQLiteStore) Save(roster Roster) error {
+    tx, err := ss.db.Begin()
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    if !ss.synced || !reflect.DeepEqual(ss.departments, roster.Departments) {
+        if _, err := tx.Exec("DELETE FROM departments"); err != nil {
+            return err
+        }
+        for i, dept := range roster.Departments {
+            query := "INSERT INTO departments (position, name, parent, retired) VALUES (?, ?, ?, ?)"
+            if _, err := tx.Exec(query, i, dept.Name, dept.Parent, dept.Retired); err != nil {
+                return err
+            }
+        }
+    }
+
+    if !ss.synced {
+        if _, err := tx.Exec("DELETE FROM employees"); err != nil {
+            return err
+        }
+    }
+
+    stmt, err := tx.Prepare(`
+    INSERT OR REPLACE INTO employees (id, name, age, department, termination_date, manager_id,
+        email, phone, hire_date, salary, title, status)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+    if err != nil {
+        return err
+    }
+    defer stmt.Close()
+
+    kept := make(map[int]bool, len(roster.Employees))
+    for _, emp := range roster.Employees {
+        kept[emp.ID] = true
+        if saved, ok := ss.employees[emp.ID]; ss.synced && ok && reflect.DeepEqual(saved, emp) {
+            continue
+        }
+        _, err := stmt.Exec(emp.ID, emp.Name, emp.Age, emp.Department, emp.TerminationDate, emp.ManagerID,
+            emp.Email, emp.Phone, emp.HireDate, emp.Salary, emp.Title, emp.Status)
+        if err != nil {
+            return err
+        }
+    }
+    if ss.synced {
+        for id := range ss.employees {
+            if kept[id] {
+                continue
+            }
+            if _, err := tx.Exec("DELETE FROM employees WHERE id = ?", id); err != nil {
+                return err
+            }
+        }
+    }
+
+    if err := tx.Commit(); err != nil {
+        return err
+    }
+    ss.remember(roster)
+    return nil
+}
+
+// remember records roster as what the database now holds
+func (ss *SQLiteStore) remember(roster Roster) {
+    ss.employees = make(map[int]Employee, len(roster.Employees))
+    for _, emp := range roster.Employees {
+        ss.employees[emp.ID] = *cloneEmployee(&emp)
+    }
+    ss.departments = append([]Department(nil), roster.Departments...)
+    ss.synced = true
+}
+
+// Close closes the underlying database
+func (ss *SQLiteStore) Close() error {
+    return ss.db.Close()
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+    "time"
+)
+
+// storeTestRoster returns a roster using every stored field, with dates at local midnight
+func storeTestRoster() Roster {
+    left := time.Date(2024, time.March, 31, 0, 0, 0, 0, time.Local)
+    return Roster{
+        Departments: []Department{{Name: HR_DEPT}, {Name: IT_DEPT}, {Name: "SUPPORT", Parent: IT_DEPT}, {Name: "LEGAL", Retired: true}},
+        Employees: []Employee{
+            {ID: 1, Name: "Asha Rao", Age: 41, Department: IT_DEPT, Email: "asha@example.com", Phone: "+91 98765 43210",
+                HireDate: time.Date(2019, time.July, 1, 0, 0, 0, 0, time.Local), Salary: 1850000, Title: "Lead", Status: STATUS_ACTIVE},
+            {ID: 2, Name: "Ravi Nair", Age: 29, Department: "SUPPORT", ManagerID: 1,
+                HireDate: time.Date(2022, time.January, 10, 0, 0, 0, 0, time.Local), Status: STATUS_ON_LEAVE},
+            {ID: 3, Name: "Meera Iyer", Age: 35, Department: HR_DEPT,
+                HireDate: time.Date(2020, time.May, 4, 0, 0, 0, 0, time.Local), Status: STATUS_TERMINATED, TerminationDate: &left},
+        },
+    }
+}
+
+// useTimeZone makes time.Local a zone east of UTC for the rest of the test, so dates read back
+// in UTC land on the previous day
+func useTimeZone(t *testing.T) {
+    t.Helper()
+    local := time.Local
+    time.Local = time.FixedZone("IST", 5*60*60+30*60)
+    t.Cleanup(func() { time.Local = local })
+}
+
+// sameRoster fails the test unless got holds the same departments and employees as want,
+// with every date read back in local time on the same day
+func sameRoster(t *testing.T, got Roster, want Roster) {
+    t.Helper()
+    if !reflect.DeepEqual(got.Departments, want.Departments) {
+        t.Errorf("departments = %+v, want %+v", got.Departments, want.Departments)
+    }
+    if len(got.Employees) != len(want.Employees) {
+        t.Fatalf("loaded %d employees, want %d", len(got.Employees), len(want.Employees))
+    }
+    for i := range want.Employees {
+        g, w := got.Employees[i], want.Employees[i]
+        if !dateOnly(g.HireDate).Equal(w.HireDate) || g.HireDate.Location() != time.Local {
+            t.Errorf("employee %d hire date = %v, want %v in local time", w.ID, g.HireDate, w.HireDate)
+        }
+        if (g.TerminationDate == nil) != (w.TerminationDate == nil) ||
+            (w.TerminationDate != nil && !dateOnly(*g.TerminationDate).Equal(*w.TerminationDate)) {
+            t.Errorf("employee %d termination date = %v, want %v", w.ID, g.TerminationDate, w.TerminationDate)
+        }
+        g.HireDate, g.TerminationDate = w.HireDate, w.TerminationDate
+        if !reflect.DeepEqual(g, w) {
+            t.Errorf("employee %d = %+v, want %+v", w.ID, g, w)
+        }
+    }
+}
+
+func TestJSONStoreRoundTrip(t *testing.T) {
+    useTimeZone(t)
+    path := filepath.Join(t.TempDir(), "employees.json")
+    store := NewJSONStore(path)
+
+    if roster, err := store.Load(); err != nil || len(roster.Employees) != 0 {
+        t.Fatalf("loading a missing file = %d employees, %v; want an empty roster", len(roster.Employees), err)
+    }
+
+    want := storeTestRoster()
+    if err := store.Save(want); err != nil {
+        t.Fatal(err)
+    }
+    got, err := NewJSONStore(path).Load()
+    if err != nil {
+        t.Fatal(err)
+    }
+    sameRoster(t, got, want)
+}
+
+func TestJSONStoreKeepsPermissions(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "employees.json")
+    store := NewJSONStore(path)
+    if err := store.Save(storeTestRoster()); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.Chmod(path, 0640); err != nil {
+        t.Fatal(err)
+    }
+    if err := store.Save(storeTestRoster()); err != nil {
+        t.Fatal(err)
+    }
+
+    info, err := os.Stat(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if info.Mode().Perm() != 0640 {
+        t.Errorf("saving changed the file mode to %v, want %v", info.Mode().Perm(), os.FileMode(0640))
+    }
+}
+
+func TestSQLiteStoreRoundTrip(t *testing.T) {
+    useTimeZone(t)
+    path := filepath.Join(t.TempDir(), "employees.db")
+    store, err := NewSQLiteStore(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer store.Close()
+
+    want := storeTestRoster()
+    if err := store.Save(want); err != nil {
+        t.Fatal(err)
+    }
+
+    // Change one employee, drop another and add a third, as an update and a rolled back import would
+    want.Employees[0].Title = "Principal"
+    want.Employees = append(want.Employees[:1], want.Employees[2],
+        Employee{ID: 4, Name: "Kiran Das", Age: 24, Department: HR_DEPT, HireDate: time.Date(2024, time.June, 3, 0, 0, 0, 0, time.Local), Status: STATUS_ACTIVE})
+    want.Departments = append(want.Departments, Department{Name: "OPS"})
+    if err := store.Save(want); err != nil {
+        t.Fatal(err)
+    }
+
+    reopened, err := NewSQLiteStore(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer reopened.Close()
+    got, err := reopened.Load()
+    if err != nil {
+        t.Fatal(err)
+    }
+    sameRoster(t, got, want)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+    "net/mail"
+    "strings"
+    "time"
+)
+
+// validateRecord checks emp against every field rule, normalizing its department,
+// email and status in place. Callers must hold em.mu
+func (em *EmployeeManager) validateRecord(emp *Employee) error {
+    // Validate age
+    if emp.Age < 18 {
+        return &ValidationError{Field: "age", Err: ErrUnderage}
+    }
+
+    // Validate department
+    emp.Department = normalizeDepartment(emp.Department)
+    if !em.departments.IsActive(emp.Department) {
+        return &ValidationError{Field: "department", Value: emp.Department, Err: ErrInvalidDepartment}
+    }
+
+    // Validate email format and uniqueness; email is optional
+    emp.Email = strings.TrimSpace(emp.Email)
+    if emp.Email != "" {
+        addr, err := mail.ParseAddress(emp.Email)
+        if err != nil || addr.Address != emp.Email {
+            return &ValidationError{Field: "email", Value: emp.Email, Err: ErrInvalidEmail}
+        }
+        if pos := em.index.emailPosition(emp.Email); pos >= 0 && em.employees[pos].ID != emp.ID {
+            return &ValidationError{Field: "email", Value: emp.Email, Err: ErrDuplicateEmail}
+        }
+    }
+
+    // Validate phone; phone is optional
+    emp.Phone = strings.TrimSpace(emp.Phone)
+    if emp.Phone != "" && !isValidPhone(emp.Phone) {
+        return &ValidationError{Field: "phone", Value: emp.Phone, Err: ErrInvalidPhone}
+    }
+
+    // Validate hire date
+    if emp.HireDate.After(time.Now()) {
+        return &ValidationError{Field: "hire_date", Value: emp.HireDate.Format("2006-01-02"), Err: ErrFutureHireDate}
+    }
+
+    // Validate salary
+    if emp.Salary < 0 {
+        return &ValidationError{Field: "salary", Err: ErrInvalidSalary}
+    }
+
+    // Validate status
+    if emp.Status == "" {
+        emp.Status = STATUS_ACTIVE
+    }
+    emp.Status = strings.ToUpper(emp.Status)
+    if emp.Status != STATUS_ACTIVE && emp.Status != STATUS_ON_LEAVE && emp.Status != STATUS_TERMINATED {
+        return &ValidationError{Field: "status", Value: emp.Status, Err: ErrInvalidStatus}
+    }
+    return nil
+}
+
+// isValidPhone accepts an optional leading +, digits, spaces, dashes and
+// parentheses, with between 7 and 15 digits in total
+func isValidPhone(phone string) bool {
+    digits := 0
+    for i, r := range phone {
+        switch {
+        case r >= '0' && r <= '9':
+            digits++
+        case r == '+' && i == 0:
+        case r == ' ' || r == '-' || r == '(' || r == ')':
+        default:
+            return false
+        }
+    }
+    return digits >= 7 && digits <= 15
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+    "testing"
+    "time"
+)
+
+// newAccountTypeTestBank returns an in-memory bank with fraud rules off and account 1
+// opened as accountType at opened, with no withdrawal limits
+func newAccountTypeTestBank(t *testing.T, accountType string, opened time.Time) (*BankSystem, *Account) {
+    t.Helper()
+    bs := NewBankSystem()
+    bs.SetFraudRules()
+    acc, err := bs.CreateAccount(1, "Asha Rao", accountType)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := bs.SetWithdrawalLimits(1, WithdrawalLimits{}); err != nil {
+        t.Fatal(err)
+    }
+    acc.mu.Lock()
+    acc.OpenedAt = opened
+    acc.mu.Unlock()
+    return bs, acc
+}
+
+// depositOn posts a deposit to an account dated at, as if it had been made then
+func depositOn(t *testing.T, bs *BankSystem, acc *Account, amount Money, at time.Time) {
+    t.Helper()
+    bs.mu.RLock()
+    defer bs.mu.RUnlock()
+    acc.mu.Lock()
+    defer acc.mu.Unlock()
+
+    balance, _ := bs.balanceOf(acc.ID).Add(amount)
+    record := postRecord(JournalEntry{Description: "Dated deposit", Postings: transfer(CASH_LEDGER, customerLedger(acc.ID), amount)},
+        walEntry{AccountID: acc.ID, Transaction: Transaction{Type: DEPOSIT_TYPE, Amount: amount, BalanceAfter: balance}})
+    record.Timestamp = at
+    if err := bs.commit(record); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestRunEndOfDay(t *testing.T) {
+    day := func(month time.Month, d int) time.Time {
+        return time.Date(2024, month, d, 12, 0, 0, 0, time.Local)
+    }
+    type deposit struct {
+        at    time.Time
+        paise int64
+    }
+
+    // Rs. 36,500 at 4% earns exactly Rs. 4 a day
+    tests := []struct {
+        name        string
+        deposits    []deposit
+        through     time.Time
+        wantPosted  string
+        wantAccrued int64
+        wantBalance int64
+    }{
+        {"daily accrual", []deposit{{day(time.January, 1), 3650000}}, day(time.January, 10), "", 4000, 3650000},
+        {"opening day only", []deposit{{day(time.January, 1), 3650000}}, day(time.January, 1), "", 400, 3650000},
+        {
+            "each day on its own closing balance",
+            []deposit{{day(time.January, 1), 3650000}, {day(time.January, 6), 3650000}},
+            day(time.January, 10), "", 5*400 + 5*800, 7300000,
+        },
+        {
+            "deposit after the last day processed",
+            []deposit{{day(time.January, 1), 3650000}, {day(time.January, 6), 3650000}},
+            day(time.January, 5), "", 5 * 400, 7300000,
+        },
+        {"month-end posting", []deposit{{day(time.January, 1), 3650000}}, day(time.January, 31), "INTEREST 12400", 0, 3662400},
+        // February accrues on January's balance plus its interest: 2 days of Rs. 36,624 at 4%
+        {"posted interest earns interest", []deposit{{day(time.January, 1), 3650000}}, day(time.February, 2), "INTEREST 12400", 802, 3662400},
+        {"minimum balance penalty", []deposit{{day(time.January, 1), 50000}}, day(time.January, 31), "INTEREST 169, PENALTY 10000", 0, 40169},
+        {"penalty stops at zero", []deposit{{day(time.January, 1), 5000}}, day(time.January, 31), "INTEREST 16, PENALTY 5016", 0, 0},
+        // 15 days at Rs. 500 and 16 at Rs. 2,000 average Rs. 1,274, above the Rs. 1,000 minimum
+        {
+            "average above the minimum",
+            []deposit{{day(time.January, 1), 50000}, {day(time.January, 16), 150000}},
+            day(time.January, 31), "INTEREST 432", 0, 200432,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            bs, acc := newAccountTypeTestBank(t, SAVINGS_ACCOUNT, time.Date(2024, time.January, 1, 9, 0, 0, 0, time.Local))
+            for _, d := range tt.deposits {
+                depositOn(t, bs, acc, INR(d.paise), d.at)
+            }
+
+            posted, err := bs.RunEndOfDay(tt.through)
+            if err != nil {
+                t.Fatal(err)
+            }
+            // Running again for the same day changes nothing
+            if again, err := bs.RunEndOfDay(tt.through); err != nil || len(again) != 0 {
+                t.Errorf("second run posted %v, %v; want nothing", again, err)
+            }
+
+            lines := make([]string, len(posted))
+            for i, tx := range posted {
+                lines[i] = fmt.Sprintf("%s %d", tx.Type, tx.Amount.Minor)
+            }
+            if got := strings.Join(lines, ", "); got != tt.wantPosted {
+                t.Errorf("posted %q, want %q", got, tt.wantPosted)
+            }
+            if accrued, _ := bs.AccruedInterest(1); accrued != INR(tt.wantAccrued) {
+                t.Errorf("accrued %v, want %v", accrued, INR(tt.wantAccrued))
+            }
+            wantBalances(t, bs, tt.wantBalance)
+        })
+    }
+}
+
+func TestRunEndOfDayCatchUpBalances(t *testing.T) {
+    opened := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.Local)
+    later := time.Date(2024, time.February, 5, 12, 0, 0, 0, time.Local)
+
+    // End of day runs only after a deposit made in February, so the month-end postings
+    // must carry January 31's balance rather than the current one
+    tests := []struct {
+        name   string
+        before int64
+        want   string
+    }{
+        {"interest", 3650000, "INTEREST 12400 -> 3662400"},
+        {"interest and penalty", 50000, "INTEREST 169 -> 50169, PENALTY 10000 -> 40169"},
+        {"penalty stops at that day's balance", 5000, "INTEREST 16 -> 5016, PENALTY 5016 -> 0"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            bs, acc := newAccountTypeTestBank(t, SAVINGS_ACCOUNT, opened)
+            depositOn(t, bs, acc, INR(tt.before), opened)
+            depositOn(t, bs, acc, INR(1000000), later)
+
+            posted, err := bs.RunEndOfDay(later)
+            if err != nil {
+                t.Fatal(err)
+            }
+            lines := make([]string, len(posted))
+            for i, tx := range posted {
+                lines[i] = fmt.Sprintf("%s %d -> %d", tx.Type, tx.Amount.Minor, tx.BalanceAfter.Minor)
+            }
+            if got := strings.Join(lines, ", "); got != tt.want {
+                t.Errorf("posted %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestOverdraftLimit(t *testing.T) {
+    tests := []struct {
+        name        string
+        accountType string
+        amount      int64
+        wantErr     string
+    }{
+        {"savings down to zero", SAVINGS_ACCOUNT, 10000, ""},
+        {"savings below zero", SAVINGS_ACCOUNT, 10001, "Current balance: Rs. 100.00"},
+        {"current to the overdraft limit", CURRENT_ACCOUNT, 1010000, ""},
+        {"current past the overdraft limit", CURRENT_ACCOUNT, 1010001, "overdraft limit of Rs. 10000.00 exceeded"},
+    }
+
+    for _, tt := range tests {
+        bs, _ := newAccountTypeTestBank(t, tt.accountType, time.Now())
+        if _, err := bs.Deposit(1, INR(10000)); err != nil {
+            t.Fatal(err)
+        }
+
+        _, err := bs.Withdraw(1, INR(tt.amount))
+        if tt.wantErr == "" {
+            if err != nil {
+                t.Errorf("%s: %v", tt.name, err)
+            }
+            continue
+        }
+        if !errors.Is(err, ErrInsufficientBalance) || !strings.Contains(err.Error(), tt.wantErr) {
+            t.Errorf("%s = %v, want ErrInsufficientBalance mentioning %q", tt.name, err, tt.wantErr)
+        }
+        wantBalances(t, bs, 10000)
+    }
+}
+
+func TestFixedDepositLockIn(t *testing.T) {
+    tests := []struct {
+        name       string
+        openedAgo  int
+        wantLocked bool
+    }{
+        {"opened today", 0, true},
+        {"a day before the lock-in ends", 364, true},
+        {"lock-in over", 365, false},
+    }
+
+    for _, tt := range tests {
+        opened := time.Now().AddDate(0, 0, -tt.openedAgo)
+        bs, acc := newAccountTypeTestBank(t, FIXED_DEPOSIT, opened)
+        if _, err := bs.Deposit(1, INR(100000)); err != nil {
+            t.Fatal(err)
+        }
+        if want := dateOnly(opened).AddDate(0, 0, 365); !acc.LockedUntil().Equal(want) {
+            t.Errorf("%s: locked until %v, want %v", tt.name, acc.LockedUntil(), want)
+        }
+
+        _, withdrawErr := bs.Withdraw(1, INR(100))
+        _, closeErr := bs.CloseAccount(1)
+        if tt.wantLocked {
+            if !errors.Is(withdrawErr, ErrAccountLocked) || !errors.Is(closeErr, ErrAccountLocked) {
+                t.Errorf("%s: withdrawing = %v and closing = %v, want ErrAccountLocked", tt.name, withdrawErr, closeErr)
+            }
+            continue
+        }
+        if withdrawErr != nil || closeErr != nil {
+            t.Errorf("%s: withdrawing = %v and closing = %v, want both allowed", tt.name, withdrawErr, closeErr)
+        }
+    }
+
+    // Other account types have no lock-in
+    _, savings := newAccountTypeTestBank(t, SAVINGS_ACCOUNT, time.Now())
+    if until := savings.LockedUntil(); !until.IsZero() {
+        t.Errorf("savings account locked until %v", until)
+    }
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+    "time"
+)
+
+// What a fraud rule does with a withdrawal it matches
+const (
+    FRAUD_ALLOW = "ALLOW"
+    FRAUD_FLAG  = "FLAG"
+    FRAUD_BLOCK = "BLOCK"
+)
+
+// Review status of a flagged transaction
+const (
+    FLAG_PENDING   = "PENDING"
+    FLAG_CLEARED   = "CLEARED"
+    FLAG_CONFIRMED = "CONFIRMED_FRAUD"
+)
+
+// WithdrawalLimits cap how much can leave an account. A zero limit means no limit
+type WithdrawalLimits struct {
+    PerTransaction Money
+    // Daily caps the total withdrawn and transferred out since midnight
+    Daily Money
+}
+
+// FraudRule screens a withdrawal or outgoing transfer against the account's recent history.
+// Check returns the action to take and why, or FRAUD_ALLOW if the rule does not match
+type FraudRule interface {
+    Check(history []Transaction, amount Money, now time.Time) (string, string)
+}
+
+// VelocityRule matches when a withdrawal would make more than MaxCount withdrawals
+// within Window
+type VelocityRule struct {
+    MaxCount int
+    Window   time.Duration
+    Action   string
+}
+
+// UnusualAmountRule matches a withdrawal of more than Multiplier times the average of the
+// account's last Lookback withdrawals. Accounts with fewer than MinHistory withdrawals are
+// not checked, as they have no usual amount yet
+type UnusualAmountRule struct {
+    Multiplier int64
+    Lookback   int
+    MinHistory int
+    Action     string
+}
+
+// FlaggedTransaction is a withdrawal a fraud rule flagged or blocked, awaiting review.
+// A flagged withdrawal was posted and TransactionID is its entry on the account; a blocked
+// one was refused and has no transaction
+type FlaggedTransaction struct {
+    ID            int64
+    AccountID     int
+    TransactionID int64 `json:",omitempty"`
+    Type          string
+    Amount        Money
+    Action        string
+    Reasons       []string
+    FlaggedAt     time.Time
+    Status        string
+    ReviewedAt    time.Time
+    Note          string `json:",omitempty"`
+}
+
+// DefaultFraudRules returns the rules a new bank screens withdrawals with
+func DefaultFraudRules() []FraudRule {
+    return []FraudRule{
+        VelocityRule{MaxCount: 5, Window: 10 * time.Minute, Action: FRAUD_BLOCK},
+        VelocityRule{MaxCount: 3, Window: 10 * time.Minute, Action: FRAUD_FLAG},
+        UnusualAmountRule{Multiplier: 5, Lookback: 20, MinHistory: 3, Action: FRAUD_FLAG},
+    }
+}
+
+// Check counts the withdrawals made within the window
+func (r VelocityRule) Check(history []Transaction, amount Money, now time.Time) (string, string) {
+    since := now.Add(-r.Window)
+    count := 1
+    for _, t := range history {
+        if isWithdrawal(t) && !t.Timestamp.Before(since) {
+            count++
+        }
+    }
+    if count <= r.MaxCount {
+        return FRAUD_ALLOW, ""
+    }
+    return r.Action, fmt.Sprintf("%d withdrawals within %v, more than %d", count, r.Window, r.MaxCount)
+}
+
+// Check compares the amount with the average of recent withdrawals
+func (r UnusualAmountRule) Check(history []Transaction, amount Money, now time.Time) (string, string) {
+    var total int64
+    count := 0
+    for i := len(history) - 1; i >= 0 && count < r.Lookback; i-- {
+        if isWithdrawal(history[i]) {
+            total += history[i].Amount.Minor
+            count++
+        }
+    }
+    if count == 0 || count < r.MinHistory {
+        return FRAUD_ALLOW, ""
+    }
+
+    average := total / int64(count)
+    if amount.Minor <= average*r.Multiplier {
+        return FRAUD_ALLOW, ""
+    }
+    return r.Action, fmt.Sprintf("%v is more than %d times the usual withdrawal of %v", amount, r.Multiplier, NewMoney(average, amount.Currency))
+}
+
+// isWithdrawal reports whether a transaction took money out at the customer's request
+func isWithdrawal(t Transaction) bool {
+    return t.Type == WITHDRAW_TYPE || t.Type == TRANSFER_OUT_TYPE
+}
+
+// SetFraudRules replaces the rules withdrawals are screened with. No rules turns screening off
+func (bs *BankSystem) SetFraudRules(rules ...FraudRule) {
+    bs.mu.Lock()
+    defer bs.mu.Unlock()
+    bs.fraudRules = append([]FraudRule(nil), rules...)
+}
+
+// SetWithdrawalLimits changes the withdrawal limits of an open account
+func (bs *BankSystem) SetWithdrawalLimits(id int, limits WithdrawalLimits) error {
+    defer bs.maybeCheckpoint()
+
+    if limits.PerTransaction.Minor < 0 || limits.Daily.Minor < 0 {
+        return fmt.Errorf("withdrawal limits cannot be negative: %w", ErrInvalidAmount)
+    }
+
+    bs.mu.RLock()
+    defer bs.mu.RUnlock()
+
+    account, err := bs.findOpenAccount(id)
+    if err != nil {
+        return err
+    }
+
+    account.mu.Lock()
+    defer account.mu.Unlock()
+    if account.Closed {
+        return fmt.Errorf("account with ID %d %w", id, ErrAccountClosed)
+    }
+    return bs.commit(walRecord{Op: WAL_SET_LIMITS, AccountID: id, Limits: &limits})
+}
+
+// WithdrawalLimits returns the account's current withdrawal limits
+func (a *Account) WithdrawalLimits() WithdrawalLimits {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    return a.Limits
+}
+
+// WithdrawnToday returns how much has been withdrawn and transferred out of an account since midnight
+func (bs *BankSystem) WithdrawnToday(id int) (Money, error) {
+    bs.mu.RLock()
+    defer bs.mu.RUnlock()
+
+    account, err := bs.findAnyAccount(id)
+    if err != nil {
+        return Money{}, err
+    }
+
+    account.mu.Lock()
+    defer account.mu.Unlock()
+    return withdrawnSince(account.Transactions, dateOnly(time.Now()), bs.journal.currency), nil
+}
+
+// withdrawnSince totals the withdrawals in history made at or after since. History is not in
+// timestamp order, as month-end interest is posted dated to the end of the month, so every
+// entry is checked
+func withdrawnSince(history []Transaction, since time.Time, currency string) Money {
+    total := NewMoney(0, currency)
+    for _, t := range history {
+        if isWithdrawal(t) && !t.Timestamp.Before(since) {
+            total, _ = total.Add(t.Amount)
+        }
+    }
+    return total
+}
+
+// screenDebit checks a withdrawal or outgoing transfer against the account's limits and the
+// fraud rules. A withdrawal over a limit is refused. One a rule blocks is refused and queued
+// for review; one a rule flags is returned as a flag to post with it. Callers must hold bs.mu
+// and a.mu
+func (bs *BankSystem) screenDebit(a *Account, txType string, amount Money) (*FlaggedTransaction, error) {
+    now := time.Now()
+
+    if limit := a.Limits.PerTransaction; limit.IsPositive() && amount.Minor > limit.Minor {
+        return nil, fmt.Errorf("%w: %v is over the per-transaction limit of %v", ErrWithdrawalLimitExceeded, amount, limit)
+    }
+    if limit := a.Limits.Daily; limit.IsPositive() {
+        today := withdrawnSince(a.Transactions, dateOnly(now), amount.Currency)
+        if today.Minor+amount.Minor > limit.Minor {
+            return nil, fmt.Errorf("%w: %v withdrawn today of a daily limit of %v", ErrWithdrawalLimitExceeded, today, limit)
+        }
+    }
+
+    flag := &FlaggedTransaction{AccountID: a.ID, Type: txType, Amount: amount, Action: FRAUD_ALLOW, Status: FLAG_PENDING}
+    for _, rule := range bs.fraudRules {
+        action, reason := rule.Check(a.Transactions, amount, now)
+        if action == FRAUD_ALLOW {
+            continue
+        }
+        flag.Reasons = append(flag.Reasons, reason)
+        if action == FRAUD_BLOCK || flag.Action == FRAUD_ALLOW {
+            flag.Action = action
+        }
+    }
+
+    switch flag.Action {
+    case FRAUD_BLOCK:
+        if err := bs.commit(walRecord{Op: WAL_FLAG, AccountID: a.ID, Flag: flag}); err != nil {
+            return nil, err
+        }
+        return nil, fmt.Errorf("%w: %s", ErrTransactionBlocked, strings.Join(flag.Reasons, "; "))
+    case FRAUD_FLAG:
+        return flag, nil
+    default:
+        return nil, nil
+    }
+}
+
+// FlaggedTransactions returns the review queue, oldest first. An empty status returns
+// every flag; otherwise only those with that status
+func (bs *BankSystem) FlaggedTransactions(status string) []FlaggedTransaction {
+    bs.flagMu.Lock()
+    defer bs.flagMu.Unlock()
+
+    flags := make([]FlaggedTransaction, 0)
+    for _, flag := range bs.flags {
+        if status == "" || strings.EqualFold(flag.Status, status) {
+            flags = append(flags, flag)
+        }
+    }
+    return flags
+}
+
+// FlagForTransaction returns the flag raised on a posted transaction, if any
+func (bs *BankSystem) FlagForTransaction(transactionID int64) (FlaggedTransaction, bool) {
+    bs.flagMu.Lock()
+    defer bs.flagMu.Unlock()
+
+    for i := len(bs.flags) - 1; i >= 0; i-- {
+        if bs.flags[i].TransactionID == transactionID {
+            return bs.flags[i], true
+        }
+    }
+    return FlaggedTransaction{}, false
+}
+
+// ReviewFlag records the decision on a pending flag: FLAG_CLEARED if the withdrawal was
+// genuine or FLAG_CONFIRMED if it was fraud
+func (bs *BankSystem) ReviewFlag(id int64, status string, note string) (FlaggedTransaction, error) {
+    defer bs.maybeCheckpoint()
+
+    status = strings.ToUpper(status)
+    if status != FLAG_CLEARED && status != FLAG_CONFIRMED {
+        return FlaggedTransaction{}, fmt.Errorf("%w %q", ErrInvalidReview, status)
+    }
+
+    // Reviews hold bs.mu for writing so two reviewers cannot both decide the same flag
+    bs.mu.Lock()
+    defer bs.mu.Unlock()
+
+    var reviewed FlaggedTransaction
+    found := false
+    for _, flag := range bs.FlaggedTransactions("") {
+        if flag.ID == id {
+            reviewed, found = flag, true
+            break
+        }
+    }
+    if !found {
+        return FlaggedTransaction{}, fmt.Errorf("flag %d %w", id, ErrFlagNotFound)
+    }
+    if reviewed.Status != FLAG_PENDING {
+        return FlaggedTransaction{}, fmt.Errorf("flag %d %w as %s", id, ErrFlagReviewed, reviewed.Status)
+    }
+
+    reviewed.Status = status
+    reviewed.Note = note
+    reviewed.ReviewedAt = time.Now()
+    if err := bs.commit(walRecord{Op: WAL_REVIEW_FLAG, AccountID: reviewed.AccountID, Flag: &reviewed}); err != nil {
+        return FlaggedTransaction{}, err
+    }
+    return reviewed, nil
+}
+
+// applyFlag adds a new flag to the review queue or replaces a reviewed one
+func (bs *BankSystem) applyFlag(record walRecord) {
+    bs.flagMu.Lock()
+    defer bs.flagMu.Unlock()
+
+    if record.Op == WAL_REVIEW_FLAG {
+        for i := range bs.flags {
+            if bs.flags[i].ID == record.Flag.ID {
+                bs.flags[i] = *record.Flag
+                return
+            }
+        }
+    }
+    bs.flags = append(bs.flags, *record.Flag)
+}
+
+// String renders the flag as a single review queue line
+func (f FlaggedTransaction) String() string {
+    line := fmt.Sprintf("#%d %s: account %d %s %v - %s - %s", f.ID, f.Status, f.AccountID, f.Type, f.Amount,
+        f.FlaggedAt.Format("2006-01-02 15:04:05"), strings.Join(f.Reasons, "; "))
+    if f.Action == FRAUD_BLOCK {
+        line += " [blocked]"
+    }
+    if f.Note != "" {
+        line += " - " + f.Note
+    }
+    return line
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+    "testing"
+    "time"
+)
+
+// backdatedHistory is two withdrawals followed by month-end interest posted with an
+// older timestamp, as catching up on interest does
+func backdatedHistory(now time.Time) []Transaction {
+    return []Transaction{
+        {Type: WITHDRAW_TYPE, Amount: INR(500), Timestamp: now.Add(-2 * time.Minute)},
+        {Type: TRANSFER_OUT_TYPE, Amount: INR(700), Timestamp: now.Add(-time.Minute)},
+        {Type: INTEREST_TYPE, Amount: INR(12), Timestamp: now.AddDate(0, -1, 0)},
+    }
+}
+
+func TestVelocityRuleLooksPastBackdatedEntries(t *testing.T) {
+    now := time.Now()
+    rule := VelocityRule{MaxCount: 2, Window: 10 * time.Minute, Action: FRAUD_FLAG}
+    if action, reason := rule.Check(backdatedHistory(now), INR(100), now); action != FRAUD_FLAG {
+        t.Errorf("third withdrawal in the window got %s (%s), want %s", action, reason, FRAUD_FLAG)
+    }
+}
+
+func TestWithdrawnSinceLooksPastBackdatedEntries(t *testing.T) {
+    now := time.Now()
+    if total := withdrawnSince(backdatedHistory(now), now.Add(-time.Hour), CURRENCY_INR); total != INR(1200) {
+        t.Errorf("withdrawn in the last hour = %v, want %v", total, INR(1200))
+    }
+}
+
+func TestWithdrawalLimits(t *testing.T) {
+    bs := newTestBank(t, 1000000, 0)
+    if err := bs.SetWithdrawalLimits(1, WithdrawalLimits{PerTransaction: INR(300000), Daily: INR(500000)}); err != nil {
+        t.Fatal(err)
+    }
+    if err := bs.SetWithdrawalLimits(1, WithdrawalLimits{Daily: INR(-1)}); !errors.Is(err, ErrInvalidAmount) {
+        t.Errorf("setting a negative limit = %v, want %v", err, ErrInvalidAmount)
+    }
+
+    steps := []struct {
+        name    string
+        run     func() error
+        wantErr error
+    }{
+        {"over the per-transaction limit", func() error { return discard(bs.Withdraw(1, INR(300001))) }, ErrWithdrawalLimitExceeded},
+        {"transfer over the per-transaction limit", func() error { return discard(bs.Transfer(1, 2, INR(300001))) }, ErrWithdrawalLimitExceeded},
+        {"at the per-transaction limit", func() error { return discard(bs.Withdraw(1, INR(300000))) }, nil},
+        {"transfer up to the daily limit", func() error { return discard(bs.Transfer(1, 2, INR(200000))) }, nil},
+        {"withdrawal past the daily limit", func() error { return discard(bs.Withdraw(1, INR(1))) }, ErrWithdrawalLimitExceeded},
+        {"transfer past the daily limit", func() error { return discard(bs.Transfer(1, 2, INR(1))) }, ErrWithdrawalLimitExceeded},
+        {"deposits are not limited", func() error { return discard(bs.Deposit(1, INR(900000))) }, nil},
+    }
+    for _, step := range steps {
+        if err := step.run(); !errors.Is(err, step.wantErr) {
+            t.Errorf("%s = %v, want %v", step.name, err, step.wantErr)
+        }
+    }
+    wantBalances(t, bs, 1400000, 200000)
+
+    if today, err := bs.WithdrawnToday(1); err != nil || today != INR(500000) {
+        t.Errorf("WithdrawnToday = %v, %v; want %v", today, err, INR(500000))
+    }
+
+    // Lifting the limits lets the next withdrawal through
+    if err := bs.SetWithdrawalLimits(1, WithdrawalLimits{}); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := bs.Withdraw(1, INR(400000)); err != nil {
+        t.Errorf("withdrawing without limits: %v", err)
+    }
+}
+
+func TestFraudBlockMovesNoMoney(t *testing.T) {
+    bs := newTestBank(t, 100000, 0)
+    bs.SetFraudRules(VelocityRule{MaxCount: 2, Window: 10 * time.Minute, Action: FRAUD_BLOCK})
+
+    for i := 0; i < 2; i++ {
+        if _, err := bs.Withdraw(1, INR(1000)); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if _, err := bs.Transfer(1, 2, INR(5000)); !errors.Is(err, ErrTransactionBlocked) {
+        t.Fatalf("third withdrawal in ten minutes = %v, want %v", err, ErrTransactionBlocked)
+    }
+    wantBalances(t, bs, 98000, 0)
+    if transactions, _ := bs.Transactions(1, TransactionFilter{}); len(transactions) != 3 {
+        t.Errorf("account 1 has %d transactions, want the deposit and two withdrawals", len(transactions))
+    }
+    if transactions, _ := bs.Transactions(2, TransactionFilter{}); len(transactions) != 0 {
+        t.Errorf("account 2 received %d transactions from a blocked transfer", len(transactions))
+    }
+
+    flags := bs.FlaggedTransactions(FLAG_PENDING)
+    if len(flags) != 1 {
+        t.Fatalf("review queue holds %d flags, want 1", len(flags))
+    }
+    flag := flags[0]
+    if flag.Action != FRAUD_BLOCK || flag.AccountID != 1 || flag.Type != TRANSFER_OUT_TYPE || flag.Amount != INR(5000) ||
+        flag.TransactionID != 0 || len(flag.Reasons) != 1 || !strings.Contains(flag.Reasons[0], "3 withdrawals") {
+        t.Errorf("blocked transfer flagged as %+v", flag)
+    }
+}
+
+func TestUnusualAmountRule(t *testing.T) {
+    now := time.Now()
+    rule := UnusualAmountRule{Multiplier: 5, Lookback: 3, MinHistory: 2, Action: FRAUD_FLAG}
+    withdrawals := func(amounts ...int64) []Transaction {
+        history := make([]Transaction, len(amounts))
+        for i, amount := range amounts {
+            history[i] = Transaction{Type: WITHDRAW_TYPE, Amount: INR(amount), Timestamp: now}
+        }
+        return history
+    }
+
+    tests := []struct {
+        name    string
+        history []Transaction
+        amount  int64
+        want    string
+    }{
+        {"no history", nil, 1000000, FRAUD_ALLOW},
+        {"too little history", withdrawals(100), 1000000, FRAUD_ALLOW},
+        {"at the multiple", withdrawals(100, 100), 500, FRAUD_ALLOW},
+        {"over the multiple", withdrawals(100, 100), 501, FRAUD_FLAG},
+        {"only the last withdrawals count", withdrawals(100000, 100, 100, 100), 501, FRAUD_FLAG},
+        {"deposits are not withdrawals", append(withdrawals(100, 100), Transaction{Type: DEPOSIT_TYPE, Amount: INR(100000)}), 501, FRAUD_FLAG},
+    }
+    for _, tt := range tests {
+        if action, reason := rule.Check(tt.history, INR(tt.amount), now); action != tt.want {
+            t.Errorf("%s: got %s (%s), want %s", tt.name, action, reason, tt.want)
+        }
+    }
+}
+
+func TestFlaggedWithdrawalIsPosted(t *testing.T) {
+    bs := newTestBank(t, 100000, 0)
+    bs.SetFraudRules(UnusualAmountRule{Multiplier: 5, Lookback: 20, MinHistory: 3, Action: FRAUD_FLAG})
+
+    for i := 0; i < 3; i++ {
+        if _, err := bs.Withdraw(1, INR(1000)); err != nil {
+            t.Fatal(err)
+        }
+    }
+    tx, err := bs.Withdraw(1, INR(6000))
+    if err != nil {
+        t.Fatalf("flagged withdrawal = %v, want it posted", err)
+    }
+    wantBalances(t, bs, 91000)
+
+    flag, ok := bs.FlagForTransaction(tx.ID)
+    if !ok || flag.Action != FRAUD_FLAG || flag.Status != FLAG_PENDING || flag.Amount != INR(6000) {
+        t.Errorf("flag on the withdrawal = %+v, %v; want a pending FLAG for Rs. 60.00", flag, ok)
+    }
+}
+
+// flagLines renders flags for comparison. Times are left out, since those read back from
+// disk lose their monotonic clock reading
+func flagLines(flags []FlaggedTransaction) string {
+    lines := make([]string, len(flags))
+    for i, f := range flags {
+        lines[i] = fmt.Sprintf("%d %d %d %s %v %s %s %s %q", f.ID, f.AccountID, f.TransactionID, f.Type, f.Amount, f.Action, f.Status, f.Note, f.Reasons)
+    }
+    return strings.Join(lines, "\n")
+}
+
+// flagTestRules block a fifth withdrawal within ten minutes and flag a fourth
+func flagTestRules(bs *BankSystem) {
+    bs.SetFraudRules(
+        VelocityRule{MaxCount: 4, Window: 10 * time.Minute, Action: FRAUD_BLOCK},
+        VelocityRule{MaxCount: 3, Window: 10 * time.Minute, Action: FRAUD_FLAG},
+    )
+}
+
+// openFlagTestAccount opens account 1 in bs with a Rs. 1000 deposit and leaves one flag of
+// each action: three withdrawals of Rs. 10 pass, the fourth is flagged and the fifth blocked
+func openFlagTestAccount(t *testing.T, bs *BankSystem) {
+    t.Helper()
+    flagTestRules(bs)
+    if _, err := bs.CreateAccount(1, "Asha Rao", SAVINGS_ACCOUNT); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := bs.Deposit(1, INR(100000)); err != nil {
+        t.Fatal(err)
+    }
+    for i := 0; i < 4; i++ {
+        if _, err := bs.Withdraw(1, INR(1000)); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if _, err := bs.Withdraw(1, INR(1000)); !errors.Is(err, ErrTransactionBlocked) {
+        t.Fatalf("fifth withdrawal = %v, want %v", err, ErrTransactionBlocked)
+    }
+}
+
+func TestReviewFlag(t *testing.T) {
+    bs := NewBankSystem()
+    openFlagTestAccount(t, bs)
+
+    flags := bs.FlaggedTransactions("")
+    if len(flags) != 2 || flags[0].Action != FRAUD_FLAG || flags[1].Action != FRAUD_BLOCK {
+        t.Fatalf("review queue = %s\nwant a flagged and then a blocked withdrawal", flagLines(flags))
+    }
+
+    if _, err := bs.ReviewFlag(flags[0].ID, "maybe", ""); !errors.Is(err, ErrInvalidReview) {
+        t.Errorf("reviewing as maybe = %v, want %v", err, ErrInvalidReview)
+    }
+    if _, err := bs.ReviewFlag(99, FLAG_CLEARED, ""); !errors.Is(err, ErrFlagNotFound) {
+        t.Errorf("reviewing an unknown flag = %v, want %v", err, ErrFlagNotFound)
+    }
+
+    cleared, err := bs.ReviewFlag(flags[0].ID, "cleared", "customer confirmed")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if cleared.Status != FLAG_CLEARED || cleared.Note != "customer confirmed" || cleared.ReviewedAt.IsZero() {
+        t.Errorf("cleared flag = %+v", cleared)
+    }
+    if _, err := bs.ReviewFlag(flags[1].ID, FLAG_CONFIRMED, "card reported stolen"); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := bs.ReviewFlag(flags[0].ID, FLAG_CONFIRMED, ""); !errors.Is(err, ErrFlagReviewed) {
+        t.Errorf("reviewing a flag twice = %v, want %v", err, ErrFlagReviewed)
+    }
+
+    tests := []struct {
+        status string
+        want   []int64
+    }{
+        {"", []int64{flags[0].ID, flags[1].ID}},
+        {FLAG_PENDING, nil},
+        {"cleared", []int64{flags[0].ID}},
+        {FLAG_CONFIRMED, []int64{flags[1].ID}},
+    }
+    for _, tt := range tests {
+        var got []int64
+        for _, flag := range bs.FlaggedTransactions(tt.status) {
+            got = append(got, flag.ID)
+        }
+        if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+            t.Errorf("FlaggedTransactions(%q) = %v, want %v", tt.status, got, tt.want)
+        }
+    }
+    // The flagged withdrawal stays posted whatever the decision
+    wantBalances(t, bs, 96000)
+}
+
+func TestFlagsSurviveRecovery(t *testing.T) {
+    for _, checkpoint := range []bool{false, true} {
+        t.Run(fmt.Sprintf("checkpoint=%v", checkpoint), func(t *testing.T) {
+            dir := t.TempDir()
+            bs, _, err := OpenBankSystem(dir, 1000)
+            if err != nil {
+                t.Fatal(err)
+            }
+            openFlagTestAccount(t, bs)
+            flags := bs.FlaggedTransactions("")
+            if _, err := bs.ReviewFlag(flags[0].ID, FLAG_CLEARED, "known customer"); err != nil {
+                t.Fatal(err)
+            }
+            if checkpoint {
+                if err := bs.Checkpoint(); err != nil {
+                    t.Fatal(err)
+                }
+            }
+            want := flagLines(bs.FlaggedTransactions(""))
+            bs.wal.Close()
+
+            bs, _, err = OpenBankSystem(dir, 1000)
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer bs.Close()
+            if got := flagLines(bs.FlaggedTransactions("")); got != want {
+                t.Errorf("recovered review queue\n%s\nwant\n%s", got, want)
+            }
+            if _, err := bs.ReviewFlag(flags[0].ID, FLAG_CONFIRMED, ""); !errors.Is(err, ErrFlagReviewed) {
+                t.Errorf("reviewing a recovered cleared flag = %v, want %v", err, ErrFlagReviewed)
+            }
+
+            // Fraud rules are configuration rather than logged state, so they are set again
+            flagTestRules(bs)
+
+            // New flags carry on from the recovered IDs
+            if _, err := bs.Withdraw(1, INR(1000)); !errors.Is(err, ErrTransactionBlocked) {
+                t.Fatalf("withdrawal after recovery = %v, want %v", err, ErrTransactionBlocked)
+            }
+            recovered := bs.FlaggedTransactions(FLAG_PENDING)
+            if last := recovered[len(recovered)-1]; last.ID != flags[1].ID+1 {
+                t.Errorf("new flag after recovery has ID %d, want %d", last.ID, flags[1].ID+1)
+            }
+        })
+    }
+}
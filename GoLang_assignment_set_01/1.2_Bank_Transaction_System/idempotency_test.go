@@ -0,0 +1,50 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+// idempotentRequest sends a POST with an Idempotency-Key through handler
+func idempotentRequest(handler http.HandlerFunc, key string) *httptest.ResponseRecorder {
+    r := httptest.NewRequest(http.MethodPost, "/transfers", strings.NewReader(`{"amount":"10.00"}`))
+    r.Header.Set(IDEMPOTENCY_HEADER, key)
+    w := httptest.NewRecorder()
+    handler(w, r)
+    return w
+}
+
+func TestIdempotentPanicReleasesKey(t *testing.T) {
+    store := NewIdempotencyStore(IDEMPOTENCY_TTL)
+    calls := 0
+    handler := store.idempotent(func(w http.ResponseWriter, r *http.Request) {
+        calls++
+        if calls == 1 {
+            panic("handler failed")
+        }
+        w.WriteHeader(http.StatusCreated)
+    })
+
+    func() {
+        defer func() {
+            if recover() == nil {
+                t.Error("the handler's panic was swallowed")
+            }
+        }()
+        idempotentRequest(handler, "retry-me")
+    }()
+
+    done := make(chan *httptest.ResponseRecorder)
+    go func() { done <- idempotentRequest(handler, "retry-me") }()
+    select {
+    case w := <-done:
+        if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+            t.Errorf("retry got %d (replayed %q), want a fresh %d", w.Code, w.Header().Get("Idempotent-Replayed"), http.StatusCreated)
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatal("retry after a panic is still waiting on the key")
+    }
+}
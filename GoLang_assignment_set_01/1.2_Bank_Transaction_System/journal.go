@@ -0,0 +1,320 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "text/tabwriter"
+    "time"
+)
+
+// Posting sides
+const (
+    DEBIT  = "DEBIT"
+    CREDIT = "CREDIT"
+)
+
+// CASH_LEDGER is the bank's vault. Cash paid in is debited to it and cash paid out credited
+const CASH_LEDGER = "CASH"
+
+// Prefix of the ledger account holding a customer account's balance
+const customerLedgerPrefix = "ACC-"
+
+// Posting debits or credits one ledger account
+type Posting struct {
+    Ledger string
+    Side   string
+    Amount Money
+}
+
+// JournalEntry is one balanced set of postings: its debits always equal its credits
+type JournalEntry struct {
+    ID          int64
+    Timestamp   time.Time
+    Description string
+    Reference   string `json:",omitempty"`
+    Postings    []Posting
+}
+
+// Journal is the bank's double-entry book of record. Every balance is derived from its postings
+type Journal struct {
+    mu       sync.Mutex
+    currency string
+    entries  []JournalEntry
+    // debits and credits total the postings to each ledger account, in minor units
+    debits  map[string]int64
+    credits map[string]int64
+    // moves holds each ledger account's postings in timestamp order
+    moves map[string][]ledgerMove
+}
+
+// ledgerMove is what one posting did to a ledger account's balance on its normal side
+type ledgerMove struct {
+    Timestamp time.Time
+    Amount    int64
+}
+
+// TrialBalanceRow is the posting totals of one ledger account
+type TrialBalanceRow struct {
+    Ledger  string
+    Debits  Money
+    Credits Money
+    // Balance is positive on the account's normal side: debit for cash and interest, credit for the rest
+    Balance Money
+}
+
+// TrialBalance lists every ledger account's totals and checks that debits equal credits
+type TrialBalance struct {
+    Rows         []TrialBalanceRow
+    TotalDebits  Money
+    TotalCredits Money
+}
+
+// NewJournal creates an empty journal kept in currency
+func NewJournal(currency string) *Journal {
+    return &Journal{
+        currency: currency,
+        entries:  make([]JournalEntry, 0),
+        debits:   make(map[string]int64),
+        credits:  make(map[string]int64),
+        moves:    make(map[string][]ledgerMove),
+    }
+}
+
+// customerLedger returns the ledger account holding the balance of a customer account
+func customerLedger(accountID int) string {
+    return customerLedgerPrefix + strconv.Itoa(accountID)
+}
+
+// transfer returns a two-posting entry debiting one ledger account and crediting another
+func transfer(debit string, credit string, amount Money) []Posting {
+    return []Posting{
+        {Ledger: debit, Side: DEBIT, Amount: amount},
+        {Ledger: credit, Side: CREDIT, Amount: amount},
+    }
+}
+
+// debitNormal reports whether a ledger account's balance grows with debits. The vault is
+// an asset and interest paid an expense; customer accounts are money the bank owes and
+// fees are income, so they grow with credits
+func debitNormal(ledger string) bool {
+    return ledger == CASH_LEDGER || ledger == INTEREST_EXPENSE_LEDGER
+}
+
+// Post validates an entry and adds it to the journal
+func (j *Journal) Post(entry JournalEntry) error {
+    if err := j.validate(entry); err != nil {
+        return fmt.Errorf("journal entry %d: %w", entry.ID, err)
+    }
+
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    j.entries = append(j.entries, entry)
+    for _, p := range entry.Postings {
+        if p.Side == DEBIT {
+            j.debits[p.Ledger] += p.Amount.Minor
+        } else {
+            j.credits[p.Ledger] += p.Amount.Minor
+        }
+        j.addMove(p, entry.Timestamp)
+    }
+    return nil
+}
+
+// addMove records a posting in its ledger account's moves. Entries are nearly always posted
+// in timestamp order; month-end postings dated back to the end of the month are inserted in
+// place. Callers must hold j.mu
+func (j *Journal) addMove(p Posting, timestamp time.Time) {
+    amount := p.Amount.Minor
+    if (p.Side == DEBIT) != debitNormal(p.Ledger) {
+        amount = -amount
+    }
+
+    moves := j.moves[p.Ledger]
+    i := sort.Search(len(moves), func(i int) bool { return moves[i].Timestamp.After(timestamp) })
+    moves = append(moves, ledgerMove{})
+    copy(moves[i+1:], moves[i:])
+    moves[i] = ledgerMove{Timestamp: timestamp, Amount: amount}
+    j.moves[p.Ledger] = moves
+}
+
+// validate checks that an entry has at least two positive postings in the journal's
+// currency and that its debits equal its credits
+func (j *Journal) validate(entry JournalEntry) error {
+    if len(entry.Postings) < 2 {
+        return errors.New("needs at least two postings")
+    }
+
+    debits, credits := NewMoney(0, j.currency), NewMoney(0, j.currency)
+    for _, p := range entry.Postings {
+        if !p.Amount.IsPositive() {
+            return fmt.Errorf("posting to %s must be greater than zero", p.Ledger)
+        }
+        var err error
+        switch p.Side {
+        case DEBIT:
+            debits, err = debits.Add(p.Amount)
+        case CREDIT:
+            credits, err = credits.Add(p.Amount)
+        default:
+            err = fmt.Errorf("posting to %s has unknown side %q", p.Ledger, p.Side)
+        }
+        if err != nil {
+            return err
+        }
+    }
+
+    if debits != credits {
+        return fmt.Errorf("debits %v do not equal credits %v", debits, credits)
+    }
+    return nil
+}
+
+// Balance returns a ledger account's balance on its normal side
+func (j *Journal) Balance(ledger string) Money {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    balance := j.credits[ledger] - j.debits[ledger]
+    if debitNormal(ledger) {
+        balance = -balance
+    }
+    return NewMoney(balance, j.currency)
+}
+
+// ClosingBalances returns a ledger account's balance at the end of each day from from to
+// through. Postings count on the day of their entry's timestamp, whenever they were posted
+func (j *Journal) ClosingBalances(ledger string, from time.Time, through time.Time) []Money {
+    from, through = dateOnly(from), dateOnly(through)
+
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    // One pass over the ledger's moves: those before from make up the opening balance,
+    // and the rest are taken day by day
+    moves := j.moves[ledger]
+    var balance int64
+    i := 0
+    for ; i < len(moves) && moves[i].Timestamp.Before(from); i++ {
+        balance += moves[i].Amount
+    }
+
+    balances := make([]Money, 0)
+    for day := from; !day.After(through); day = day.AddDate(0, 0, 1) {
+        next := day.AddDate(0, 0, 1)
+        for ; i < len(moves) && moves[i].Timestamp.Before(next); i++ {
+            balance += moves[i].Amount
+        }
+        balances = append(balances, NewMoney(balance, j.currency))
+    }
+    return balances
+}
+
+// Entries returns a copy of every entry, oldest first
+func (j *Journal) Entries() []JournalEntry {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+    return append([]JournalEntry(nil), j.entries...)
+}
+
+// TrialBalance totals every posting in the journal afresh, by ledger account
+func (j *Journal) TrialBalance() (*TrialBalance, error) {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    rows := make(map[string]*TrialBalanceRow)
+    tb := &TrialBalance{
+        Rows:         make([]TrialBalanceRow, 0),
+        TotalDebits:  NewMoney(0, j.currency),
+        TotalCredits: NewMoney(0, j.currency),
+    }
+    for _, entry := range j.entries {
+        for _, p := range entry.Postings {
+            row, ok := rows[p.Ledger]
+            if !ok {
+                row = &TrialBalanceRow{Ledger: p.Ledger, Debits: NewMoney(0, j.currency), Credits: NewMoney(0, j.currency)}
+                rows[p.Ledger] = row
+            }
+
+            var err error
+            if p.Side == DEBIT {
+                if row.Debits, err = row.Debits.Add(p.Amount); err == nil {
+                    tb.TotalDebits, err = tb.TotalDebits.Add(p.Amount)
+                }
+            } else {
+                if row.Credits, err = row.Credits.Add(p.Amount); err == nil {
+                    tb.TotalCredits, err = tb.TotalCredits.Add(p.Amount)
+                }
+            }
+            if err != nil {
+                return nil, err
+            }
+        }
+    }
+
+    for _, row := range rows {
+        row.Balance = NewMoney(row.Credits.Minor-row.Debits.Minor, j.currency)
+        if debitNormal(row.Ledger) {
+            row.Balance = row.Balance.Neg()
+        }
+        tb.Rows = append(tb.Rows, *row)
+    }
+    // Bank ledger accounts first, then customer accounts in account order
+    sort.Slice(tb.Rows, func(a, b int) bool {
+        idA, customerA := customerAccountID(tb.Rows[a].Ledger)
+        idB, customerB := customerAccountID(tb.Rows[b].Ledger)
+        if customerA != customerB {
+            return customerB
+        }
+        if customerA {
+            return idA < idB
+        }
+        return tb.Rows[a].Ledger < tb.Rows[b].Ledger
+    })
+    return tb, nil
+}
+
+// customerAccountID returns the customer account a ledger account belongs to, if any
+func customerAccountID(ledger string) (int, bool) {
+    number, ok := strings.CutPrefix(ledger, customerLedgerPrefix)
+    if !ok {
+        return 0, false
+    }
+    id, err := strconv.Atoi(number)
+    return id, err == nil
+}
+
+// Balanced reports whether total debits equal total credits
+func (tb *TrialBalance) Balanced() bool {
+    return tb.TotalDebits == tb.TotalCredits
+}
+
+// WriteText writes the trial balance to w as a table
+func (tb *TrialBalance) WriteText(w io.Writer) error {
+    fmt.Fprintln(w, "\nTrial Balance")
+    tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+    fmt.Fprintln(tw, "LEDGER\tDEBITS\tCREDITS\tBALANCE\t")
+    for _, row := range tb.Rows {
+        side := "Cr"
+        if debitNormal(row.Ledger) {
+            side = "Dr"
+        }
+        fmt.Fprintf(tw, "%s\t%v\t%v\t%v %s\t\n", row.Ledger, row.Debits, row.Credits, row.Balance, side)
+    }
+    fmt.Fprintf(tw, "TOTAL\t%v\t%v\t\t\n", tb.TotalDebits, tb.TotalCredits)
+    if err := tw.Flush(); err != nil {
+        return err
+    }
+
+    status := "Books balance: total debits equal total credits."
+    if !tb.Balanced() {
+        status = "BOOKS DO NOT BALANCE: total debits differ from total credits!"
+    }
+    _, err := fmt.Fprintln(w, status)
+    return err
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+    "bytes"
+    "fmt"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestJournalRejectsInvalidEntries(t *testing.T) {
+    tests := []struct {
+        name     string
+        postings []Posting
+        wantErr  string
+    }{
+        {"one posting", []Posting{{Ledger: CASH_LEDGER, Side: DEBIT, Amount: INR(100)}}, "at least two postings"},
+        {"unbalanced", []Posting{
+            {Ledger: CASH_LEDGER, Side: DEBIT, Amount: INR(100)},
+            {Ledger: customerLedger(1), Side: CREDIT, Amount: INR(99)},
+        }, "do not equal"},
+        {"zero posting", transfer(CASH_LEDGER, customerLedger(1), INR(0)), "greater than zero"},
+        {"unknown side", []Posting{
+            {Ledger: CASH_LEDGER, Side: "SIDEWAYS", Amount: INR(100)},
+            {Ledger: customerLedger(1), Side: CREDIT, Amount: INR(100)},
+        }, "unknown side"},
+        {"other currency", transfer(CASH_LEDGER, customerLedger(1), NewMoney(100, "USD")), "currency mismatch"},
+    }
+
+    j := NewJournal(CURRENCY_INR)
+    for _, tt := range tests {
+        err := j.Post(JournalEntry{Description: tt.name, Postings: tt.postings})
+        if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+            t.Errorf("posting %s = %v, want an error containing %q", tt.name, err, tt.wantErr)
+        }
+    }
+    if entries := j.Entries(); len(entries) != 0 {
+        t.Errorf("journal kept %d rejected entries", len(entries))
+    }
+}
+
+func TestTrialBalance(t *testing.T) {
+    bs := newTestBank(t)
+    // Account 10 sorts after account 2 by number even though "ACC-10" < "ACC-2"
+    for _, id := range []int{10, 2} {
+        if _, err := bs.CreateAccount(id, fmt.Sprintf("Customer %d", id), SAVINGS_ACCOUNT); err != nil {
+            t.Fatal(err)
+        }
+    }
+    steps := []error{
+        discard(bs.Deposit(10, INR(100000))),
+        discard(bs.Deposit(2, INR(20000))),
+        discard(bs.Withdraw(10, INR(5000))),
+        discard(bs.Transfer(10, 2, INR(30000))),
+        bs.journal.Post(JournalEntry{Description: "Interest", Postings: transfer(INTEREST_EXPENSE_LEDGER, customerLedger(2), INR(150))}),
+    }
+    for _, err := range steps {
+        if err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    tb, err := bs.TrialBalance()
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := []TrialBalanceRow{
+        {Ledger: CASH_LEDGER, Debits: INR(120000), Credits: INR(5000), Balance: INR(115000)},
+        {Ledger: INTEREST_EXPENSE_LEDGER, Debits: INR(150), Credits: INR(0), Balance: INR(150)},
+        {Ledger: "ACC-2", Debits: INR(0), Credits: INR(50150), Balance: INR(50150)},
+        {Ledger: "ACC-10", Debits: INR(35000), Credits: INR(100000), Balance: INR(65000)},
+    }
+    if fmt.Sprint(tb.Rows) != fmt.Sprint(want) {
+        t.Errorf("trial balance rows =\n%v\nwant\n%v", tb.Rows, want)
+    }
+    if tb.TotalDebits != INR(155150) || tb.TotalCredits != INR(155150) || !tb.Balanced() {
+        t.Errorf("totals are %v debit and %v credit, want Rs. 1551.50 each", tb.TotalDebits, tb.TotalCredits)
+    }
+
+    var out bytes.Buffer
+    if err := tb.WriteText(&out); err != nil {
+        t.Fatal(err)
+    }
+    if !strings.Contains(out.String(), "Books balance: total debits equal total credits.") {
+        t.Errorf("trial balance\n%s\nwant it to report balanced books", out.String())
+    }
+
+    tb.TotalCredits = INR(155149)
+    out.Reset()
+    tb.WriteText(&out)
+    if tb.Balanced() || !strings.Contains(out.String(), "BOOKS DO NOT BALANCE") {
+        t.Errorf("trial balance with unequal totals\n%s\nwant it reported as not balancing", out.String())
+    }
+}
+
+// discard drops the transaction from an operation's result, keeping its error
+func discard(_ Transaction, err error) error {
+    return err
+}
+
+func TestClosingBalances(t *testing.T) {
+    day := func(d int, hour int) time.Time {
+        return time.Date(2024, time.March, d, hour, 0, 0, 0, time.Local)
+    }
+    j := NewJournal(CURRENCY_INR)
+    entries := []struct {
+        at       time.Time
+        postings []Posting
+    }{
+        {day(1, 10), transfer(CASH_LEDGER, customerLedger(1), INR(1000))},
+        {day(3, 9), transfer(CASH_LEDGER, customerLedger(1), INR(500))},
+        {day(3, 18), transfer(customerLedger(1), CASH_LEDGER, INR(200))},
+        {day(5, 10), transfer(customerLedger(1), customerLedger(2), INR(100))},
+        {day(6, 10), transfer(CASH_LEDGER, customerLedger(1), INR(7))},
+        // Posted last but dated back to the second, as month-end processing does
+        {day(2, 23), transfer(INTEREST_EXPENSE_LEDGER, customerLedger(1), INR(40))},
+    }
+    for _, e := range entries {
+        if err := j.Post(JournalEntry{Timestamp: e.at, Postings: e.postings}); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    tests := []struct {
+        name          string
+        ledger        string
+        from, through time.Time
+        want          []int64
+    }{
+        {"whole period", customerLedger(1), day(1, 0), day(6, 0), []int64{1000, 1040, 1340, 1340, 1240, 1247}},
+        {"opening balance from earlier days", customerLedger(1), day(4, 0), day(5, 0), []int64{1340, 1240}},
+        {"days before any posting", customerLedger(1), time.Date(2024, time.February, 28, 0, 0, 0, 0, time.Local), day(1, 0), []int64{0, 0, 1000}},
+        {"debit-normal ledger", CASH_LEDGER, day(2, 12), day(3, 12), []int64{1000, 1300}},
+        {"other account", customerLedger(2), day(4, 0), day(6, 0), []int64{0, 100, 100}},
+    }
+    for _, tt := range tests {
+        balances := j.ClosingBalances(tt.ledger, tt.from, tt.through)
+        got := make([]int64, len(balances))
+        for i, balance := range balances {
+            got[i] = balance.Minor
+        }
+        if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+            t.Errorf("%s: closing balances = %v, want %v", tt.name, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+    "errors"
+    "strings"
+    "testing"
+)
+
+func TestParseMoney(t *testing.T) {
+    tests := []struct {
+        input     string
+        wantMinor int64
+        wantErr   string
+    }{
+        {"100", 10000, ""},
+        {"0.1", 10, ""},
+        {"0.01", 1, ""},
+        {".5", 50, ""},
+        {"1,250.5", 125050, ""},
+        {"Rs. 99.99", 9999, ""},
+        {"INR 12", 1200, ""},
+        {"  7.25 ", 725, ""},
+        {"-5", -500, ""},
+        {"-0.50", -50, ""},
+        {"Rs. -3.10", -310, ""},
+        {"92233720368547758.07", 9223372036854775807, ""},
+        {"1.005", 0, "at most two decimal places"},
+        {"5.", 0, "at most two decimal places"},
+        {".", 0, "at most two decimal places"},
+        {"", 0, "at most two decimal places"},
+        {"-", 0, "at most two decimal places"},
+        {"1.2.3", 0, "at most two decimal places"},
+        {"12a", 0, "at most two decimal places"},
+        {"--5", 0, "at most two decimal places"},
+        {"92233720368547758.08", 0, "too large"},
+        {"99999999999999999999", 0, "too large"},
+    }
+
+    for _, tt := range tests {
+        got, err := ParseMoney(tt.input, CURRENCY_INR)
+        if tt.wantErr != "" {
+            if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+                t.Errorf("ParseMoney(%q) = %v, %v; want an error containing %q", tt.input, got, err, tt.wantErr)
+            }
+            continue
+        }
+        if err != nil || got != INR(tt.wantMinor) {
+            t.Errorf("ParseMoney(%q) = %#v, %v; want %d paise", tt.input, got, err, tt.wantMinor)
+        }
+    }
+}
+
+func TestMoneyFormatting(t *testing.T) {
+    tests := []struct {
+        amount      Money
+        wantString  string
+        wantDecimal string
+    }{
+        {INR(0), "Rs. 0.00", "0.00"},
+        {INR(5), "Rs. 0.05", "0.05"},
+        {INR(123450), "Rs. 1234.50", "1234.50"},
+        {INR(-50), "-Rs. 0.50", "-0.50"},
+        {INR(-123405), "-Rs. 1234.05", "-1234.05"},
+        {NewMoney(1999, "USD"), "USD 19.99", "19.99"},
+    }
+
+    for _, tt := range tests {
+        if got := tt.amount.String(); got != tt.wantString {
+            t.Errorf("%#v.String() = %q, want %q", tt.amount, got, tt.wantString)
+        }
+        if got := tt.amount.Decimal(); got != tt.wantDecimal {
+            t.Errorf("%#v.Decimal() = %q, want %q", tt.amount, got, tt.wantDecimal)
+        }
+    }
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+    if _, err := INR(100).Add(NewMoney(100, "USD")); err == nil {
+        t.Error("added rupees to dollars")
+    }
+    if _, err := INR(100).Cmp(NewMoney(100, "USD")); err == nil {
+        t.Error("compared rupees with dollars")
+    }
+    if _, err := INR(9223372036854775807).Add(INR(1)); !errors.Is(err, ErrInvalidAmount) {
+        t.Errorf("Add overflowed with %v, want %v", err, ErrInvalidAmount)
+    }
+    if _, err := INR(-9223372036854775807).Sub(INR(2)); !errors.Is(err, ErrInvalidAmount) {
+        t.Errorf("Sub overflowed with %v, want %v", err, ErrInvalidAmount)
+    }
+    if got, err := INR(1050).Sub(INR(1100)); err != nil || got != INR(-50) {
+        t.Errorf("Rs. 10.50 - Rs. 11.00 = %v, %v; want -Rs. 0.50", got, err)
+    }
+    if cmp, err := INR(1).Cmp(INR(2)); err != nil || cmp != -1 {
+        t.Errorf("Cmp(Rs. 0.01, Rs. 0.02) = %d, %v; want -1", cmp, err)
+    }
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+    "fmt"
+    "math/rand"
+    "sync"
+    "sync/atomic"
+    "testing"
+)
+
+// Size of the stress test: accounts it spreads its operations over, goroutines and operations each
+const (
+    stressAccounts   = 8
+    stressWorkers    = 32
+    stressOperations = 500
+)
+
+// TestConcurrentOperationsConserveMoney hammers an in-memory bank with deposits, withdrawals,
+// transfers and reads from many goroutines at once, then checks that no money was created or
+// destroyed: the customer balances must add up to the cash taken in less the cash paid out,
+// every account's ledger must end on its journal balance and the trial balance must balance.
+// Run it under the race detector with: go test -race -run Conserve
+func TestConcurrentOperationsConserveMoney(t *testing.T) {
+    operations := stressOperations
+    if testing.Short() {
+        operations /= 10
+    }
+
+    bs := NewBankSystem()
+    // Limits and velocity rules would soon refuse most withdrawals and leave little to contend over
+    bs.SetFraudRules()
+    opening := INR(100000)
+    for id := 1; id <= stressAccounts; id++ {
+        if _, err := bs.CreateAccount(id, fmt.Sprintf("Stress %d", id), SAVINGS_ACCOUNT); err != nil {
+            t.Fatal(err)
+        }
+        if err := bs.SetWithdrawalLimits(id, WithdrawalLimits{}); err != nil {
+            t.Fatal(err)
+        }
+        if _, err := bs.Deposit(id, opening); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    // Net cash in through successful deposits and withdrawals, in minor units
+    var cashIn atomic.Int64
+    cashIn.Store(opening.Minor * stressAccounts)
+    var succeeded atomic.Int64
+
+    var wg sync.WaitGroup
+    for w := 0; w < stressWorkers; w++ {
+        wg.Add(1)
+        go func(seed int64) {
+            defer wg.Done()
+            rng := rand.New(rand.NewSource(seed))
+            for i := 0; i < operations; i++ {
+                id := rng.Intn(stressAccounts) + 1
+                amount := INR(rng.Int63n(50000) + 1)
+
+                var err error
+                switch rng.Intn(5) {
+                case 0:
+                    if _, err = bs.Deposit(id, amount); err == nil {
+                        cashIn.Add(amount.Minor)
+                    }
+                case 1:
+                    if _, err = bs.Withdraw(id, amount); err == nil {
+                        cashIn.Add(-amount.Minor)
+                    }
+                case 2, 3:
+                    _, err = bs.Transfer(id, rng.Intn(stressAccounts)+1, amount)
+                default:
+                    if _, err = bs.Balance(id); err == nil {
+                        _, err = bs.Transactions(id, TransactionFilter{})
+                    }
+                }
+                if err == nil {
+                    succeeded.Add(1)
+                }
+            }
+        }(int64(w + 1))
+    }
+    wg.Wait()
+
+    if succeeded.Load() == 0 {
+        t.Fatal("every operation was refused, so nothing was contended over")
+    }
+
+    total := INR(0)
+    for _, acc := range bs.ListAccounts() {
+        balance, err := bs.Balance(acc.ID)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if balance.Minor < 0 {
+            t.Errorf("account %d went below zero: %v", acc.ID, balance)
+        }
+        transactions, err := bs.Transactions(acc.ID, TransactionFilter{})
+        if err != nil {
+            t.Fatal(err)
+        }
+        if last := transactions[len(transactions)-1]; last.BalanceAfter != balance {
+            t.Errorf("account %d ledger ends on %v but the journal balance is %v", acc.ID, last.BalanceAfter, balance)
+        }
+        total, _ = total.Add(balance)
+    }
+
+    if expected := INR(cashIn.Load()); total != expected {
+        t.Errorf("customer balances total %v but net cash taken in is %v", total, expected)
+    }
+    if vault := bs.journal.Balance(CASH_LEDGER); vault != total {
+        t.Errorf("vault holds %v but customers are owed %v", vault, total)
+    }
+    tb, err := bs.TrialBalance()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !tb.Balanced() {
+        t.Errorf("trial balance does not balance: debits %v, credits %v", tb.TotalDebits, tb.TotalCredits)
+    }
+}
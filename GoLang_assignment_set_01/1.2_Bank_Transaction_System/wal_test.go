@@ -0,0 +1,259 @@
+package main
+
+import (
+    "encoding/binary"
+    "encoding/json"
+    "fmt"
+    "hash/crc32"
+    "os"
+    "path/filepath"
+    "sync"
+    "testing"
+    "time"
+)
+
+// walFrame encodes a record the way WriteAheadLog.Append writes it
+func walFrame(t *testing.T, lsn uint64) []byte {
+    t.Helper()
+
+    payload, err := json.Marshal(walRecord{LSN: lsn, Op: WAL_CREATE_ACCOUNT, AccountID: int(lsn)})
+    if err != nil {
+        t.Fatal(err)
+    }
+    frame := make([]byte, walHeaderSize+len(payload))
+    binary.BigEndian.PutUint32(frame[0:4], uint32(len(payload)))
+    binary.BigEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(payload))
+    copy(frame[walHeaderSize:], payload)
+    return frame
+}
+
+func TestParseWALTornTail(t *testing.T) {
+    first, second := walFrame(t, 1), walFrame(t, 2)
+    data := append(append([]byte{}, first...), second[:len(second)-5]...)
+
+    records, valid, err := parseWAL(data)
+    if err != nil {
+        t.Fatalf("torn last record returned %v", err)
+    }
+    if len(records) != 1 || valid != int64(len(first)) {
+        t.Errorf("kept %d records and %d bytes, want 1 record and %d bytes", len(records), valid, len(first))
+    }
+}
+
+func TestParseWALCorruptLength(t *testing.T) {
+    first, second, third := walFrame(t, 1), walFrame(t, 2), walFrame(t, 3)
+    // A length that runs past the end of the log, with a whole record still behind it
+    binary.BigEndian.PutUint32(second[0:4], 1<<20)
+    data := append(append(append([]byte{}, first...), second...), third...)
+
+    if records, _, err := parseWAL(data); err == nil {
+        t.Fatalf("corrupt length mid-log was treated as a torn tail, keeping %d records", len(records))
+    }
+}
+
+// bankState is what a bank must look like after recovery: balances, transactions and the
+// last ID of each kind handed out
+type bankState struct {
+    Balances        []Money
+    Transactions    [][]string
+    LastTransaction int64
+    LastJournal     int64
+    LastTransfer    int64
+}
+
+// captureBankState records the state of accounts 1 to n. Transactions are compared by their
+// fields, since times read back from disk lose their monotonic clock reading
+func captureBankState(t *testing.T, bs *BankSystem, n int) bankState {
+    t.Helper()
+    state := bankState{
+        LastTransaction: bs.lastTransactionID.Load(),
+        LastJournal:     bs.lastJournalID.Load(),
+        LastTransfer:    bs.lastTransferID.Load(),
+    }
+    for id := 1; id <= n; id++ {
+        balance, err := bs.Balance(id)
+        if err != nil {
+            t.Fatal(err)
+        }
+        transactions, err := bs.Transactions(id, TransactionFilter{})
+        if err != nil {
+            t.Fatal(err)
+        }
+        lines := make([]string, len(transactions))
+        for i, tx := range transactions {
+            lines[i] = fmt.Sprintf("%d %s %v %v %s %d %s",
+                tx.ID, tx.Type, tx.Amount, tx.BalanceAfter, tx.Reference, tx.JournalID, tx.Timestamp.Format(time.RFC3339Nano))
+        }
+        state.Balances = append(state.Balances, balance)
+        state.Transactions = append(state.Transactions, lines)
+    }
+    return state
+}
+
+func TestOpenBankSystemRecovers(t *testing.T) {
+    dir := t.TempDir()
+    bs, report, err := OpenBankSystem(dir, 1000)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if report.SnapshotLSN != 0 || report.RecordsReplayed != 0 || report.TornBytes != 0 {
+        t.Errorf("opening an empty directory reported %+v", report)
+    }
+
+    // Records before the checkpoint are recovered from the snapshot, the rest from the log
+    steps := []func() error{
+        func() error { return discardAccount(bs.CreateAccount(1, "Asha Rao", SAVINGS_ACCOUNT)) },
+        func() error { return discardAccount(bs.CreateAccount(2, "Ravi Nair", CURRENT_ACCOUNT)) },
+        func() error { return discard(bs.Deposit(1, INR(100000))) },
+        func() error { return discard(bs.Transfer(1, 2, INR(30000))) },
+        bs.Checkpoint,
+        func() error { return discard(bs.Deposit(2, INR(5000))) },
+        func() error { return discard(bs.Withdraw(1, INR(1000))) },
+        func() error { return discard(bs.Transfer(2, 1, INR(2500))) },
+    }
+    for i, step := range steps {
+        if err := step(); err != nil {
+            t.Fatalf("step %d: %v", i, err)
+        }
+    }
+    want := captureBankState(t, bs, 2)
+
+    // Crash part-way through writing the next record: no checkpoint, half a frame on disk
+    bs.wal.Close()
+    torn := walFrame(t, 99)[:20]
+    file, err := os.OpenFile(filepath.Join(dir, WAL_FILE), os.O_APPEND|os.O_WRONLY, 0644)
+    if err != nil {
+        t.Fatal(err)
+    }
+    file.Write(torn)
+    file.Close()
+
+    bs, report, err = OpenBankSystem(dir, 1000)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer bs.Close()
+
+    if report.SnapshotLSN != 4 || report.RecordsReplayed != 3 || report.TornBytes != int64(len(torn)) {
+        t.Errorf("recovery reported %+v, want snapshot LSN 4, 3 records replayed and %d torn bytes", report, len(torn))
+    }
+    if got := captureBankState(t, bs, 2); fmt.Sprint(got) != fmt.Sprint(want) {
+        t.Errorf("recovered state\n%v\nwant\n%v", got, want)
+    }
+
+    // New records carry on from the recovered counters
+    deposit, err := bs.Deposit(1, INR(100))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if deposit.ID != want.LastTransaction+1 || deposit.JournalID != want.LastJournal+1 {
+        t.Errorf("deposit after recovery got transaction %d and journal entry %d, want %d and %d",
+            deposit.ID, deposit.JournalID, want.LastTransaction+1, want.LastJournal+1)
+    }
+    if tb, err := bs.TrialBalance(); err != nil || !tb.Balanced() {
+        t.Errorf("recovered books do not balance: %v", err)
+    }
+}
+
+func TestWALAssignsIDsInSequenceOrder(t *testing.T) {
+    const accounts, deposits = 4, 25
+
+    dir := t.TempDir()
+    bs, _, err := OpenBankSystem(dir, 1000)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer bs.wal.Close()
+    for id := 1; id <= accounts; id++ {
+        if _, err := bs.CreateAccount(id, fmt.Sprintf("Customer %d", id), SAVINGS_ACCOUNT); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    // Deposits to different accounts commit concurrently under the bank's read lock
+    var wg sync.WaitGroup
+    for id := 1; id <= accounts; id++ {
+        wg.Add(1)
+        go func(id int) {
+            defer wg.Done()
+            for i := 0; i < deposits; i++ {
+                if _, err := bs.Deposit(id, INR(100)); err != nil {
+                    t.Error(err)
+                    return
+                }
+            }
+        }(id)
+    }
+    wg.Wait()
+
+    data, err := os.ReadFile(filepath.Join(dir, WAL_FILE))
+    if err != nil {
+        t.Fatal(err)
+    }
+    records, _, err := parseWAL(data)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var lastJournal, lastTransaction int64
+    for _, record := range records {
+        if record.Journal == nil {
+            continue
+        }
+        if record.Journal.ID <= lastJournal || record.Entries[0].Transaction.ID <= lastTransaction {
+            t.Fatalf("record %d has journal entry %d and transaction %d after %d and %d",
+                record.LSN, record.Journal.ID, record.Entries[0].Transaction.ID, lastJournal, lastTransaction)
+        }
+        lastJournal, lastTransaction = record.Journal.ID, record.Entries[0].Transaction.ID
+    }
+}
+
+func TestCommitRejectsUnreplayableRecords(t *testing.T) {
+    dir := t.TempDir()
+    bs, _, err := OpenBankSystem(dir, 1000)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := bs.CreateAccount(1, "Asha Rao", SAVINGS_ACCOUNT); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := bs.Deposit(1, INR(10000)); err != nil {
+        t.Fatal(err)
+    }
+    want := captureBankState(t, bs, 1)
+
+    // Records the journal or the account ledgers would refuse once applied
+    unbalanced := JournalEntry{Description: "Unbalanced", Postings: []Posting{
+        {Ledger: CASH_LEDGER, Side: DEBIT, Amount: INR(500)},
+        {Ledger: customerLedger(1), Side: CREDIT, Amount: INR(400)},
+    }}
+    records := map[string]walRecord{
+        "an unbalanced journal entry":   postRecord(unbalanced, walEntry{AccountID: 1, Transaction: Transaction{Type: DEPOSIT_TYPE, Amount: INR(500)}}),
+        "an entry on an unknown account": postRecord(JournalEntry{Description: "Cash deposit", Postings: transfer(CASH_LEDGER, customerLedger(9), INR(500))}, walEntry{AccountID: 9, Transaction: Transaction{Type: DEPOSIT_TYPE, Amount: INR(500)}}),
+        "limits for an unknown account": {Op: WAL_SET_LIMITS, AccountID: 9, Limits: &WithdrawalLimits{}},
+    }
+    for name, record := range records {
+        bs.mu.Lock()
+        err := bs.commit(record)
+        bs.mu.Unlock()
+        if err == nil {
+            t.Errorf("committing a record with %s succeeded", name)
+        }
+    }
+    if lsn := bs.wal.LastLSN(); lsn != 2 {
+        t.Errorf("log holds %d records after the rejections, want 2", lsn)
+    }
+    // Crash rather than close, so recovery has to replay the log
+    bs.wal.Close()
+
+    bs, report, err := OpenBankSystem(dir, 1000)
+    if err != nil {
+        t.Fatalf("reopening after rejected records: %v", err)
+    }
+    defer bs.Close()
+    if report.RecordsReplayed != 2 {
+        t.Errorf("replayed %d records, want 2", report.RecordsReplayed)
+    }
+    if got := captureBankState(t, bs, 1); fmt.Sprint(got) != fmt.Sprint(want) {
+        t.Errorf("recovered state\n%v\nwant\n%v", got, want)
+    }
+}
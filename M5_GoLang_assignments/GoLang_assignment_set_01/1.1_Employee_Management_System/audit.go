@@ -0,0 +1,415 @@
+package main
+
+import (
+    "bufio"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "reflect"
+    "sort"
+    "sync"
+    "time"
+)
+
+// Change event actions
+const (
+    AUDIT_BASELINE  = "BASELINE"
+    AUDIT_CREATE    = "CREATE"
+    AUDIT_UPDATE    = "UPDATE"
+    AUDIT_TERMINATE = "TERMINATE"
+    AUDIT_ROLLBACK  = "ROLLBACK"
+)
+
+// FieldChange is one field that differs between two versions of an employee record
+type FieldChange struct {
+    Field  string      `json:"field"`
+    Before interface{} `json:"before"`
+    After  interface{} `json:"after"`
+}
+
+// ChangeEvent records one change to an employee record. Before is nil for a new hire
+type ChangeEvent struct {
+    Seq        int           `json:"seq"`
+    EmployeeID int           `json:"employee_id"`
+    Action     string        `json:"action"`
+    Actor      string        `json:"actor"`
+    Timestamp  time.Time     `json:"timestamp"`
+    Before     *Employee     `json:"before,omitempty"`
+    After      *Employee     `json:"after,omitempty"`
+    Changes    []FieldChange `json:"changes"`
+}
+
+// AuditLog is an append-only store of change events. Events can never be edited or removed
+type AuditLog interface {
+    // Append numbers event with the next sequence number and stores it
+    Append(event ChangeEvent) (ChangeEvent, error)
+    // Events returns every stored event in sequence order
+    Events() ([]ChangeEvent, error)
+    // Close releases any resources held by the log
+    Close() error
+}
+
+// MemoryAuditLog keeps change events in memory for the life of the process
+type MemoryAuditLog struct {
+    mu     sync.Mutex
+    events []ChangeEvent
+}
+
+// NewMemoryAuditLog creates an empty in-memory audit log
+func NewMemoryAuditLog() *MemoryAuditLog {
+    return &MemoryAuditLog{events: make([]ChangeEvent, 0)}
+}
+
+// Append stores a copy of event
+func (ml *MemoryAuditLog) Append(event ChangeEvent) (ChangeEvent, error) {
+    ml.mu.Lock()
+    defer ml.mu.Unlock()
+
+    event = event.clone()
+    event.Seq = len(ml.events) + 1
+    ml.events = append(ml.events, event)
+    return event.clone(), nil
+}
+
+// Events returns copies of every stored event
+func (ml *MemoryAuditLog) Events() ([]ChangeEvent, error) {
+    ml.mu.Lock()
+    defer ml.mu.Unlock()
+
+    events := make([]ChangeEvent, len(ml.events))
+    for i, event := range ml.events {
+        events[i] = event.clone()
+    }
+    return events, nil
+}
+
+// Close is a no-op for MemoryAuditLog
+func (ml *MemoryAuditLog) Close() error {
+    return nil
+}
+
+// FileAuditLog appends change events to a file, one JSON object per line.
+// Every append is synced to disk before it returns
+type FileAuditLog struct {
+    mu      sync.Mutex
+    path    string
+    file    *os.File
+    lastSeq int
+}
+
+// OpenFileAuditLog opens the audit log at path, creating it if it does not exist yet.
+// A last line cut short by a crash part-way through Append is truncated away
+func OpenFileAuditLog(path string) (*FileAuditLog, error) {
+    fl := &FileAuditLog{path: path}
+
+    events, valid, err := fl.read()
+    if err != nil {
+        return nil, err
+    }
+    if len(events) > 0 {
+        fl.lastSeq = events[len(events)-1].Seq
+    }
+
+    fl.file, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+    if err != nil {
+        return nil, err
+    }
+    info, err := fl.file.Stat()
+    if err != nil {
+        fl.file.Close()
+        return nil, err
+    }
+    if info.Size() > valid {
+        if err := fl.file.Truncate(valid); err != nil {
+            fl.file.Close()
+            return nil, err
+        }
+        if err := fl.file.Sync(); err != nil {
+            fl.file.Close()
+            return nil, err
+        }
+    }
+    return fl, nil
+}
+
+// Append writes event as a new line and syncs the file
+func (fl *FileAuditLog) Append(event ChangeEvent) (ChangeEvent, error) {
+    fl.mu.Lock()
+    defer fl.mu.Unlock()
+
+    event = event.clone()
+    event.Seq = fl.lastSeq + 1
+    data, err := json.Marshal(event)
+    if err != nil {
+        return ChangeEvent{}, err
+    }
+
+    if _, err := fl.file.Write(append(data, '\n')); err != nil {
+        return ChangeEvent{}, err
+    }
+    if err := fl.file.Sync(); err != nil {
+        return ChangeEvent{}, err
+    }
+    fl.lastSeq = event.Seq
+    return event, nil
+}
+
+// Events reads every event back from the file
+func (fl *FileAuditLog) Events() ([]ChangeEvent, error) {
+    fl.mu.Lock()
+    defer fl.mu.Unlock()
+
+    events, _, err := fl.read()
+    return events, err
+}
+
+// Close closes the underlying file
+func (fl *FileAuditLog) Close() error {
+    return fl.file.Close()
+}
+
+// read parses the log file, returning no events if it does not exist yet, along with the
+// length of the file up to the end of its last complete line. Append writes each event and its
+// newline together, so a last line without a newline is torn and left out; the append that
+// wrote it never reported success
+func (fl *FileAuditLog) read() ([]ChangeEvent, int64, error) {
+    events := make([]ChangeEvent, 0)
+
+    file, err := os.Open(fl.path)
+    if errors.Is(err, os.ErrNotExist) {
+        return events, 0, nil
+    }
+    if err != nil {
+        return nil, 0, err
+    }
+    defer file.Close()
+
+    reader := bufio.NewReader(file)
+    var valid int64
+    for line := 1; ; line++ {
+        data, err := reader.ReadBytes('\n')
+        if err == io.EOF {
+            return events, valid, nil
+        }
+        if err != nil {
+            return nil, 0, err
+        }
+
+        var event ChangeEvent
+        if err := json.Unmarshal(data, &event); err != nil {
+            return nil, 0, fmt.Errorf("invalid audit log %s line %d: %w", fl.path, line, err)
+        }
+        events = append(events, event)
+        valid += int64(len(data))
+    }
+}
+
+// SetAuditLog starts recording every change to the roster in log. Employees the log
+// has never seen are recorded with a BASELINE event, since their earlier history is unknown
+func (em *EmployeeManager) SetAuditLog(log AuditLog) error {
+    em.mu.Lock()
+    defer em.mu.Unlock()
+
+    events, err := log.Events()
+    if err != nil {
+        return fmt.Errorf("%w: %w", ErrAudit, err)
+    }
+    seen := make(map[int]bool)
+    for _, event := range events {
+        seen[event.EmployeeID] = true
+    }
+
+    em.audit = log
+    for i := range em.employees {
+        if !seen[em.employees[i].ID] {
+            if err := em.recordChange(AUDIT_BASELINE, nil, &em.employees[i]); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+// SetActor sets who is named as the actor on change events from now on
+func (em *EmployeeManager) SetActor(actor string) {
+    em.mu.Lock()
+    defer em.mu.Unlock()
+
+    em.actor = actor
+}
+
+// EmployeeHistory returns every recorded change to the employee with the given ID, oldest first
+func (em *EmployeeManager) EmployeeHistory(id int) ([]ChangeEvent, error) {
+    events, err := em.auditEvents()
+    if err != nil {
+        return nil, err
+    }
+
+    history := make([]ChangeEvent, 0)
+    for _, event := range events {
+        if event.EmployeeID == id {
+            history = append(history, event)
+        }
+    }
+    if len(history) == 0 {
+        return nil, fmt.Errorf("no history for employee with ID %d: %w", id, ErrNotFound)
+    }
+    return history, nil
+}
+
+// RosterAsOf replays the audit log to rebuild every employee record as it stood at asOf,
+// including employees who had already left. Employees added later are left out
+func (em *EmployeeManager) RosterAsOf(asOf time.Time) ([]Employee, error) {
+    events, err := em.auditEvents()
+    if err != nil {
+        return nil, err
+    }
+
+    records := make(map[int]Employee)
+    for _, event := range events {
+        if event.Timestamp.After(asOf) {
+            continue
+        }
+        if event.After == nil {
+            delete(records, event.EmployeeID)
+        } else {
+            records[event.EmployeeID] = *event.After
+        }
+    }
+
+    roster := make([]Employee, 0, len(records))
+    for _, emp := range records {
+        roster = append(roster, emp)
+    }
+    sort.Slice(roster, func(i, j int) bool {
+        return roster[i].ID < roster[j].ID
+    })
+    return roster, nil
+}
+
+// auditEvents returns the audit log's events, failing if no log is attached
+func (em *EmployeeManager) auditEvents() ([]ChangeEvent, error) {
+    em.mu.RLock()
+    log := em.audit
+    em.mu.RUnlock()
+
+    if log == nil {
+        return nil, fmt.Errorf("%w: no audit log configured", ErrAudit)
+    }
+    events, err := log.Events()
+    if err != nil {
+        return nil, fmt.Errorf("%w: %w", ErrAudit, err)
+    }
+    return events, nil
+}
+
+// recordChange appends a change event for the move from before to after, if an audit
+// log is attached. Callers must hold em.mu
+func (em *EmployeeManager) recordChange(action string, before *Employee, after *Employee) error {
+    if em.audit == nil {
+        return nil
+    }
+
+    event := ChangeEvent{
+        Action:    action,
+        Actor:     em.actor,
+        Timestamp: time.Now(),
+        Before:    before,
+        After:     after,
+        Changes:   diffEmployees(before, after),
+    }
+    if after != nil {
+        event.EmployeeID = after.ID
+    } else if before != nil {
+        event.EmployeeID = before.ID
+    }
+    if event.Actor == "" {
+        event.Actor = "system"
+    }
+
+    if _, err := em.audit.Append(event); err != nil {
+        return fmt.Errorf("%w: %w", ErrAudit, err)
+    }
+    return nil
+}
+
+// recordRosterChanges records an event for every employee whose record differs
+// between two versions of the roster. Callers must hold em.mu
+func (em *EmployeeManager) recordRosterChanges(action string, before []Employee, after []Employee) error {
+    positions := make(map[int]int, len(before))
+    for i, emp := range before {
+        positions[emp.ID] = i
+    }
+
+    for i := range after {
+        if j, ok := positions[after[i].ID]; ok && !reflect.DeepEqual(before[j], after[i]) {
+            if err := em.recordChange(action, &before[j], &after[i]); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+// clone returns a copy of the event that shares no memory with it
+func (ce ChangeEvent) clone() ChangeEvent {
+    ce.Before = cloneEmployee(ce.Before)
+    ce.After = cloneEmployee(ce.After)
+    ce.Changes = append([]FieldChange(nil), ce.Changes...)
+    return ce
+}
+
+// cloneEmployee returns a deep copy of emp, or nil
+func cloneEmployee(emp *Employee) *Employee {
+    if emp == nil {
+        return nil
+    }
+    copied := *emp
+    if emp.TerminationDate != nil {
+        terminated := *emp.TerminationDate
+        copied.TerminationDate = &terminated
+    }
+    return &copied
+}
+
+// diffEmployees lists the fields that differ between two versions of a record,
+// named by their JSON keys. A nil version counts as having every field empty
+func diffEmployees(before *Employee, after *Employee) []FieldChange {
+    beforeFields, afterFields := employeeFields(before), employeeFields(after)
+
+    keys := make([]string, 0, len(afterFields))
+    for key := range afterFields {
+        keys = append(keys, key)
+    }
+    for key := range beforeFields {
+        if _, ok := afterFields[key]; !ok {
+            keys = append(keys, key)
+        }
+    }
+    sort.Strings(keys)
+
+    changes := make([]FieldChange, 0)
+    for _, key := range keys {
+        if !reflect.DeepEqual(beforeFields[key], afterFields[key]) {
+            changes = append(changes, FieldChange{Field: key, Before: beforeFields[key], After: afterFields[key]})
+        }
+    }
+    return changes
+}
+
+// employeeFields returns emp's JSON representation as a map of field name to value
+func employeeFields(emp *Employee) map[string]interface{} {
+    fields := make(map[string]interface{})
+    if emp == nil {
+        return fields
+    }
+
+    data, err := json.Marshal(emp)
+    if err != nil {
+        return fields
+    }
+    json.Unmarshal(data, &fields)
+    return fields
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+// appendAuditEvents appends one CREATE event per ID to log
+func appendAuditEvents(t *testing.T, log AuditLog, ids ...int) {
+    t.Helper()
+    for _, id := range ids {
+        emp := Employee{ID: id, Name: "Asha Rao", Age: 30, Department: IT_DEPT}
+        if _, err := log.Append(ChangeEvent{EmployeeID: id, Action: AUDIT_CREATE, Actor: "test", Timestamp: time.Now(), After: &emp}); err != nil {
+            t.Fatal(err)
+        }
+    }
+}
+
+func TestFileAuditLogTruncatesTornLine(t *testing.T) {
+    tests := []struct {
+        name string
+        tail string
+    }{
+        {"partial object", `{"seq":3,"employee_id":3,"act`},
+        {"complete object without newline", `{"seq":3,"employee_id":3,"action":"CREATE"}`},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            path := filepath.Join(t.TempDir(), "audit.log")
+            log, err := OpenFileAuditLog(path)
+            if err != nil {
+                t.Fatal(err)
+            }
+            appendAuditEvents(t, log, 1, 2)
+            log.Close()
+
+            info, err := os.Stat(path)
+            if err != nil {
+                t.Fatal(err)
+            }
+            file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+            if err != nil {
+                t.Fatal(err)
+            }
+            file.WriteString(tt.tail)
+            file.Close()
+
+            log, err = OpenFileAuditLog(path)
+            if err != nil {
+                t.Fatalf("reopening a log with a torn last line: %v", err)
+            }
+            defer log.Close()
+            if after, _ := os.Stat(path); after.Size() != info.Size() {
+                t.Errorf("log is %d bytes after reopening, want the torn line cut back to %d", after.Size(), info.Size())
+            }
+
+            appendAuditEvents(t, log, 3)
+            events, err := log.Events()
+            if err != nil {
+                t.Fatal(err)
+            }
+            if len(events) != 3 || events[2].Seq != 3 || events[2].EmployeeID != 3 {
+                t.Errorf("events after the next append = %+v, want 3 events ending with seq 3", events)
+            }
+        })
+    }
+}
+
+func TestFileAuditLogRejectsCorruptLine(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "audit.log")
+    log, err := OpenFileAuditLog(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    appendAuditEvents(t, log, 1)
+    log.Close()
+
+    // A damaged line followed by a complete one is not a torn append
+    file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+    if err != nil {
+        t.Fatal(err)
+    }
+    file.WriteString("{\"seq\":2,\n{\"seq\":3}\n")
+    file.Close()
+
+    if _, err := OpenFileAuditLog(path); err == nil {
+        t.Fatal("opened a log with a corrupt line before the last")
+    }
+}
+
+func TestRosterAsOf(t *testing.T) {
+    day := func(d int) time.Time {
+        return time.Date(2024, time.March, d, 9, 0, 0, 0, time.Local)
+    }
+    hired := Employee{ID: 1, Name: "Asha Rao", Age: 30, Department: IT_DEPT, Status: STATUS_ACTIVE}
+    moved := hired
+    moved.Department = HR_DEPT
+    left := moved
+    left.Status = STATUS_TERMINATED
+    leftOn := day(20)
+    left.TerminationDate = &leftOn
+    rolledBack := Employee{ID: 2, Name: "Ravi Nair", Age: 28, Department: IT_DEPT, Status: STATUS_ACTIVE}
+
+    log := NewMemoryAuditLog()
+    for _, event := range []ChangeEvent{
+        {EmployeeID: 1, Action: AUDIT_CREATE, Timestamp: day(1), After: &hired},
+        {EmployeeID: 2, Action: AUDIT_CREATE, Timestamp: day(5), After: &rolledBack},
+        {EmployeeID: 2, Action: AUDIT_ROLLBACK, Timestamp: day(5).Add(time.Second), Before: &rolledBack},
+        {EmployeeID: 1, Action: AUDIT_UPDATE, Timestamp: day(10), Before: &hired, After: &moved},
+        {EmployeeID: 1, Action: AUDIT_TERMINATE, Timestamp: day(20), Before: &moved, After: &left},
+    } {
+        if _, err := log.Append(event); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    em := NewEmployeeManager()
+    if _, err := em.RosterAsOf(day(1)); !errors.Is(err, ErrAudit) {
+        t.Errorf("RosterAsOf without an audit log = %v, want ErrAudit", err)
+    }
+    if err := em.SetAuditLog(log); err != nil {
+        t.Fatal(err)
+    }
+
+    tests := []struct {
+        name     string
+        asOf     time.Time
+        wantDept string
+        wantLeft bool
+    }{
+        {"before anyone joined", day(1).Add(-time.Hour), "", false},
+        {"on joining", day(1), IT_DEPT, false},
+        {"after the rolled back hire", day(6), IT_DEPT, false},
+        {"after the transfer", day(15), HR_DEPT, false},
+        {"after leaving", day(25), HR_DEPT, true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            roster, err := em.RosterAsOf(tt.asOf)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if tt.wantDept == "" {
+                if len(roster) != 0 {
+                    t.Errorf("roster = %+v, want nobody", roster)
+                }
+                return
+            }
+            if len(roster) != 1 || roster[0].ID != 1 {
+                t.Fatalf("roster = %+v, want only employee 1", roster)
+            }
+            if roster[0].Department != tt.wantDept || (roster[0].TerminationDate != nil) != tt.wantLeft {
+                t.Errorf("employee 1 = %+v, want department %s and terminated %v", roster[0], tt.wantDept, tt.wantLeft)
+            }
+        })
+    }
+}
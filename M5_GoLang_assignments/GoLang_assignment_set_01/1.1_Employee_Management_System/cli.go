@@ -0,0 +1,655 @@
+package main
+
+import (
+    "bufio"
+    "encoding/csv"
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "strconv"
+    "strings"
+    "text/tabwriter"
+    "time"
+)
+
+// Output formats accepted by the -format flag
+const (
+    FORMAT_TABLE = "table"
+    FORMAT_JSON  = "json"
+    FORMAT_CSV   = "csv"
+)
+
+// cliCommands is the usage of every subcommand, in the order help prints them
+var cliCommands = []string{
+    "add -id ID -name NAME -age AGE -dept DEPT [-email E] [-phone P] [-hire-date YYYY-MM-DD] [-salary S] [-title T]",
+    "find [-include-terminated] ID",
+    "search [-fuzzy N] [-dept D,D] [-min-age A] [-max-age A] [-sort FIELD] [-desc] [-offset N] [-limit N] [-include-terminated] [NAME]",
+    "list [-dept DEPT] [-include-sub] [-include-terminated]",
+    "count [-dept DEPT] [-include-sub]",
+    "import FILE",
+    "export [-dept DEPT] [FILE]   (always CSV; stdout when FILE is omitted)",
+    "report [-from YYYY-MM-DD] [-to YYYY-MM-DD]   (department analytics; default the last 12 months)",
+    "history ID   (every recorded change to an employee)",
+    "payroll [-csv FILE] YYYY-MM   (payslips for a month; -csv writes the run to FILE instead)",
+    "repl   (interactive mode; also accepts: format table|json|csv, help, exit)",
+}
+
+// CLI runs the employee subcommands against an EmployeeManager
+type CLI struct {
+    manager *EmployeeManager
+    out     io.Writer
+    format  string
+}
+
+// NewCLI creates a CLI that writes results to out in the given format
+func NewCLI(manager *EmployeeManager, out io.Writer, format string) *CLI {
+    return &CLI{
+        manager: manager,
+        out:     out,
+        format:  strings.ToLower(format),
+    }
+}
+
+// Run executes one subcommand; args[0] is the subcommand name
+func (c *CLI) Run(args []string) error {
+    if len(args) == 0 {
+        c.help()
+        return nil
+    }
+
+    if err := checkFormat(c.format); err != nil {
+        return err
+    }
+
+    switch args[0] {
+    case "add":
+        return c.add(args[1:])
+    case "find":
+        return c.find(args[1:])
+    case "search":
+        return c.search(args[1:])
+    case "list":
+        return c.list(args[1:])
+    case "count":
+        return c.count(args[1:])
+    case "import":
+        return c.importFile(args[1:])
+    case "export":
+        return c.export(args[1:])
+    case "report":
+        return c.report(args[1:])
+    case "history":
+        return c.history(args[1:])
+    case "payroll":
+        return c.payroll(args[1:])
+    case "repl":
+        return c.REPL(os.Stdin)
+    case "help":
+        c.help()
+        return nil
+    default:
+        return fmt.Errorf("unknown command %q (try help)", args[0])
+    }
+}
+
+// REPL reads commands from in, one per line, until EOF or exit. Errors are printed
+// and the session carries on
+func (c *CLI) REPL(in io.Reader) error {
+    scanner := bufio.NewScanner(in)
+    fmt.Fprintln(c.out, "Employee management shell. Type help for commands, exit to quit.")
+    for {
+        fmt.Fprint(c.out, "employees> ")
+        if !scanner.Scan() {
+            fmt.Fprintln(c.out)
+            return scanner.Err()
+        }
+
+        args, err := splitCommandLine(scanner.Text())
+        if err != nil {
+            fmt.Fprintf(c.out, "Error: %v\n", err)
+            continue
+        }
+        if len(args) == 0 {
+            continue
+        }
+
+        switch args[0] {
+        case "exit", "quit":
+            return nil
+        case "repl":
+            fmt.Fprintln(c.out, "Already in interactive mode")
+        case "format":
+            if len(args) != 2 {
+                fmt.Fprintf(c.out, "Output format is %s\n", c.format)
+                continue
+            }
+            if err := checkFormat(strings.ToLower(args[1])); err != nil {
+                fmt.Fprintf(c.out, "Error: %v\n", err)
+                continue
+            }
+            c.format = strings.ToLower(args[1])
+        default:
+            if err := c.Run(args); err != nil {
+                fmt.Fprintf(c.out, "Error: %v\n", err)
+            }
+        }
+    }
+}
+
+// help prints every subcommand's usage
+func (c *CLI) help() {
+    fmt.Fprintln(c.out, "Commands:")
+    for _, usage := range cliCommands {
+        fmt.Fprintf(c.out, "  %s\n", usage)
+    }
+}
+
+// add hires a new employee from flags
+func (c *CLI) add(args []string) error {
+    fs := c.flagSet("add")
+    id := fs.Int("id", 0, "employee ID")
+    name := fs.String("name", "", "full name")
+    age := fs.Int("age", 0, "age in years")
+    dept := fs.String("dept", "", "department")
+    email := fs.String("email", "", "email address")
+    phone := fs.String("phone", "", "phone number")
+    hireDate := fs.String("hire-date", "", "hire date as YYYY-MM-DD (default: today)")
+    salary := fs.Float64("salary", 0, "monthly salary")
+    title := fs.String("title", "", "job title")
+    if _, err := parseFlags(fs, args); err != nil {
+        return err
+    }
+
+    emp := Employee{
+        ID:         *id,
+        Name:       *name,
+        Age:        *age,
+        Department: *dept,
+        Email:      *email,
+        Phone:      *phone,
+        Salary:     *salary,
+        Title:      *title,
+    }
+    if *hireDate != "" {
+        parsed, err := time.ParseInLocation("2006-01-02", *hireDate, time.Local)
+        if err != nil {
+            return fmt.Errorf("hire date must be YYYY-MM-DD: %q", *hireDate)
+        }
+        emp.HireDate = parsed
+    }
+
+    if err := c.manager.AddEmployeeRecord(emp); err != nil {
+        return err
+    }
+    added, err := c.manager.SearchByID(emp.ID)
+    if err != nil {
+        return err
+    }
+    return c.writeEmployees([]*Employee{added})
+}
+
+// find looks up one employee by ID
+func (c *CLI) find(args []string) error {
+    fs := c.flagSet("find")
+    includeTerminated := fs.Bool("include-terminated", false, "also find employees who have left")
+    positional, err := parseFlags(fs, args)
+    if err != nil {
+        return err
+    }
+    if len(positional) != 1 {
+        return errors.New("usage: find [-include-terminated] ID")
+    }
+    id, err := strconv.Atoi(positional[0])
+    if err != nil {
+        return fmt.Errorf("employee ID must be a number: %q", positional[0])
+    }
+
+    var emp *Employee
+    if *includeTerminated {
+        emp, err = c.manager.SearchByIDIncludingTerminated(id)
+    } else {
+        emp, err = c.manager.SearchByID(id)
+    }
+    if err != nil {
+        return err
+    }
+    return c.writeEmployees([]*Employee{emp})
+}
+
+// search runs a query over the roster; a name is optional
+func (c *CLI) search(args []string) error {
+    fs := c.flagSet("search")
+    fuzzy := fs.Int("fuzzy", 0, "match names within this many typos instead of by substring")
+    depts := fs.String("dept", "", "comma-separated departments")
+    minAge := fs.Int("min-age", 0, "minimum age")
+    maxAge := fs.Int("max-age", 0, "maximum age")
+    sortField := fs.String("sort", SORT_BY_ID, "sort field")
+    descending := fs.Bool("desc", false, "sort in descending order")
+    offset := fs.Int("offset", 0, "skip this many matches")
+    limit := fs.Int("limit", 0, "return at most this many matches")
+    includeTerminated := fs.Bool("include-terminated", false, "also search employees who have left")
+    positional, err := parseFlags(fs, args)
+    if err != nil {
+        return err
+    }
+
+    query := c.manager.Query().
+        AgeBetween(*minAge, *maxAge).
+        SortBy(*sortField, *descending).
+        Offset(*offset).
+        Limit(*limit)
+    if name := strings.Join(positional, " "); name != "" {
+        if *fuzzy > 0 {
+            query.NameFuzzy(name, *fuzzy)
+        } else {
+            query.NameContains(name)
+        }
+    }
+    if *depts != "" {
+        query.InDepartments(strings.Split(*depts, ",")...)
+    }
+    if *includeTerminated {
+        query.IncludeTerminated()
+    }
+
+    result, err := query.Run()
+    if err != nil {
+        return err
+    }
+    if err := c.writeEmployees(result.Employees); err != nil {
+        return err
+    }
+    if c.format == FORMAT_TABLE {
+        fmt.Fprintf(c.out, "%d of %d matches\n", len(result.Employees), result.Total)
+    }
+    return nil
+}
+
+// list prints the whole roster or one department
+func (c *CLI) list(args []string) error {
+    fs := c.flagSet("list")
+    dept := fs.String("dept", "", "only list this department")
+    includeSub := fs.Bool("include-sub", false, "include sub-departments of -dept")
+    includeTerminated := fs.Bool("include-terminated", false, "also list employees who have left")
+    if _, err := parseFlags(fs, args); err != nil {
+        return err
+    }
+
+    if *dept == "" {
+        return c.writeEmployees(c.manager.ListEmployees(*includeTerminated))
+    }
+
+    // The list methods only fail when nobody matches, which just prints an empty list here
+    var employees []*Employee
+    switch {
+    case *includeSub:
+        employees, _ = c.manager.ListByDepartmentTree(*dept)
+    case *includeTerminated:
+        employees, _ = c.manager.ListByDepartmentIncludingTerminated(*dept)
+    default:
+        employees, _ = c.manager.ListByDepartment(*dept)
+    }
+    return c.writeEmployees(employees)
+}
+
+// count prints active headcount for one department, or for every active department
+func (c *CLI) count(args []string) error {
+    fs := c.flagSet("count")
+    dept := fs.String("dept", "", "only count this department")
+    includeSub := fs.Bool("include-sub", false, "include sub-departments")
+    if _, err := parseFlags(fs, args); err != nil {
+        return err
+    }
+
+    var names []string
+    if *dept != "" {
+        name := normalizeDepartment(*dept)
+        for _, d := range c.manager.Departments() {
+            if d.Name == name {
+                names = []string{name}
+                break
+            }
+        }
+        if names == nil {
+            return fmt.Errorf("department %s %w", name, ErrNotFound)
+        }
+    } else {
+        for _, d := range c.manager.Departments() {
+            if !d.Retired {
+                names = append(names, d.Name)
+            }
+        }
+    }
+
+    counts := make([]countResponse, 0, len(names))
+    for _, name := range names {
+        count := c.manager.CountByDepartment(name)
+        if *includeSub {
+            count = c.manager.CountByDepartmentTree(name)
+        }
+        counts = append(counts, countResponse{Department: name, Count: count})
+    }
+
+    switch c.format {
+    case FORMAT_JSON:
+        return c.writeJSON(counts)
+    case FORMAT_CSV:
+        rows := [][]string{{"department", "count"}}
+        for _, count := range counts {
+            rows = append(rows, []string{count.Department, strconv.Itoa(count.Count)})
+        }
+        return c.writeCSV(rows)
+    default:
+        tw := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+        fmt.Fprintln(tw, "DEPARTMENT\tCOUNT")
+        for _, count := range counts {
+            fmt.Fprintf(tw, "%s\t%d\n", count.Department, count.Count)
+        }
+        return tw.Flush()
+    }
+}
+
+// importFile imports employees from a CSV file and prints the per-row report
+func (c *CLI) importFile(args []string) error {
+    if len(args) != 1 {
+        return errors.New("usage: import FILE")
+    }
+
+    file, err := os.Open(args[0])
+    if err != nil {
+        return err
+    }
+    defer file.Close()
+
+    report, err := c.manager.ImportCSV(file)
+    if err != nil {
+        return err
+    }
+
+    switch c.format {
+    case FORMAT_JSON:
+        rows := make([]map[string]interface{}, len(report.Errors))
+        for i, rowErr := range report.Errors {
+            rows[i] = map[string]interface{}{"row": rowErr.Row, "error": rowErr.Err.Error()}
+        }
+        return c.writeJSON(map[string]interface{}{"imported": report.Imported, "errors": rows})
+    case FORMAT_CSV:
+        rows := [][]string{{"row", "error"}}
+        for _, rowErr := range report.Errors {
+            rows = append(rows, []string{strconv.Itoa(rowErr.Row), rowErr.Err.Error()})
+        }
+        return c.writeCSV(rows)
+    default:
+        fmt.Fprintf(c.out, "Imported %d employees, %d rows rejected\n", report.Imported, len(report.Errors))
+        for _, rowErr := range report.Errors {
+            fmt.Fprintf(c.out, "  %v\n", rowErr)
+        }
+        return nil
+    }
+}
+
+// export writes the roster as CSV to a file, or to the CLI's output
+func (c *CLI) export(args []string) error {
+    fs := c.flagSet("export")
+    dept := fs.String("dept", "", "only export this department")
+    positional, err := parseFlags(fs, args)
+    if err != nil {
+        return err
+    }
+
+    switch len(positional) {
+    case 0:
+        return c.manager.ExportCSV(c.out, *dept)
+    case 1:
+        file, err := os.Create(positional[0])
+        if err != nil {
+            return err
+        }
+        if err := c.manager.ExportCSV(file, *dept); err != nil {
+            file.Close()
+            return err
+        }
+        return file.Close()
+    default:
+        return errors.New("usage: export [-dept DEPT] [FILE]")
+    }
+}
+
+// report prints department analytics for a period
+func (c *CLI) report(args []string) error {
+    fs := c.flagSet("report")
+    fromFlag := fs.String("from", "", "first day of the period as YYYY-MM-DD (default: 12 months ago)")
+    toFlag := fs.String("to", "", "last day of the period as YYYY-MM-DD (default: today)")
+    if _, err := parseFlags(fs, args); err != nil {
+        return err
+    }
+
+    from, to, err := analyticsPeriod(*fromFlag, *toFlag)
+    if err != nil {
+        return err
+    }
+    report, err := c.manager.DepartmentAnalytics(from, to)
+    if err != nil {
+        return err
+    }
+
+    switch c.format {
+    case FORMAT_JSON:
+        return report.WriteJSON(c.out)
+    case FORMAT_CSV:
+        rows := [][]string{{"department", "headcount", "average_age", "median_age", "new_hires", "leavers", "transfers_in", "transfers_out", "attrition_rate"}}
+        for _, s := range append(report.Departments, report.Total) {
+            rows = append(rows, []string{
+                s.Department,
+                strconv.Itoa(s.Headcount),
+                strconv.FormatFloat(s.AverageAge, 'f', 1, 64),
+                strconv.FormatFloat(s.MedianAge, 'f', 1, 64),
+                strconv.Itoa(s.NewHires),
+                strconv.Itoa(s.Leavers),
+                strconv.Itoa(s.TransfersIn),
+                strconv.Itoa(s.TransfersOut),
+                strconv.FormatFloat(s.AttritionRate, 'f', 1, 64),
+            })
+        }
+        return c.writeCSV(rows)
+    default:
+        return report.WriteText(c.out)
+    }
+}
+
+// history prints every recorded change to one employee, oldest first
+func (c *CLI) history(args []string) error {
+    if len(args) != 1 {
+        return errors.New("usage: history ID")
+    }
+    id, err := strconv.Atoi(args[0])
+    if err != nil {
+        return fmt.Errorf("employee ID must be a number: %q", args[0])
+    }
+
+    history, err := c.manager.EmployeeHistory(id)
+    if err != nil {
+        return err
+    }
+
+    switch c.format {
+    case FORMAT_JSON:
+        return c.writeJSON(history)
+    case FORMAT_CSV:
+        rows := [][]string{{"seq", "timestamp", "action", "actor", "field", "before", "after"}}
+        for _, event := range history {
+            for _, change := range event.Changes {
+                rows = append(rows, []string{strconv.Itoa(event.Seq), event.Timestamp.Format(time.RFC3339), event.Action,
+                    event.Actor, change.Field, fmt.Sprint(change.Before), fmt.Sprint(change.After)})
+            }
+        }
+        return c.writeCSV(rows)
+    default:
+        for _, event := range history {
+            fmt.Fprintf(c.out, "#%d %s %s by %s\n", event.Seq, event.Timestamp.Format(time.RFC3339), event.Action, event.Actor)
+            for _, change := range event.Changes {
+                fmt.Fprintf(c.out, "  %s: %v -> %v\n", change.Field, change.Before, change.After)
+            }
+        }
+        return nil
+    }
+}
+
+// payroll prints the payslips for one month, or writes the run to a CSV file
+func (c *CLI) payroll(args []string) error {
+    fs := c.flagSet("payroll")
+    csvPath := fs.String("csv", "", "write the payroll run to this CSV file")
+    positional, err := parseFlags(fs, args)
+    if err != nil {
+        return err
+    }
+    if len(positional) != 1 {
+        return errors.New("usage: payroll [-csv FILE] YYYY-MM")
+    }
+    period, err := time.Parse("2006-01", positional[0])
+    if err != nil {
+        return fmt.Errorf("payroll month must be YYYY-MM: %q", positional[0])
+    }
+
+    run, err := NewPayrollEngine(c.manager, DefaultPayrollConfig()).Run(period.Year(), period.Month())
+    if err != nil {
+        return err
+    }
+
+    if *csvPath != "" {
+        file, err := os.Create(*csvPath)
+        if err != nil {
+            return err
+        }
+        if err := run.WriteCSV(file); err != nil {
+            file.Close()
+            return err
+        }
+        return file.Close()
+    }
+    switch c.format {
+    case FORMAT_JSON:
+        return c.writeJSON(run)
+    case FORMAT_CSV:
+        return run.WriteCSV(c.out)
+    default:
+        return run.WriteText(c.out)
+    }
+}
+
+// writeEmployees prints employees in the CLI's output format
+func (c *CLI) writeEmployees(employees []*Employee) error {
+    switch c.format {
+    case FORMAT_JSON:
+        return c.writeJSON(employees)
+    case FORMAT_CSV:
+        return writeEmployeesCSV(c.out, employees)
+    default:
+        tw := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+        fmt.Fprintln(tw, "ID\tNAME\tAGE\tDEPARTMENT\tTITLE\tSTATUS\tHIRED")
+        for _, emp := range employees {
+            hired := ""
+            if !emp.HireDate.IsZero() {
+                hired = emp.HireDate.Format("2006-01-02")
+            }
+            fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%s\t%s\t%s\n",
+                emp.ID, emp.Name, emp.Age, emp.Department, emp.Title, emp.Status, hired)
+        }
+        return tw.Flush()
+    }
+}
+
+// writeJSON prints v as indented JSON
+func (c *CLI) writeJSON(v interface{}) error {
+    encoder := json.NewEncoder(c.out)
+    encoder.SetIndent("", "  ")
+    return encoder.Encode(v)
+}
+
+// writeCSV prints rows as CSV
+func (c *CLI) writeCSV(rows [][]string) error {
+    writer := csv.NewWriter(c.out)
+    writer.WriteAll(rows)
+    return writer.Error()
+}
+
+// checkFormat reports an error unless format is one of the FORMAT_ constants
+func checkFormat(format string) error {
+    switch format {
+    case FORMAT_TABLE, FORMAT_JSON, FORMAT_CSV:
+        return nil
+    default:
+        return fmt.Errorf("unknown output format %q: use table, json or csv", format)
+    }
+}
+
+// flagSet creates a subcommand flag set that reports errors instead of exiting
+func (c *CLI) flagSet(name string) *flag.FlagSet {
+    fs := flag.NewFlagSet(name, flag.ContinueOnError)
+    fs.SetOutput(c.out)
+    return fs
+}
+
+// parseFlags parses args into fs, allowing flags before and after positional arguments
+// up to a "--" terminator, and returns the positional arguments
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+    var positional []string
+    for {
+        if err := fs.Parse(args); err != nil {
+            return nil, err
+        }
+        rest := fs.Args()
+        if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+            return append(positional, rest...), nil
+        }
+        args = rest
+        if len(args) == 0 {
+            return positional, nil
+        }
+        positional = append(positional, args[0])
+        args = args[1:]
+    }
+}
+
+// splitCommandLine splits a REPL line into arguments on whitespace. Single or double
+// quotes group words, so names with spaces can be passed as one argument
+func splitCommandLine(line string) ([]string, error) {
+    var args []string
+    var current strings.Builder
+    var quote rune
+    inArg := false
+
+    for _, r := range line {
+        switch {
+        case quote != 0:
+            if r == quote {
+                quote = 0
+            } else {
+                current.WriteRune(r)
+            }
+        case r == '"' || r == '\'':
+            quote = r
+            inArg = true
+        case r == ' ' || r == '\t':
+            if inArg {
+                args = append(args, current.String())
+                current.Reset()
+                inArg = false
+            }
+        default:
+            current.WriteRune(r)
+            inArg = true
+        }
+    }
+
+    if quote != 0 {
+        return nil, errors.New("unterminated quote")
+    }
+    if inArg {
+        args = append(args, current.String())
+    }
+    return args, nil
+}
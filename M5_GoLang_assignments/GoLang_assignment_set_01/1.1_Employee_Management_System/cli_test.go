@@ -0,0 +1,207 @@
+package main
+
+import (
+    "bytes"
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestParseFlags(t *testing.T) {
+    tests := []struct {
+        name           string
+        args           []string
+        wantDept       string
+        wantAll        bool
+        wantPositional string
+        wantErr        bool
+    }{
+        {"nothing", nil, "", false, "[]", false},
+        {"flags only", []string{"-dept", "IT", "-all"}, "IT", true, "[]", false},
+        {"flags before", []string{"-dept=HR", "Asha", "Rao"}, "HR", false, "[Asha Rao]", false},
+        {"flags after", []string{"Asha", "-all", "Rao", "-dept", "IT"}, "IT", true, "[Asha Rao]", false},
+        {"double dash", []string{"-all", "--", "-dept"}, "", true, "[-dept]", false},
+        {"double dash after positional", []string{"Asha", "--", "Rao", "-all", "-dept", "IT"}, "", false, "[Asha Rao -all -dept IT]", false},
+        {"unknown flag", []string{"-shoe-size", "9"}, "", false, "", true},
+        {"missing value", []string{"Asha", "-dept"}, "", false, "", true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            fs := flag.NewFlagSet("test", flag.ContinueOnError)
+            fs.SetOutput(&bytes.Buffer{})
+            dept := fs.String("dept", "", "")
+            all := fs.Bool("all", false, "")
+
+            positional, err := parseFlags(fs, tt.args)
+            if tt.wantErr {
+                if err == nil {
+                    t.Fatalf("parseFlags(%q) succeeded, want an error", tt.args)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatal(err)
+            }
+            if *dept != tt.wantDept || *all != tt.wantAll || fmt.Sprint(positional) != tt.wantPositional {
+                t.Errorf("parseFlags(%q) = %v with -dept %q -all %v, want %s with -dept %q -all %v",
+                    tt.args, positional, *dept, *all, tt.wantPositional, tt.wantDept, tt.wantAll)
+            }
+        })
+    }
+}
+
+func TestSplitCommandLine(t *testing.T) {
+    tests := []struct {
+        line    string
+        want    []string
+        wantErr bool
+    }{
+        {"", nil, false},
+        {"   \t ", nil, false},
+        {"find 1", []string{"find", "1"}, false},
+        {"  list\t-dept   IT  ", []string{"list", "-dept", "IT"}, false},
+        {`search "Asha Rao"`, []string{"search", "Asha Rao"}, false},
+        {`add -name 'Sneha "S" Patil'`, []string{"add", "-name", `Sneha "S" Patil`}, false},
+        {`add -title Senior" Engineer"`, []string{"add", "-title", "Senior Engineer"}, false},
+        {`search ""`, []string{"search", ""}, false},
+        {`search "Asha`, nil, true},
+    }
+
+    for _, tt := range tests {
+        got, err := splitCommandLine(tt.line)
+        if tt.wantErr {
+            if err == nil {
+                t.Errorf("splitCommandLine(%q) = %q, want an error", tt.line, got)
+            }
+            continue
+        }
+        if err != nil || fmt.Sprintf("%q", got) != fmt.Sprintf("%q", tt.want) {
+            t.Errorf("splitCommandLine(%q) = %q, %v; want %q", tt.line, got, err, tt.want)
+        }
+    }
+}
+
+// newCLITestManager returns a roster of two IT employees and one in HR, with a change history
+func newCLITestManager(t *testing.T) *EmployeeManager {
+    t.Helper()
+    em := NewEmployeeManager()
+    if err := em.SetAuditLog(NewMemoryAuditLog()); err != nil {
+        t.Fatal(err)
+    }
+    hired := time.Date(2023, time.January, 9, 0, 0, 0, 0, time.Local)
+    for _, emp := range []Employee{
+        {ID: 1, Name: "Asha Rao", Age: 30, Department: IT_DEPT, Salary: 60000, HireDate: hired},
+        {ID: 2, Name: "Ravi Nair", Age: 45, Department: HR_DEPT, Salary: 50000, HireDate: hired},
+        {ID: 3, Name: "Meera Iyer", Age: 28, Department: IT_DEPT, Salary: 40000, HireDate: hired},
+    } {
+        if err := em.AddEmployeeRecord(emp); err != nil {
+            t.Fatal(err)
+        }
+    }
+    return em
+}
+
+func TestCLIDispatch(t *testing.T) {
+    exported := filepath.Join(t.TempDir(), "export.csv")
+
+    tests := []struct {
+        name    string
+        format  string
+        args    []string
+        want    []string
+        wantErr string
+    }{
+        {"no command prints help", FORMAT_TABLE, nil, []string{"Commands:", "payroll [-csv FILE] YYYY-MM"}, ""},
+        {"help", FORMAT_TABLE, []string{"help"}, []string{"history ID"}, ""},
+        {"unknown command", FORMAT_TABLE, []string{"fire", "1"}, nil, `unknown command "fire"`},
+        {"unknown format", "yaml", []string{"list"}, nil, `unknown output format "yaml"`},
+        {"add", FORMAT_TABLE, []string{"add", "-id", "4", "-name", "Kiran Das", "-age", "38", "-dept", "finance"}, []string{"Kiran Das", "FINANCE"}, ""},
+        {"add invalid", FORMAT_TABLE, []string{"add", "-id", "4", "-name", "Kiran Das", "-age", "16", "-dept", "IT"}, nil, "at least 18"},
+        {"find", FORMAT_JSON, []string{"find", "2"}, []string{`"name": "Ravi Nair"`}, ""},
+        {"find unknown", FORMAT_TABLE, []string{"find", "9"}, nil, "not found"},
+        {"find without ID", FORMAT_TABLE, []string{"find"}, nil, "usage: find"},
+        {"search", FORMAT_TABLE, []string{"search", "-sort", "age", "-dept", "IT"}, []string{"Meera Iyer", "Asha Rao", "2 of 2 matches"}, ""},
+        {"fuzzy search", FORMAT_CSV, []string{"search", "-fuzzy", "1", "ashe"}, []string{"1,Asha Rao,30,IT"}, ""},
+        {"list department", FORMAT_CSV, []string{"list", "-dept", "hr"}, []string{"2,Ravi Nair,45,HR"}, ""},
+        {"count", FORMAT_CSV, []string{"count"}, []string{"IT,2", "HR,1", "FINANCE,0"}, ""},
+        {"count department", FORMAT_CSV, []string{"count", "-dept", "it"}, []string{"IT,2"}, ""},
+        {"count unknown department", FORMAT_CSV, []string{"count", "-dept", "UNKNOWN"}, nil, "department UNKNOWN not found"},
+        {"export to stdout", FORMAT_TABLE, []string{"export", "-dept", "IT"}, []string{"id,name,age,department", "3,Meera Iyer,28,IT"}, ""},
+        {"export to file", FORMAT_TABLE, []string{"export", exported}, nil, ""},
+        {"import missing file", FORMAT_TABLE, []string{"import", filepath.Join(t.TempDir(), "missing.csv")}, nil, "no such file"},
+        {"history", FORMAT_TABLE, []string{"history", "1"}, []string{"#1 ", "CREATE by system", "  name: <nil> -> Asha Rao"}, ""},
+        {"history unknown", FORMAT_TABLE, []string{"history", "9"}, nil, "not found"},
+        {"payroll", FORMAT_TABLE, []string{"payroll", "2024-06"}, []string{"Payslip for 2024-06", "3 payslips"}, ""},
+        {"payroll CSV", FORMAT_CSV, []string{"payroll", "2024-06"}, []string{"2024-06,2,Ravi Nair,HR,30,NET,Net Pay,"}, ""},
+        {"payroll bad month", FORMAT_TABLE, []string{"payroll", "June"}, nil, "YYYY-MM"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var out bytes.Buffer
+            err := NewCLI(newCLITestManager(t), &out, tt.format).Run(tt.args)
+            if tt.wantErr != "" {
+                if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+                    t.Fatalf("Run(%q) = %v, want an error containing %q", tt.args, err, tt.wantErr)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("Run(%q) = %v", tt.args, err)
+            }
+            for _, want := range tt.want {
+                if !strings.Contains(out.String(), want) {
+                    t.Errorf("Run(%q) printed\n%s\nwant it to contain %q", tt.args, out.String(), want)
+                }
+            }
+        })
+    }
+
+    data, err := os.ReadFile(exported)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if lines := strings.Count(string(data), "\n"); lines != 4 {
+        t.Errorf("exported file has %d lines, want a header and 3 employees:\n%s", lines, data)
+    }
+}
+
+func TestCLIREPL(t *testing.T) {
+    var out bytes.Buffer
+    cli := NewCLI(newCLITestManager(t), &out, FORMAT_TABLE)
+
+    input := strings.Join([]string{
+        `add -id 4 -name "Kiran Das" -age 38 -dept IT`,
+        `find "`,
+        `fire 1`,
+        `format yaml`,
+        `format json`,
+        `find 4`,
+        `exit`,
+        `find 1`,
+    }, "\n")
+    if err := cli.REPL(strings.NewReader(input)); err != nil {
+        t.Fatal(err)
+    }
+
+    printed := out.String()
+    for _, want := range []string{
+        "Kiran Das  38",
+        "Error: unterminated quote",
+        `Error: unknown command "fire"`,
+        `Error: unknown output format "yaml"`,
+        `"name": "Kiran Das"`,
+    } {
+        if !strings.Contains(printed, want) {
+            t.Errorf("REPL printed\n%s\nwant it to contain %q", printed, want)
+        }
+    }
+    if strings.Contains(printed, "Asha Rao") {
+        t.Errorf("REPL kept reading after exit:\n%s", printed)
+    }
+}
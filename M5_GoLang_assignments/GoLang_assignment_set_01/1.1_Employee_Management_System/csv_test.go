@@ -0,0 +1,142 @@
+package main
+
+import (
+    "bytes"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+)
+
+// countingStore is an in-memory Store that counts saves and can be made to fail them
+type countingStore struct {
+    roster Roster
+    saves  int
+    fail   bool
+}
+
+func (cs *countingStore) Load() (Roster, error) {
+    return cs.roster, nil
+}
+
+func (cs *countingStore) Save(roster Roster) error {
+    if cs.fail {
+        return errors.New("disk full")
+    }
+    cs.saves++
+    cs.roster = roster
+    return nil
+}
+
+func (cs *countingStore) Close() error {
+    return nil
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+    source := NewEmployeeManager()
+    records := []Employee{
+        {ID: 1, Name: "Asha Rao", Age: 41, Department: IT_DEPT, Email: "asha@example.com", Phone: "+91 98765 43210",
+            HireDate: time.Date(2019, time.July, 1, 0, 0, 0, 0, time.Local), Salary: 1850000.5, Title: "Lead, Platform"},
+        {ID: 2, Name: `Ravi "RN" Nair`, Age: 29, Department: HR_DEPT, HireDate: time.Date(2022, time.January, 10, 0, 0, 0, 0, time.Local)},
+    }
+    for _, emp := range records {
+        if err := source.AddEmployeeRecord(emp); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    var exported bytes.Buffer
+    if err := source.ExportCSV(&exported, ""); err != nil {
+        t.Fatal(err)
+    }
+
+    store := &countingStore{}
+    target, err := NewEmployeeManagerWithStore(store)
+    if err != nil {
+        t.Fatal(err)
+    }
+    report, err := target.ImportCSV(&exported)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if report.Imported != len(records) || len(report.Errors) != 0 {
+        t.Fatalf("imported %d with errors %v, want %d and none", report.Imported, report.Errors, len(records))
+    }
+    if store.saves != 1 {
+        t.Errorf("import saved the roster %d times, want once", store.saves)
+    }
+    for _, want := range records {
+        got, err := target.SearchByID(want.ID)
+        if err != nil {
+            t.Fatal(err)
+        }
+        want.Status = STATUS_ACTIVE
+        if got.Name != want.Name || got.Email != want.Email || got.Phone != want.Phone || got.Salary != want.Salary ||
+            got.Title != want.Title || !got.HireDate.Equal(want.HireDate) || got.Department != want.Department {
+            t.Errorf("employee %d imported as %+v, want %+v", want.ID, *got, want)
+        }
+    }
+}
+
+func TestImportCSVReportsBadRows(t *testing.T) {
+    input := strings.Join([]string{
+        "name,id,age,department,hire_date",
+        "Asha Rao,1,30,IT,2023-03-01",
+        "Bad ID,x,30,IT,",
+        "Bad Age,3,old,IT,",
+        "Underage,4,17,IT,",
+        "Unknown Department,5,30,OPS,",
+        `Bare "quote,6,30,IT,`,
+        "Ravi Nair,1,28,HR,",
+        "Meera Iyer,7,35,HR,01/02/2023",
+        "Kiran Das,8,24,HR,",
+    }, "\n")
+
+    em := NewEmployeeManager()
+    report, err := em.ImportCSV(strings.NewReader(input))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    wantRows := []int{3, 4, 5, 6, 7, 8, 9}
+    if report.Imported != 2 || len(report.Errors) != len(wantRows) {
+        t.Fatalf("imported %d with errors %v, want 2 imported and rows %v rejected", report.Imported, report.Errors, wantRows)
+    }
+    for i, row := range wantRows {
+        if report.Errors[i].Row != row {
+            t.Errorf("error %d is for row %d, want row %d: %v", i, report.Errors[i].Row, row, report.Errors[i])
+        }
+    }
+    if !errors.Is(report.Errors[2], ErrUnderage) || !errors.Is(report.Errors[3], ErrInvalidDepartment) {
+        t.Errorf("rows 5 and 6 failed with %v and %v, want %v and %v", report.Errors[2], report.Errors[3], ErrUnderage, ErrInvalidDepartment)
+    }
+    if !errors.Is(report.Errors[5], ErrDuplicateID) {
+        t.Errorf("row 8 reused ID 1 but failed with %v, want %v", report.Errors[5], ErrDuplicateID)
+    }
+    if got := len(em.ListEmployees(false)); got != 2 {
+        t.Errorf("roster holds %d employees, want 2", got)
+    }
+}
+
+func TestImportCSVRollsBackWhenSaveFails(t *testing.T) {
+    store := &countingStore{}
+    em, err := NewEmployeeManagerWithStore(store)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := em.SetAuditLog(NewMemoryAuditLog()); err != nil {
+        t.Fatal(err)
+    }
+    store.fail = true
+
+    report, err := em.ImportCSV(strings.NewReader("id,name,age,department\n1,Asha Rao,30,IT\n2,Ravi Nair,28,HR\n"))
+    if !errors.Is(err, ErrStorage) {
+        t.Fatalf("import with a failing store = %v, want %v", err, ErrStorage)
+    }
+    if report.Imported != 0 || len(em.ListEmployees(true)) != 0 {
+        t.Errorf("failed import left %d reported and %d on the roster, want none", report.Imported, len(em.ListEmployees(true)))
+    }
+    if roster, err := em.RosterAsOf(time.Now()); err != nil || len(roster) != 0 {
+        t.Errorf("audit log rebuilds %d employees after the rollback, want none (%v)", len(roster), err)
+    }
+}
@@ -1,3 +1,8 @@
 module main.go
 
 go 1.23.3
+
+require (
+	github.com/gorilla/mux v1.8.1
+	github.com/mattn/go-sqlite3 v1.14.24
+)
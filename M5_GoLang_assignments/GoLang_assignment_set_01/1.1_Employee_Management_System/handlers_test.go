@@ -0,0 +1,64 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gorilla/mux"
+)
+
+// newHandlerTestRouter serves a roster holding employee 1 in IT, hired on 1 March 2023
+func newHandlerTestRouter(t *testing.T) *mux.Router {
+    t.Helper()
+    em := NewEmployeeManager()
+    err := em.AddEmployeeRecord(Employee{ID: 1, Name: "Asha Rao", Age: 30, Department: IT_DEPT,
+        Email: "asha@example.com", HireDate: time.Date(2023, time.March, 1, 0, 0, 0, 0, time.Local)})
+    if err != nil {
+        t.Fatal(err)
+    }
+    router := mux.NewRouter()
+    NewEmployeeHandler(em).RegisterRoutes(router)
+    return router
+}
+
+func TestHandlerStatusCodes(t *testing.T) {
+    tests := []struct {
+        name   string
+        method string
+        path   string
+        body   string
+        want   int
+    }{
+        {"get", "GET", "/employees/1", "", http.StatusOK},
+        {"get unknown", "GET", "/employees/99", "", http.StatusNotFound},
+        {"get bad id", "GET", "/employees/abc", "", http.StatusBadRequest},
+        {"create", "POST", "/employees", `{"id":2,"name":"Ravi Nair","age":28,"department":"HR"}`, http.StatusCreated},
+        {"create bad body", "POST", "/employees", `{"id":`, http.StatusBadRequest},
+        {"create duplicate id", "POST", "/employees", `{"id":1,"name":"Ravi Nair","age":28,"department":"HR"}`, http.StatusConflict},
+        {"create duplicate email", "POST", "/employees", `{"id":2,"name":"Ravi Nair","age":28,"department":"HR","email":"ASHA@example.com"}`, http.StatusConflict},
+        {"create underage", "POST", "/employees", `{"id":2,"name":"Ravi Nair","age":17,"department":"HR"}`, http.StatusUnprocessableEntity},
+        {"create unknown department", "POST", "/employees", `{"id":2,"name":"Ravi Nair","age":28,"department":"OPS"}`, http.StatusUnprocessableEntity},
+        {"update", "PUT", "/employees/1", `{"name":"Asha Rao","age":31,"department":"HR"}`, http.StatusOK},
+        {"update unknown", "PUT", "/employees/99", `{"name":"Asha Rao","age":31,"department":"HR"}`, http.StatusNotFound},
+        {"delete", "DELETE", "/employees/1", "", http.StatusNoContent},
+        {"delete bad date", "DELETE", "/employees/1?termination_date=31-03-2024", "", http.StatusBadRequest},
+        {"delete before hire", "DELETE", "/employees/1?termination_date=2023-02-28", "", http.StatusUnprocessableEntity},
+        {"department count", "GET", "/departments/it/count", "", http.StatusOK},
+        {"unknown department", "GET", "/departments/ops/employees", "", http.StatusNotFound},
+        {"roster bad date", "GET", "/roster?as_of=yesterday", "", http.StatusBadRequest},
+        {"roster without audit log", "GET", "/roster?as_of=2024-01-01", "", http.StatusInternalServerError},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            router := newHandlerTestRouter(t)
+            w := httptest.NewRecorder()
+            router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+            if w.Code != tt.want {
+                t.Errorf("%s %s = %d, want %d: %s", tt.method, tt.path, w.Code, tt.want, w.Body.String())
+            }
+        })
+    }
+}
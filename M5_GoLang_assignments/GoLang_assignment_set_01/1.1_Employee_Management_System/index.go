@@ -0,0 +1,198 @@
+package main
+
+import (
+    "sort"
+    "strings"
+)
+
+// employeeIndex maps lookup keys to positions in EmployeeManager.employees.
+// Position lists are kept sorted so lookups return employees in the order they were added
+type employeeIndex struct {
+    byID               map[int]int
+    byEmail            map[string]int
+    byDepartment       map[string][]int
+    byManager          map[int][]int
+    byTrigram          map[string][]int
+    activeByDepartment map[string]int
+}
+
+// newEmployeeIndex builds an index over employees
+func newEmployeeIndex(employees []Employee) *employeeIndex {
+    ix := &employeeIndex{
+        byID:               make(map[int]int, len(employees)),
+        byEmail:            make(map[string]int),
+        byDepartment:       make(map[string][]int),
+        byManager:          make(map[int][]int),
+        byTrigram:          make(map[string][]int),
+        activeByDepartment: make(map[string]int),
+    }
+    for pos, emp := range employees {
+        ix.add(pos, emp)
+    }
+    return ix
+}
+
+// add indexes emp at position pos
+func (ix *employeeIndex) add(pos int, emp Employee) {
+    ix.byID[emp.ID] = pos
+    if emp.Email != "" {
+        ix.byEmail[strings.ToLower(emp.Email)] = pos
+    }
+    ix.byDepartment[emp.Department] = insertPosition(ix.byDepartment[emp.Department], pos)
+    if emp.ManagerID != 0 {
+        ix.byManager[emp.ManagerID] = insertPosition(ix.byManager[emp.ManagerID], pos)
+    }
+    for _, trigram := range trigrams(emp.Name) {
+        ix.byTrigram[trigram] = insertPosition(ix.byTrigram[trigram], pos)
+    }
+    if emp.IsActive() {
+        ix.activeByDepartment[emp.Department]++
+    }
+}
+
+// remove drops every entry add created for emp at position pos
+func (ix *employeeIndex) remove(pos int, emp Employee) {
+    delete(ix.byID, emp.ID)
+    if emp.Email != "" {
+        delete(ix.byEmail, strings.ToLower(emp.Email))
+    }
+    ix.byDepartment[emp.Department] = removePosition(ix.byDepartment[emp.Department], pos)
+    if emp.ManagerID != 0 {
+        ix.byManager[emp.ManagerID] = removePosition(ix.byManager[emp.ManagerID], pos)
+    }
+    for _, trigram := range trigrams(emp.Name) {
+        ix.byTrigram[trigram] = removePosition(ix.byTrigram[trigram], pos)
+    }
+    if emp.IsActive() {
+        ix.activeByDepartment[emp.Department]--
+    }
+}
+
+// position returns where the employee with the given ID is stored, or -1
+func (ix *employeeIndex) position(id int) int {
+    pos, ok := ix.byID[id]
+    if !ok {
+        return -1
+    }
+    return pos
+}
+
+// emailPosition returns where the employee with the given email is stored, or -1.
+// Emails are matched case-insensitively
+func (ix *employeeIndex) emailPosition(email string) int {
+    pos, ok := ix.byEmail[strings.ToLower(email)]
+    if !ok {
+        return -1
+    }
+    return pos
+}
+
+// departmentPositions returns the sorted positions of every employee in the given departments
+func (ix *employeeIndex) departmentPositions(departments []string) []int {
+    if len(departments) == 1 {
+        return ix.byDepartment[departments[0]]
+    }
+
+    var positions []int
+    for _, dept := range departments {
+        positions = append(positions, ix.byDepartment[dept]...)
+    }
+    sort.Ints(positions)
+    return positions
+}
+
+// reportPositions returns the sorted positions of every employee whose manager is managerID
+func (ix *employeeIndex) reportPositions(managerID int) []int {
+    return ix.byManager[managerID]
+}
+
+// activeCount returns the number of active employees in the given departments
+func (ix *employeeIndex) activeCount(departments []string) int {
+    count := 0
+    for _, dept := range departments {
+        count += ix.activeByDepartment[dept]
+    }
+    return count
+}
+
+// nameCandidates returns the positions of employees whose names contain every trigram of query.
+// Candidates still need a substring check. The second result is false when query is too
+// short to have trigrams, in which case the caller has to scan
+func (ix *employeeIndex) nameCandidates(query string) ([]int, bool) {
+    grams := trigrams(query)
+    if len(grams) == 0 {
+        return nil, false
+    }
+
+    // Intersect starting from the rarest trigram to keep the working set small
+    sort.Slice(grams, func(i, j int) bool {
+        return len(ix.byTrigram[grams[i]]) < len(ix.byTrigram[grams[j]])
+    })
+
+    candidates := ix.byTrigram[grams[0]]
+    for _, gram := range grams[1:] {
+        if len(candidates) == 0 {
+            break
+        }
+        candidates = intersectPositions(candidates, ix.byTrigram[gram])
+    }
+    return candidates, true
+}
+
+// trigrams returns the distinct three-rune substrings of the lower-cased s
+func trigrams(s string) []string {
+    runes := []rune(strings.ToLower(s))
+    if len(runes) < 3 {
+        return nil
+    }
+
+    seen := make(map[string]bool)
+    var grams []string
+    for i := 0; i+3 <= len(runes); i++ {
+        gram := string(runes[i : i+3])
+        if !seen[gram] {
+            seen[gram] = true
+            grams = append(grams, gram)
+        }
+    }
+    return grams
+}
+
+// insertPosition adds pos to the sorted slice positions
+func insertPosition(positions []int, pos int) []int {
+    i := sort.SearchInts(positions, pos)
+    if i < len(positions) && positions[i] == pos {
+        return positions
+    }
+    positions = append(positions, 0)
+    copy(positions[i+1:], positions[i:])
+    positions[i] = pos
+    return positions
+}
+
+// removePosition deletes pos from the sorted slice positions
+func removePosition(positions []int, pos int) []int {
+    i := sort.SearchInts(positions, pos)
+    if i == len(positions) || positions[i] != pos {
+        return positions
+    }
+    return append(positions[:i], positions[i+1:]...)
+}
+
+// intersectPositions returns the positions present in both sorted slices
+func intersectPositions(a []int, b []int) []int {
+    var result []int
+    for i, j := 0, 0; i < len(a) && j < len(b); {
+        switch {
+        case a[i] < b[j]:
+            i++
+        case a[i] > b[j]:
+            j++
+        default:
+            result = append(result, a[i])
+            i++
+            j++
+        }
+    }
+    return result
+}
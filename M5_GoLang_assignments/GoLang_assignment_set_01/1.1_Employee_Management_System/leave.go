@@ -0,0 +1,447 @@
+package main
+
+import (
+    "fmt"
+    "math"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Leave types
+const (
+    LEAVE_SICK   = "SICK"
+    LEAVE_CASUAL = "CASUAL"
+    LEAVE_EARNED = "EARNED"
+)
+
+// Leave request statuses
+const (
+    LEAVE_PENDING  = "PENDING"
+    LEAVE_APPROVED = "APPROVED"
+    LEAVE_REJECTED = "REJECTED"
+)
+
+// Attendance statuses
+const (
+    ATTENDANCE_PRESENT  = "PRESENT"
+    ATTENDANCE_ABSENT   = "ABSENT"
+    ATTENDANCE_HALF_DAY = "HALF_DAY"
+    ATTENDANCE_ON_LEAVE = "ON_LEAVE"
+)
+
+// AccrualPolicy says how one leave type builds up
+type AccrualPolicy struct {
+    // MonthlyDays is credited for every calendar month of service, including the month of joining
+    MonthlyDays float64 `json:"monthly_days"`
+    // MaxBalance caps the running balance each month, and credit over it lapses; 0 means no cap
+    MaxBalance float64 `json:"max_balance"`
+    // ResetYearly makes unused balance lapse at the end of each calendar year
+    ResetYearly bool `json:"reset_yearly"`
+}
+
+// LeavePolicies holds the accrual policy for each leave type, with per-department overrides
+type LeavePolicies struct {
+    Default     map[string]AccrualPolicy            `json:"default"`
+    Departments map[string]map[string]AccrualPolicy `json:"departments"`
+}
+
+// LeaveRequest is an employee's request for time off
+type LeaveRequest struct {
+    ID         int        `json:"id"`
+    EmployeeID int        `json:"employee_id"`
+    Type       string     `json:"type"`
+    From       time.Time  `json:"from"`
+    To         time.Time  `json:"to"`
+    Days       float64    `json:"days"`
+    Reason     string     `json:"reason,omitempty"`
+    Status     string     `json:"status"`
+    DecidedBy  string     `json:"decided_by,omitempty"`
+    DecidedAt  *time.Time `json:"decided_at,omitempty"`
+    Comment    string     `json:"comment,omitempty"`
+}
+
+// LeaveBalance is how much of one leave type an employee has
+type LeaveBalance struct {
+    Type      string  `json:"type"`
+    Accrued   float64 `json:"accrued"`
+    Used      float64 `json:"used"`
+    // Booked is approved leave falling after the balance date, not yet taken
+    Booked    float64 `json:"booked"`
+    Pending   float64 `json:"pending"`
+    Available float64 `json:"available"`
+}
+
+// AttendanceRecord is an employee's attendance on one day
+type AttendanceRecord struct {
+    EmployeeID int       `json:"employee_id"`
+    Date       time.Time `json:"date"`
+    Status     string    `json:"status"`
+    Note       string    `json:"note,omitempty"`
+}
+
+// LeaveTracker manages leave requests and daily attendance for an EmployeeManager's roster.
+// It is safe for concurrent use
+type LeaveTracker struct {
+    mu            sync.Mutex
+    manager       *EmployeeManager
+    policies      LeavePolicies
+    requests      []LeaveRequest
+    nextRequestID int
+    attendance    map[int]map[string]AttendanceRecord
+}
+
+// NewLeaveTracker creates a leave tracker for manager's roster using policies
+func NewLeaveTracker(manager *EmployeeManager, policies LeavePolicies) *LeaveTracker {
+    return &LeaveTracker{
+        manager:       manager,
+        policies:      policies,
+        requests:      make([]LeaveRequest, 0),
+        nextRequestID: 1,
+        attendance:    make(map[int]map[string]AttendanceRecord),
+    }
+}
+
+// DefaultLeavePolicies returns 1 sick and 1 casual day a month that lapse at year end,
+// and 1.5 earned days a month that carry forward up to 45 days
+func DefaultLeavePolicies() LeavePolicies {
+    return LeavePolicies{
+        Default: map[string]AccrualPolicy{
+            LEAVE_SICK:   {MonthlyDays: 1, ResetYearly: true},
+            LEAVE_CASUAL: {MonthlyDays: 1, ResetYearly: true},
+            LEAVE_EARNED: {MonthlyDays: 1.5, MaxBalance: 45},
+        },
+        Departments: make(map[string]map[string]AccrualPolicy),
+    }
+}
+
+// policy returns the accrual policy for leaveType in department
+func (lt *LeaveTracker) policy(department string, leaveType string) (AccrualPolicy, bool) {
+    if deptPolicies, ok := lt.policies.Departments[department]; ok {
+        if policy, ok := deptPolicies[leaveType]; ok {
+            return policy, true
+        }
+    }
+    policy, ok := lt.policies.Default[leaveType]
+    return policy, ok
+}
+
+// RequestLeave files a pending leave request for the working days (Monday to Friday)
+// from one date to another inclusive
+func (lt *LeaveTracker) RequestLeave(employeeID int, leaveType string, from time.Time, to time.Time, reason string) (LeaveRequest, error) {
+    lt.mu.Lock()
+    defer lt.mu.Unlock()
+
+    emp, err := lt.manager.SearchByID(employeeID)
+    if err != nil {
+        return LeaveRequest{}, err
+    }
+
+    leaveType = strings.ToUpper(leaveType)
+    if _, ok := lt.policy(emp.Department, leaveType); !ok {
+        return LeaveRequest{}, &ValidationError{Field: "type", Value: leaveType, Err: ErrInvalidLeaveType}
+    }
+
+    from, to = dateOnly(from), dateOnly(to)
+    if to.Before(from) {
+        return LeaveRequest{}, &ValidationError{Field: "to", Value: to.Format("2006-01-02"), Err: ErrInvalidDateRange}
+    }
+    if !emp.HireDate.IsZero() && from.Before(dateOnly(emp.HireDate)) {
+        return LeaveRequest{}, &ValidationError{Field: "from", Value: from.Format("2006-01-02"), Err: ErrInvalidDateRange}
+    }
+
+    days := float64(workingDays(from, to))
+    if days == 0 {
+        return LeaveRequest{}, &ValidationError{Field: "from", Value: from.Format("2006-01-02"), Err: ErrInvalidDateRange}
+    }
+
+    for _, other := range lt.requests {
+        if other.EmployeeID == employeeID && other.Status != LEAVE_REJECTED &&
+            !other.From.After(to) && !other.To.Before(from) {
+            return LeaveRequest{}, fmt.Errorf("request %d: %w", other.ID, ErrLeaveOverlap)
+        }
+    }
+
+    balance := lt.balance(*emp, leaveType, from)
+    if free := balance.Available - balance.Booked - balance.Pending; free < days {
+        return LeaveRequest{}, fmt.Errorf("%w: %.1f %s days requested, %.1f available",
+            ErrInsufficientLeave, days, leaveType, free)
+    }
+
+    request := LeaveRequest{
+        ID:         lt.nextRequestID,
+        EmployeeID: employeeID,
+        Type:       leaveType,
+        From:       from,
+        To:         to,
+        Days:       days,
+        Reason:     reason,
+        Status:     LEAVE_PENDING,
+    }
+    lt.nextRequestID++
+    lt.requests = append(lt.requests, request)
+    return request, nil
+}
+
+// ApproveLeave approves a pending request, re-checking the employee and balance first
+func (lt *LeaveTracker) ApproveLeave(requestID int, approver string) error {
+    lt.mu.Lock()
+    defer lt.mu.Unlock()
+
+    i, err := lt.pendingRequest(requestID)
+    if err != nil {
+        return err
+    }
+    request := lt.requests[i]
+
+    emp, err := lt.manager.SearchByID(request.EmployeeID)
+    if err != nil {
+        return err
+    }
+
+    // Other pending requests have not been granted yet, so only leave already booked is set aside
+    balance := lt.balance(*emp, request.Type, request.From)
+    if free := balance.Available - balance.Booked; free < request.Days {
+        return fmt.Errorf("%w: %.1f %s days requested, %.1f available",
+            ErrInsufficientLeave, request.Days, request.Type, free)
+    }
+
+    lt.decide(i, LEAVE_APPROVED, approver, "")
+    return nil
+}
+
+// RejectLeave rejects a pending request with an optional comment for the employee
+func (lt *LeaveTracker) RejectLeave(requestID int, approver string, comment string) error {
+    lt.mu.Lock()
+    defer lt.mu.Unlock()
+
+    i, err := lt.pendingRequest(requestID)
+    if err != nil {
+        return err
+    }
+
+    lt.decide(i, LEAVE_REJECTED, approver, comment)
+    return nil
+}
+
+// LeaveRequests returns every request filed by an employee, oldest first
+func (lt *LeaveTracker) LeaveRequests(employeeID int) []LeaveRequest {
+    lt.mu.Lock()
+    defer lt.mu.Unlock()
+
+    requests := make([]LeaveRequest, 0)
+    for _, request := range lt.requests {
+        if request.EmployeeID == employeeID {
+            requests = append(requests, request)
+        }
+    }
+    return requests
+}
+
+// PendingRequests returns every request awaiting a decision, oldest first
+func (lt *LeaveTracker) PendingRequests() []LeaveRequest {
+    lt.mu.Lock()
+    defer lt.mu.Unlock()
+
+    requests := make([]LeaveRequest, 0)
+    for _, request := range lt.requests {
+        if request.Status == LEAVE_PENDING {
+            requests = append(requests, request)
+        }
+    }
+    return requests
+}
+
+// Balance returns an active employee's balance of leaveType as of a date
+func (lt *LeaveTracker) Balance(employeeID int, leaveType string, asOf time.Time) (LeaveBalance, error) {
+    lt.mu.Lock()
+    defer lt.mu.Unlock()
+
+    emp, err := lt.manager.SearchByID(employeeID)
+    if err != nil {
+        return LeaveBalance{}, err
+    }
+
+    leaveType = strings.ToUpper(leaveType)
+    if _, ok := lt.policy(emp.Department, leaveType); !ok {
+        return LeaveBalance{}, &ValidationError{Field: "type", Value: leaveType, Err: ErrInvalidLeaveType}
+    }
+    return lt.balance(*emp, leaveType, dateOnly(asOf)), nil
+}
+
+// RecordAttendance records an active employee's attendance for one day, replacing any
+// earlier record for that day. Days covered by approved leave cannot be marked present
+func (lt *LeaveTracker) RecordAttendance(employeeID int, date time.Time, status string, note string) error {
+    lt.mu.Lock()
+    defer lt.mu.Unlock()
+
+    emp, err := lt.manager.SearchByID(employeeID)
+    if err != nil {
+        return err
+    }
+
+    date = dateOnly(date)
+    status = strings.ToUpper(status)
+    switch status {
+    case ATTENDANCE_PRESENT, ATTENDANCE_ABSENT, ATTENDANCE_HALF_DAY, ATTENDANCE_ON_LEAVE:
+    default:
+        return &ValidationError{Field: "status", Value: status, Err: ErrInvalidAttendance}
+    }
+    if date.After(today()) || (!emp.HireDate.IsZero() && date.Before(dateOnly(emp.HireDate))) {
+        return &ValidationError{Field: "date", Value: date.Format("2006-01-02"), Err: ErrInvalidDateRange}
+    }
+    if status != ATTENDANCE_ON_LEAVE && lt.onApprovedLeave(employeeID, date) {
+        return &ValidationError{Field: "date", Value: date.Format("2006-01-02"), Err: ErrOnApprovedLeave}
+    }
+
+    if lt.attendance[employeeID] == nil {
+        lt.attendance[employeeID] = make(map[string]AttendanceRecord)
+    }
+    lt.attendance[employeeID][date.Format("2006-01-02")] = AttendanceRecord{
+        EmployeeID: employeeID,
+        Date:       date,
+        Status:     status,
+        Note:       note,
+    }
+    return nil
+}
+
+// Attendance returns an employee's attendance between two dates inclusive, in date order.
+// Working days covered by approved leave are reported as ON_LEAVE even if nobody recorded them
+func (lt *LeaveTracker) Attendance(employeeID int, from time.Time, to time.Time) []AttendanceRecord {
+    lt.mu.Lock()
+    defer lt.mu.Unlock()
+
+    from, to = dateOnly(from), dateOnly(to)
+    records := make([]AttendanceRecord, 0)
+    for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+        if record, ok := lt.attendance[employeeID][day.Format("2006-01-02")]; ok {
+            records = append(records, record)
+        } else if isWorkingDay(day) && lt.onApprovedLeave(employeeID, day) {
+            records = append(records, AttendanceRecord{EmployeeID: employeeID, Date: day, Status: ATTENDANCE_ON_LEAVE})
+        }
+    }
+    return records
+}
+
+// balance works out emp's balance of leaveType as of a date. Approved leave up to that date
+// is used; approved leave after it is booked and left in the available balance. Each month's
+// credit is capped at the policy's MaxBalance before that month's leave is taken, so Accrued
+// only counts what was credited and anything over the cap lapses. Callers must hold lt.mu
+func (lt *LeaveTracker) balance(emp Employee, leaveType string, asOf time.Time) LeaveBalance {
+    policy, _ := lt.policy(emp.Department, leaveType)
+
+    // Accrual runs from joining, or from the start of the year for policies that reset
+    start := dateOnly(emp.HireDate)
+    yearStart := time.Date(asOf.Year(), time.January, 1, 0, 0, 0, 0, time.Local)
+    if emp.HireDate.IsZero() || (policy.ResetYearly && start.Before(yearStart)) {
+        start = yearStart
+    }
+
+    balance := LeaveBalance{Type: leaveType}
+    for from := start; !from.After(asOf); from = time.Date(from.Year(), from.Month()+1, 1, 0, 0, 0, 0, time.Local) {
+        credit := policy.MonthlyDays
+        if policy.MaxBalance > 0 {
+            credit = math.Max(0, math.Min(credit, policy.MaxBalance-balance.Available))
+        }
+        balance.Accrued += credit
+        balance.Available += credit
+
+        monthEnd := time.Date(from.Year(), from.Month()+1, 0, 0, 0, 0, 0, time.Local)
+        taken := float64(lt.leaveTaken(emp.ID, leaveType, from, minDate(monthEnd, asOf)))
+        balance.Used += taken
+        balance.Available -= taken
+    }
+
+    for _, request := range lt.requests {
+        if request.EmployeeID != emp.ID || request.Type != leaveType || request.To.Before(start) {
+            continue
+        }
+        switch request.Status {
+        case LEAVE_APPROVED:
+            if request.To.After(asOf) {
+                balance.Booked += float64(workingDays(maxDate(request.From, asOf.AddDate(0, 0, 1)), request.To))
+            }
+        case LEAVE_PENDING:
+            balance.Pending += request.Days
+        }
+    }
+    return balance
+}
+
+// leaveTaken counts the working days of approved leaveType leave an employee took from one
+// date to another inclusive. Callers must hold lt.mu
+func (lt *LeaveTracker) leaveTaken(employeeID int, leaveType string, from time.Time, to time.Time) int {
+    days := 0
+    for _, request := range lt.requests {
+        if request.EmployeeID == employeeID && request.Type == leaveType && request.Status == LEAVE_APPROVED {
+            days += workingDays(maxDate(request.From, from), minDate(request.To, to))
+        }
+    }
+    return days
+}
+
+// pendingRequest returns the position of a pending request. Callers must hold lt.mu
+func (lt *LeaveTracker) pendingRequest(requestID int) (int, error) {
+    for i := range lt.requests {
+        if lt.requests[i].ID == requestID {
+            if lt.requests[i].Status != LEAVE_PENDING {
+                return -1, fmt.Errorf("leave request %d is already %s", requestID, strings.ToLower(lt.requests[i].Status))
+            }
+            return i, nil
+        }
+    }
+    return -1, fmt.Errorf("leave request %d %w", requestID, ErrNotFound)
+}
+
+// decide records the outcome of a request. Callers must hold lt.mu
+func (lt *LeaveTracker) decide(i int, status string, approver string, comment string) {
+    now := time.Now()
+    lt.requests[i].Status = status
+    lt.requests[i].DecidedBy = approver
+    lt.requests[i].DecidedAt = &now
+    lt.requests[i].Comment = comment
+}
+
+// onApprovedLeave reports whether an approved request covers date. Callers must hold lt.mu
+func (lt *LeaveTracker) onApprovedLeave(employeeID int, date time.Time) bool {
+    for _, request := range lt.requests {
+        if request.EmployeeID == employeeID && request.Status == LEAVE_APPROVED &&
+            !date.Before(request.From) && !date.After(request.To) {
+            return true
+        }
+    }
+    return false
+}
+
+// minDate returns the earlier of two dates
+func minDate(a time.Time, b time.Time) time.Time {
+    if a.Before(b) {
+        return a
+    }
+    return b
+}
+
+// maxDate returns the later of two dates
+func maxDate(a time.Time, b time.Time) time.Time {
+    if a.After(b) {
+        return a
+    }
+    return b
+}
+
+// workingDays counts the Monday-to-Friday days from one date to another inclusive
+func workingDays(from time.Time, to time.Time) int {
+    days := 0
+    for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+        if isWorkingDay(day) {
+            days++
+        }
+    }
+    return days
+}
+
+// isWorkingDay reports whether day falls on a weekday
+func isWorkingDay(day time.Time) bool {
+    return day.Weekday() != time.Saturday && day.Weekday() != time.Sunday
+}
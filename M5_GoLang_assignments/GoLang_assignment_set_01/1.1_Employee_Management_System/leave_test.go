@@ -0,0 +1,269 @@
+package main
+
+import (
+    "errors"
+    "testing"
+    "time"
+)
+
+// newLeaveTestTracker returns a tracker for a roster of one employee hired two years ago
+func newLeaveTestTracker(t *testing.T) *LeaveTracker {
+    t.Helper()
+
+    em := NewEmployeeManager()
+    err := em.AddEmployeeRecord(Employee{ID: 1, Name: "Asha", Age: 30, Department: IT_DEPT, HireDate: today().AddDate(-2, 0, 0)})
+    if err != nil {
+        t.Fatal(err)
+    }
+    return NewLeaveTracker(em, DefaultLeavePolicies())
+}
+
+// lastWorkingDay returns the most recent weekday before today
+func lastWorkingDay() time.Time {
+    day := today().AddDate(0, 0, -1)
+    for !isWorkingDay(day) {
+        day = day.AddDate(0, 0, -1)
+    }
+    return day
+}
+
+// mondayIn returns the Monday the given number of weeks after this week's
+func mondayIn(weeks int) time.Time {
+    return today().AddDate(0, 0, 7*weeks-int(today().Weekday())+1)
+}
+
+func TestLeaveRequestWorkflow(t *testing.T) {
+    lt := newLeaveTestTracker(t)
+    monday := mondayIn(2)
+
+    request, err := lt.RequestLeave(1, "earned", monday, monday.AddDate(0, 0, 1), "wedding")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if request.Status != LEAVE_PENDING || request.Type != LEAVE_EARNED || request.Days != 2 {
+        t.Errorf("new request = %+v, want 2 pending EARNED days", request)
+    }
+    if pending := lt.PendingRequests(); len(pending) != 1 || pending[0].ID != request.ID {
+        t.Errorf("PendingRequests() = %+v, want request %d", pending, request.ID)
+    }
+    if balance, _ := lt.Balance(1, LEAVE_EARNED, today()); balance.Pending != 2 || balance.Booked != 0 {
+        t.Errorf("balance with a pending request = %+v, want 2 days pending and none booked", balance)
+    }
+
+    if err := lt.RejectLeave(request.ID, "Amit", "release week"); err != nil {
+        t.Fatal(err)
+    }
+    rejected := lt.LeaveRequests(1)[0]
+    if rejected.Status != LEAVE_REJECTED || rejected.DecidedBy != "Amit" || rejected.Comment != "release week" || rejected.DecidedAt == nil {
+        t.Errorf("rejected request = %+v, want REJECTED by Amit with a comment", rejected)
+    }
+    if err := lt.ApproveLeave(request.ID, "Amit"); err == nil {
+        t.Error("approving a rejected request succeeded")
+    }
+    if err := lt.ApproveLeave(99, "Amit"); !errors.Is(err, ErrNotFound) {
+        t.Errorf("approving an unknown request returned %v, want %v", err, ErrNotFound)
+    }
+
+    // A rejected request no longer blocks the same days
+    again, err := lt.RequestLeave(1, LEAVE_EARNED, monday, monday.AddDate(0, 0, 1), "")
+    if err != nil {
+        t.Fatalf("requesting the rejected days again: %v", err)
+    }
+    if err := lt.ApproveLeave(again.ID, "Amit"); err != nil {
+        t.Fatal(err)
+    }
+    if pending := lt.PendingRequests(); len(pending) != 0 {
+        t.Errorf("PendingRequests() after approval = %+v, want none", pending)
+    }
+    if balance, _ := lt.Balance(1, LEAVE_EARNED, today()); balance.Pending != 0 || balance.Booked != 2 {
+        t.Errorf("balance with approved leave = %+v, want 2 days booked and none pending", balance)
+    }
+    if err := lt.RejectLeave(again.ID, "Amit", ""); err == nil {
+        t.Error("rejecting an approved request succeeded")
+    }
+}
+
+func TestRequestLeaveOverlap(t *testing.T) {
+    lt := newLeaveTestTracker(t)
+    monday := mondayIn(2)
+
+    pending, err := lt.RequestLeave(1, LEAVE_EARNED, monday, monday.AddDate(0, 0, 2), "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := lt.RequestLeave(1, LEAVE_SICK, monday.AddDate(0, 0, 2), monday.AddDate(0, 0, 3), ""); !errors.Is(err, ErrLeaveOverlap) {
+        t.Errorf("overlapping a pending request returned %v, want %v", err, ErrLeaveOverlap)
+    }
+
+    if err := lt.ApproveLeave(pending.ID, "Amit"); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := lt.RequestLeave(1, LEAVE_CASUAL, monday.AddDate(0, 0, -3), monday, ""); !errors.Is(err, ErrLeaveOverlap) {
+        t.Errorf("overlapping an approved request returned %v, want %v", err, ErrLeaveOverlap)
+    }
+    if _, err := lt.RequestLeave(1, LEAVE_EARNED, monday.AddDate(0, 0, 3), monday.AddDate(0, 0, 3), ""); err != nil {
+        t.Errorf("requesting the day after approved leave: %v", err)
+    }
+}
+
+func TestRequestLeaveBeyondBalance(t *testing.T) {
+    lt := newLeaveTestTracker(t)
+    monday := mondayIn(2)
+
+    // Ten working weeks is more than the 45 day earned leave cap
+    if _, err := lt.RequestLeave(1, LEAVE_EARNED, monday, monday.AddDate(0, 0, 7*9+4), ""); !errors.Is(err, ErrInsufficientLeave) {
+        t.Errorf("requesting 50 days returned %v, want %v", err, ErrInsufficientLeave)
+    }
+
+    // Two years of service earns over 25 days, but not enough for two pending requests of 25
+    if _, err := lt.RequestLeave(1, LEAVE_EARNED, monday, monday.AddDate(0, 0, 7*4+4), ""); err != nil {
+        t.Fatalf("requesting 25 days: %v", err)
+    }
+    later := mondayIn(10)
+    if _, err := lt.RequestLeave(1, LEAVE_EARNED, later, later.AddDate(0, 0, 7*4+4), ""); !errors.Is(err, ErrInsufficientLeave) {
+        t.Errorf("requesting 25 more days while 25 are pending returned %v, want %v", err, ErrInsufficientLeave)
+    }
+    if requests := lt.LeaveRequests(1); len(requests) != 1 {
+        t.Errorf("employee has %d requests, want only the one within balance", len(requests))
+    }
+}
+
+func TestLeaveRequiresActiveEmployee(t *testing.T) {
+    lt := newLeaveTestTracker(t)
+    monday := mondayIn(2)
+    day := lastWorkingDay()
+
+    request, err := lt.RequestLeave(1, LEAVE_EARNED, monday, monday, "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := lt.manager.RemoveEmployee(1, today()); err != nil {
+        t.Fatal(err)
+    }
+
+    for _, id := range []int{1, 99} {
+        if _, err := lt.RequestLeave(id, LEAVE_EARNED, monday.AddDate(0, 0, 7), monday.AddDate(0, 0, 7), ""); !errors.Is(err, ErrNotFound) {
+            t.Errorf("requesting leave for employee %d returned %v, want %v", id, err, ErrNotFound)
+        }
+        if err := lt.RecordAttendance(id, day, ATTENDANCE_PRESENT, ""); !errors.Is(err, ErrNotFound) {
+            t.Errorf("recording attendance for employee %d returned %v, want %v", id, err, ErrNotFound)
+        }
+        if _, err := lt.Balance(id, LEAVE_EARNED, today()); !errors.Is(err, ErrNotFound) {
+            t.Errorf("balance for employee %d returned %v, want %v", id, err, ErrNotFound)
+        }
+    }
+
+    // Leave filed before the employee left can no longer be granted
+    if err := lt.ApproveLeave(request.ID, "Amit"); !errors.Is(err, ErrNotFound) {
+        t.Errorf("approving leave for a terminated employee returned %v, want %v", err, ErrNotFound)
+    }
+    if got := lt.LeaveRequests(1)[0].Status; got != LEAVE_PENDING {
+        t.Errorf("request status = %s, want it left %s", got, LEAVE_PENDING)
+    }
+    if records := lt.Attendance(1, day, day); len(records) != 0 {
+        t.Errorf("attendance for a terminated employee = %+v, want none", records)
+    }
+}
+
+func TestRecordAttendanceOnApprovedLeave(t *testing.T) {
+    lt := newLeaveTestTracker(t)
+    day := lastWorkingDay()
+    request, err := lt.RequestLeave(1, LEAVE_EARNED, day, day, "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := lt.ApproveLeave(request.ID, "Amit"); err != nil {
+        t.Fatal(err)
+    }
+
+    err = lt.RecordAttendance(1, day, ATTENDANCE_PRESENT, "")
+    if !errors.Is(err, ErrOnApprovedLeave) || errors.Is(err, ErrInvalidAttendance) {
+        t.Fatalf("marking a day of approved leave present returned %v, want %v", err, ErrOnApprovedLeave)
+    }
+    if err := lt.RecordAttendance(1, day, ATTENDANCE_ON_LEAVE, ""); err != nil {
+        t.Fatalf("marking a day of approved leave as leave: %v", err)
+    }
+}
+
+func TestBalanceBooksFutureLeave(t *testing.T) {
+    lt := newLeaveTestTracker(t)
+
+    // A whole working week starting the Monday after next
+    monday := mondayIn(2)
+    request, err := lt.RequestLeave(1, LEAVE_EARNED, monday, monday.AddDate(0, 0, 4), "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := lt.ApproveLeave(request.ID, "Amit"); err != nil {
+        t.Fatal(err)
+    }
+
+    now, err := lt.Balance(1, LEAVE_EARNED, today())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if now.Used != 0 || now.Booked != 5 || now.Available != now.Accrued {
+        t.Errorf("balance before the leave = %+v, want 5 days booked and none used", now)
+    }
+
+    during, err := lt.Balance(1, LEAVE_EARNED, monday.AddDate(0, 0, 1))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if during.Used != 2 || during.Booked != 3 {
+        t.Errorf("balance on the second day of leave = %+v, want 2 days used and 3 booked", during)
+    }
+
+    // Booked leave still counts against new requests
+    free := now.Available - now.Booked
+    if _, err := lt.RequestLeave(1, LEAVE_EARNED, monday.AddDate(0, 0, 7), monday.AddDate(0, 0, 7+int(free)*2), ""); !errors.Is(err, ErrInsufficientLeave) {
+        t.Errorf("requesting more than the unbooked balance returned %v, want %v", err, ErrInsufficientLeave)
+    }
+}
+
+func TestBalanceCapsRunningBalance(t *testing.T) {
+    em := NewEmployeeManager()
+    err := em.AddEmployeeRecord(Employee{ID: 1, Name: "Asha", Age: 30, Department: IT_DEPT,
+        HireDate: time.Date(2019, time.January, 1, 0, 0, 0, 0, time.Local)})
+    if err != nil {
+        t.Fatal(err)
+    }
+    lt := NewLeaveTracker(em, DefaultLeavePolicies())
+    endOf2023 := time.Date(2023, time.December, 31, 0, 0, 0, 0, time.Local)
+
+    // Sixty months at 1.5 days would be 90, but earned leave stops at the 45 day cap
+    idle, err := lt.Balance(1, LEAVE_EARNED, endOf2023)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if idle.Accrued != 45 || idle.Available != 45 {
+        t.Errorf("balance without leave = %+v, want 45 accrued and available", idle)
+    }
+
+    // Nine working weeks from January 2022, taken while the balance sat at the cap
+    lt.requests = append(lt.requests, LeaveRequest{ID: 1, EmployeeID: 1, Type: LEAVE_EARNED,
+        From: time.Date(2022, time.January, 3, 0, 0, 0, 0, time.Local), To: time.Date(2022, time.March, 4, 0, 0, 0, 0, time.Local),
+        Days: 45, Status: LEAVE_APPROVED})
+
+    tests := []struct {
+        asOf          time.Time
+        wantAccrued   float64
+        wantUsed      float64
+        wantAvailable float64
+    }{
+        {time.Date(2021, time.December, 31, 0, 0, 0, 0, time.Local), 45, 0, 45},
+        {time.Date(2022, time.January, 31, 0, 0, 0, 0, time.Local), 45, 21, 24},
+        {time.Date(2022, time.March, 31, 0, 0, 0, 0, time.Local), 48, 45, 3},
+        {endOf2023, 79.5, 45, 34.5},
+    }
+    for _, tt := range tests {
+        balance, err := lt.Balance(1, LEAVE_EARNED, tt.asOf)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if balance.Accrued != tt.wantAccrued || balance.Used != tt.wantUsed || balance.Available != tt.wantAvailable {
+            t.Errorf("balance on %s = %+v, want %.1f accrued, %.1f used and %.1f available",
+                tt.asOf.Format("2006-01-02"), balance, tt.wantAccrued, tt.wantUsed, tt.wantAvailable)
+        }
+    }
+}
@@ -2,87 +2,387 @@ package main
 
 import (
     "errors"
+    "flag"
     "fmt"
+    "log"
+    "net/http"
+    "os"
     "strings"
+    "sync"
+    "time"
+
+    "github.com/gorilla/mux"
 )
 
-// Constants for departments
+// Departments every new system starts with
 const (
     HR_DEPT  = "HR"
     IT_DEPT  = "IT"
     FIN_DEPT = "FINANCE"
 )
 
+// Employment statuses
+const (
+    STATUS_ACTIVE     = "ACTIVE"
+    STATUS_ON_LEAVE   = "ON_LEAVE"
+    STATUS_TERMINATED = "TERMINATED"
+)
+
 // Employee struct to hold employee information
 type Employee struct {
-    ID         int
-    Name       string
-    Age        int
-    Department string
+    ID         int       `json:"id"`
+    Name       string    `json:"name"`
+    Age        int       `json:"age"`
+    Department string    `json:"department"`
+    Email      string    `json:"email,omitempty"`
+    Phone      string    `json:"phone,omitempty"`
+    HireDate   time.Time `json:"hire_date"`
+    Salary     float64   `json:"salary"`
+    Title      string    `json:"title,omitempty"`
+    Status     string    `json:"status"`
+    // ManagerID is the ID of the employee this one reports to, or 0 for the top of the organisation
+    ManagerID int `json:"manager_id,omitempty"`
+    // TerminationDate is set when the employee leaves; terminated records are kept for history
+    TerminationDate *time.Time `json:"termination_date,omitempty"`
+}
+
+// IsActive reports whether the employee is still employed; employees on leave count as active
+func (e Employee) IsActive() bool {
+    return e.TerminationDate == nil && e.Status != STATUS_TERMINATED
 }
 
-// EmployeeManager handles all employee operations
+// EmployeeManager handles all employee operations.
+// It is safe for concurrent use; lookups return copies, never pointers into its storage
 type EmployeeManager struct {
-    employees []Employee
+    mu          sync.RWMutex
+    employees   []Employee
+    index       *employeeIndex
+    departments *DepartmentRegistry
+    store       Store
+    audit       AuditLog
+    actor       string
 }
 
 // NewEmployeeManager creates a new instance of EmployeeManager
 func NewEmployeeManager() *EmployeeManager {
     return &EmployeeManager{
-        employees: make([]Employee, 0),
+        employees:   make([]Employee, 0),
+        index:       newEmployeeIndex(nil),
+        departments: NewDepartmentRegistry(DefaultDepartments()),
     }
 }
 
-// AddEmployee adds a new employee after validation
-func (em *EmployeeManager) AddEmployee(id int, name string, age int, department string) error {
-    // Validate age
-    if age < 18 {
-        return errors.New("employee must be at least 18 years old")
+// NewEmployeeManagerWithStore creates an EmployeeManager backed by store,
+// loading the employees and departments it already holds
+func NewEmployeeManagerWithStore(store Store) (*EmployeeManager, error) {
+    roster, err := store.Load()
+    if err != nil {
+        return nil, fmt.Errorf("failed to load employees: %w", err)
     }
 
-    // Validate department
-    department = strings.ToUpper(department)
-    if department != HR_DEPT && department != IT_DEPT && department != FIN_DEPT {
-        return fmt.Errorf("invalid department: %s", department)
+    em := NewEmployeeManager()
+    em.store = store
+    if roster.Employees != nil {
+        em.employees = roster.Employees
+        em.index = newEmployeeIndex(em.employees)
+    }
+    if len(roster.Departments) > 0 {
+        em.departments = NewDepartmentRegistry(roster.Departments)
     }
+    return em, nil
+}
 
-    // Check for duplicate ID
-    for _, emp := range em.employees {
-        if emp.ID == id {
-            return fmt.Errorf("employee with ID %d already exists", id)
-        }
+// Departments returns every department employees can be assigned to, including retired ones
+func (em *EmployeeManager) Departments() []Department {
+    em.mu.RLock()
+    defer em.mu.RUnlock()
+
+    return em.departments.List()
+}
+
+// save writes employees and departments to the backing store, if any
+func (em *EmployeeManager) save(employees []Employee, departments *DepartmentRegistry) error {
+    if em.store == nil {
+        return nil
+    }
+
+    roster := Roster{
+        Departments: departments.List(),
+        Employees:   employees,
+    }
+    if err := em.store.Save(roster); err != nil {
+        return fmt.Errorf("%w: %w", ErrStorage, err)
     }
+    return nil
+}
 
-    // Create and add new employee
-    newEmployee := Employee{
+// AddEmployee adds a new employee after validation, hired today
+func (em *EmployeeManager) AddEmployee(id int, name string, age int, department string) error {
+    return em.AddEmployeeRecord(Employee{
         ID:         id,
         Name:       name,
         Age:        age,
         Department: department,
+    })
+}
+
+// AddEmployeeRecord adds a fully populated employee after validation.
+// A zero HireDate defaults to today and an empty Status to ACTIVE
+func (em *EmployeeManager) AddEmployeeRecord(newEmployee Employee) error {
+    em.mu.Lock()
+    defer em.mu.Unlock()
+
+    if err := em.prepareNewEmployee(&newEmployee); err != nil {
+        return err
     }
 
-    em.employees = append(em.employees, newEmployee)
+    // Persist before updating memory so a failed write leaves both unchanged
+    employees := append(em.employees, newEmployee)
+    err := em.audited(AUDIT_CREATE, nil, &newEmployee, func() error {
+        return em.save(employees, em.departments)
+    })
+    if err != nil {
+        return err
+    }
+
+    em.employees = employees
+    em.index.add(len(employees)-1, newEmployee)
+    return nil
+}
+
+// prepareNewEmployee fills in the defaults of a new hire and validates it. Callers must hold em.mu
+func (em *EmployeeManager) prepareNewEmployee(newEmployee *Employee) error {
+    // Check for duplicate ID, including terminated employees so IDs are never reused
+    if em.indexOf(newEmployee.ID) >= 0 {
+        return fmt.Errorf("employee with ID %d %w", newEmployee.ID, ErrDuplicateID)
+    }
+
+    // New hires start without a manager or termination; those have their own operations
+    newEmployee.ManagerID = 0
+    newEmployee.TerminationDate = nil
+    if newEmployee.HireDate.IsZero() {
+        newEmployee.HireDate = today()
+    }
+    if err := em.validateRecord(newEmployee); err != nil {
+        return err
+    }
+    if newEmployee.Status == STATUS_TERMINATED {
+        return &ValidationError{Field: "status", Value: newEmployee.Status, Err: ErrInvalidStatus}
+    }
     return nil
 }
 
-// SearchByID searches for an employee by their ID
+// UpdateEmployee corrects the name, age and department of an active employee
+func (em *EmployeeManager) UpdateEmployee(id int, name string, age int, department string) error {
+    em.mu.Lock()
+    defer em.mu.Unlock()
+
+    return em.update(id, func(emp *Employee) error {
+        emp.Name = name
+        emp.Age = age
+        emp.Department = department
+        return nil
+    })
+}
+
+// UpdateEmployeeRecord replaces the personal and job details of the active employee with
+// record's ID. Reporting line, status and termination are left to their own operations
+func (em *EmployeeManager) UpdateEmployeeRecord(record Employee) error {
+    em.mu.Lock()
+    defer em.mu.Unlock()
+
+    return em.update(record.ID, func(emp *Employee) error {
+        emp.Name = record.Name
+        emp.Age = record.Age
+        emp.Department = record.Department
+        emp.Email = record.Email
+        emp.Phone = record.Phone
+        emp.Salary = record.Salary
+        emp.Title = record.Title
+        if !record.HireDate.IsZero() {
+            emp.HireDate = record.HireDate
+        }
+        return nil
+    })
+}
+
+// SetStatus marks an active employee as ACTIVE or ON_LEAVE; use RemoveEmployee to terminate
+func (em *EmployeeManager) SetStatus(id int, status string) error {
+    em.mu.Lock()
+    defer em.mu.Unlock()
+
+    status = strings.ToUpper(status)
+    if status == STATUS_TERMINATED {
+        return &ValidationError{Field: "status", Value: status, Err: ErrInvalidStatus}
+    }
+
+    return em.update(id, func(emp *Employee) error {
+        emp.Status = status
+        return nil
+    })
+}
+
+// TransferDepartment moves an active employee to another department
+func (em *EmployeeManager) TransferDepartment(id int, department string) error {
+    em.mu.Lock()
+    defer em.mu.Unlock()
+
+    department = normalizeDepartment(department)
+    return em.update(id, func(emp *Employee) error {
+        if emp.Department == department {
+            return fmt.Errorf("employee with ID %d is already in department %s", id, department)
+        }
+        emp.Department = department
+        return nil
+    })
+}
+
+// RemoveEmployee terminates an active employee as of terminationDate.
+// The record is kept so it can still be found with the IncludingTerminated lookups.
+// A manager's direct reports must be moved to someone else with SetManager first
+func (em *EmployeeManager) RemoveEmployee(id int, terminationDate time.Time) error {
+    em.mu.Lock()
+    defer em.mu.Unlock()
+
+    return em.update(id, func(emp *Employee) error {
+        if reports := em.directReports(id); len(reports) > 0 {
+            return fmt.Errorf("employee %d %w: %d still report to them", id, ErrHasReports, len(reports))
+        }
+        if terminationDate.Before(emp.HireDate) {
+            return &ValidationError{Field: "termination_date", Value: terminationDate.Format("2006-01-02"), Err: ErrTerminationBeforeHire}
+        }
+        emp.TerminationDate = &terminationDate
+        emp.Status = STATUS_TERMINATED
+        return nil
+    })
+}
+
+// today returns the current date at midnight local time
+func today() time.Time {
+    return dateOnly(time.Now())
+}
+
+// indexOf returns the slice position of the employee with the given ID, or -1
+func (em *EmployeeManager) indexOf(id int) int {
+    return em.index.position(id)
+}
+
+// update applies change to a copy of an active employee's record, validates it and
+// persists the result before replacing the in-memory record. Callers must hold em.mu
+func (em *EmployeeManager) update(id int, change func(emp *Employee) error) error {
+    i := em.indexOf(id)
+    if i < 0 || !em.employees[i].IsActive() {
+        return fmt.Errorf("employee with ID %d %w", id, ErrNotFound)
+    }
+
+    updated := em.employees[i]
+    if err := change(&updated); err != nil {
+        return err
+    }
+    if err := em.validateRecord(&updated); err != nil {
+        return err
+    }
+
+    action := AUDIT_UPDATE
+    if updated.TerminationDate != nil && em.employees[i].TerminationDate == nil {
+        action = AUDIT_TERMINATE
+    }
+    err := em.audited(action, &em.employees[i], &updated, func() error {
+        if em.store == nil {
+            return nil
+        }
+        employees := make([]Employee, len(em.employees))
+        copy(employees, em.employees)
+        employees[i] = updated
+        return em.save(employees, em.departments)
+    })
+    if err != nil {
+        return err
+    }
+
+    em.index.remove(i, em.employees[i])
+    em.employees[i] = updated
+    em.index.add(i, updated)
+    return nil
+}
+
+// audited records a change event and then runs persist. If persist fails, a ROLLBACK
+// event reverses the change so the audit log still matches the roster. Callers must hold em.mu
+func (em *EmployeeManager) audited(action string, before *Employee, after *Employee, persist func() error) error {
+    if err := em.recordChange(action, before, after); err != nil {
+        return err
+    }
+    if err := persist(); err != nil {
+        if rollbackErr := em.recordChange(AUDIT_ROLLBACK, after, before); rollbackErr != nil {
+            return errors.Join(err, rollbackErr)
+        }
+        return err
+    }
+    return nil
+}
+
+// SearchByID searches for an active employee by their ID
 func (em *EmployeeManager) SearchByID(id int) (*Employee, error) {
+    em.mu.RLock()
+    defer em.mu.RUnlock()
+
+    return em.searchByID(id, false)
+}
+
+// SearchByIDIncludingTerminated searches for an employee by their ID, including those who have left
+func (em *EmployeeManager) SearchByIDIncludingTerminated(id int) (*Employee, error) {
+    em.mu.RLock()
+    defer em.mu.RUnlock()
+
+    return em.searchByID(id, true)
+}
+
+func (em *EmployeeManager) searchByID(id int, includeTerminated bool) (*Employee, error) {
+    i := em.indexOf(id)
+    if i < 0 || (!includeTerminated && !em.employees[i].IsActive()) {
+        return nil, fmt.Errorf("employee with ID %d %w", id, ErrNotFound)
+    }
+
+    emp := em.employees[i]
+    return &emp, nil
+}
+
+// ListEmployees returns every active employee, or every employee when includeTerminated is set
+func (em *EmployeeManager) ListEmployees(includeTerminated bool) []*Employee {
+    em.mu.RLock()
+    defer em.mu.RUnlock()
+
+    employees := make([]*Employee, 0, len(em.employees))
     for i := range em.employees {
-        if em.employees[i].ID == id {
-            return &em.employees[i], nil
+        if includeTerminated || em.employees[i].IsActive() {
+            emp := em.employees[i]
+            employees = append(employees, &emp)
         }
     }
-    return nil, fmt.Errorf("employee with ID %d not found", id)
+    return employees
 }
 
-// SearchByName searches for an employee by their name
+// SearchByName searches for an active employee by their name
 func (em *EmployeeManager) SearchByName(name string) ([]*Employee, error) {
+    em.mu.RLock()
+    defer em.mu.RUnlock()
+
     var found []*Employee
     name = strings.ToLower(name)
-    
-    for i := range em.employees {
-        if strings.Contains(strings.ToLower(em.employees[i].Name), name) {
-            found = append(found, &em.employees[i])
+
+    // Narrow the search with the trigram index when the query is long enough
+    candidates, ok := em.index.nameCandidates(name)
+    if !ok {
+        candidates = make([]int, len(em.employees))
+        for i := range candidates {
+            candidates[i] = i
+        }
+    }
+
+    for _, i := range candidates {
+        if em.employees[i].IsActive() && strings.Contains(strings.ToLower(em.employees[i].Name), name) {
+            emp := em.employees[i]
+            found = append(found, &emp)
         }
     }
 
@@ -92,45 +392,158 @@ func (em *EmployeeManager) SearchByName(name string) ([]*Employee, error) {
     return found, nil
 }
 
-// ListByDepartment returns all employees in a given department
+// ListByDepartment returns all active employees in a given department
 func (em *EmployeeManager) ListByDepartment(department string) ([]*Employee, error) {
-    department = strings.ToUpper(department)
+    em.mu.RLock()
+    defer em.mu.RUnlock()
+
+    return em.listByDepartments(department, []string{normalizeDepartment(department)}, false)
+}
+
+// ListByDepartmentIncludingTerminated returns all employees in a given department, including those who have left
+func (em *EmployeeManager) ListByDepartmentIncludingTerminated(department string) ([]*Employee, error) {
+    em.mu.RLock()
+    defer em.mu.RUnlock()
+
+    return em.listByDepartments(department, []string{normalizeDepartment(department)}, true)
+}
+
+// ListByDepartmentTree returns all active employees in a department and its sub-departments
+func (em *EmployeeManager) ListByDepartmentTree(department string) ([]*Employee, error) {
+    em.mu.RLock()
+    defer em.mu.RUnlock()
+
+    return em.listByDepartments(department, em.departments.Tree(department), false)
+}
+
+func (em *EmployeeManager) listByDepartments(department string, departments []string, includeTerminated bool) ([]*Employee, error) {
     var deptEmployees []*Employee
 
-    for i := range em.employees {
-        if em.employees[i].Department == department {
-            deptEmployees = append(deptEmployees, &em.employees[i])
+    for _, i := range em.index.departmentPositions(departments) {
+        if includeTerminated || em.employees[i].IsActive() {
+            emp := em.employees[i]
+            deptEmployees = append(deptEmployees, &emp)
         }
     }
 
     if len(deptEmployees) == 0 {
-        return nil, fmt.Errorf("no employees found in department %s", department)
+        return nil, fmt.Errorf("no employees found in department %s", normalizeDepartment(department))
     }
     return deptEmployees, nil
 }
 
-// CountByDepartment returns the number of employees in a department
+// CountByDepartment returns the number of active employees in a department
 func (em *EmployeeManager) CountByDepartment(department string) int {
-    department = strings.ToUpper(department)
-    count := 0
-    
-    for _, emp := range em.employees {
-        if emp.Department == department {
-            count++
-        }
-    }
-    return count
+    em.mu.RLock()
+    defer em.mu.RUnlock()
+
+    return em.countByDepartments([]string{normalizeDepartment(department)})
+}
+
+// CountByDepartmentTree returns the number of active employees in a department and its sub-departments
+func (em *EmployeeManager) CountByDepartmentTree(department string) int {
+    em.mu.RLock()
+    defer em.mu.RUnlock()
+
+    return em.countByDepartments(em.departments.Tree(department))
+}
+
+func (em *EmployeeManager) countByDepartments(departments []string) int {
+    return em.index.activeCount(departments)
+}
+
+// startServer serves the employee directory API on addr
+func startServer(manager *EmployeeManager, addr string) error {
+    router := mux.NewRouter()
+    NewEmployeeHandler(manager).RegisterRoutes(router)
+
+    // Apply middleware
+    router.Use(loggingMiddleware)
+
+    log.Printf("Server is running on http://localhost%s", addr)
+    return http.ListenAndServe(addr, router)
 }
 
 func main() {
+    storeKind := flag.String("store", "", "storage backend: json or sqlite (default: json for commands, in-memory otherwise)")
+    storePath := flag.String("path", "", "path to the storage file (default: employees.json or employees.db)")
+    serveAddr := flag.String("serve", "", "serve the REST API on this address (e.g. :8080) instead of running the demo")
+    auditPath := flag.String("audit", "", "record every change in this audit log file (default: employees.audit.log for commands, in-memory otherwise)")
+    actor := flag.String("actor", os.Getenv("USER"), "name recorded as the actor on audit events")
+    format := flag.String("format", FORMAT_TABLE, "output format for commands: table, json or csv")
+    flag.Usage = func() {
+        fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [command [args]]\n\nFlags:\n", os.Args[0])
+        flag.PrintDefaults()
+        fmt.Fprintln(flag.CommandLine.Output())
+        NewCLI(nil, flag.CommandLine.Output(), *format).help()
+        fmt.Fprintln(flag.CommandLine.Output(), "\nWith no command and no -serve, a demonstration script runs.")
+    }
+    flag.Parse()
+
+    // Commands change the roster for later runs, so they are saved and audited on disk
+    // unless told otherwise; the demo and the API server keep everything in memory
+    if flag.NArg() > 0 {
+        if *storeKind == "" {
+            *storeKind = JSON_STORE
+        }
+        if *auditPath == "" {
+            *auditPath = "employees.audit.log"
+        }
+    }
+
     // Create new employee manager
     manager := NewEmployeeManager()
+    if *storeKind != "" {
+        store, err := OpenStore(*storeKind, *storePath)
+        if err != nil {
+            fmt.Printf("Error opening store: %v\n", err)
+            os.Exit(1)
+        }
+        defer store.Close()
+
+        manager, err = NewEmployeeManagerWithStore(store)
+        if err != nil {
+            fmt.Printf("Error loading employees: %v\n", err)
+            os.Exit(1)
+        }
+    }
+
+    var auditLog AuditLog = NewMemoryAuditLog()
+    if *auditPath != "" {
+        fileLog, err := OpenFileAuditLog(*auditPath)
+        if err != nil {
+            fmt.Printf("Error opening audit log: %v\n", err)
+            os.Exit(1)
+        }
+        defer fileLog.Close()
+        auditLog = fileLog
+    }
+    manager.SetActor(*actor)
+    if err := manager.SetAuditLog(auditLog); err != nil {
+        fmt.Printf("Error starting audit log: %v\n", err)
+        os.Exit(1)
+    }
+
+    if flag.NArg() > 0 {
+        if err := NewCLI(manager, os.Stdout, *format).Run(flag.Args()); err != nil {
+            fmt.Printf("Error: %v\n", err)
+            os.Exit(1)
+        }
+        return
+    }
+
+    if *serveAddr != "" {
+        if err := startServer(manager, *serveAddr); err != nil {
+            log.Fatal(err)
+        }
+        return
+    }
 
     // Example 
     fmt.Println("Adding employees...")
     
     // Add some employees
-    errors := []error{
+    addErrors := []error{
         manager.AddEmployee(1, "Rajesh hadpe", 30, "IT"),
         manager.AddEmployee(2, "Vishwa Ghuge", 25, "HR"),
         manager.AddEmployee(3, "Amar bodke", 35, "IT"),
@@ -138,15 +551,39 @@ func main() {
     }
 
     // Check for errors during addition
-    for _, err := range errors {
+    for _, err := range addErrors {
         if err != nil {
             fmt.Printf("Error adding employee: %v\n", err)
         }
     }
 
-    // Try to add an employee with duplicate ID
-    err := manager.AddEmployee(1, "Test User", 20, "IT")
+    // Add an employee with full details
+    err := manager.AddEmployeeRecord(Employee{
+        ID:         5,
+        Name:       "Sneha Patil",
+        Age:        29,
+        Department: "HR",
+        Email:      "sneha.patil@example.com",
+        Phone:      "+91 98765 43210",
+        HireDate:   time.Date(2022, time.June, 1, 0, 0, 0, 0, time.Local),
+        Salary:     65000,
+        Title:      "HR Executive",
+    })
     if err != nil {
+        fmt.Printf("Error adding employee: %v\n", err)
+    }
+    hiringDone := time.Now()
+
+    // Reusing an email address is rejected with a typed error
+    err = manager.AddEmployeeRecord(Employee{ID: 6, Name: "Test User", Age: 30, Department: "IT", Email: "Sneha.Patil@example.com"})
+    var validationErr *ValidationError
+    if errors.As(err, &validationErr) {
+        fmt.Printf("Expected error on field %s: %v\n", validationErr.Field, err)
+    }
+
+    // Try to add an employee with duplicate ID
+    err = manager.AddEmployee(1, "Test User", 20, "IT")
+    if errors.Is(err, ErrDuplicateID) {
         fmt.Printf("Expected error: %v\n", err)
     }
 
@@ -169,6 +606,26 @@ func main() {
         }
     }
 
+    // Combine search criteria: IT or HR staff aged 25-40, youngest first
+    result, err := manager.Query().
+        InDepartments(IT_DEPT, HR_DEPT).
+        AgeBetween(25, 40).
+        SortBy(SORT_BY_AGE, false).
+        Limit(10).
+        Run()
+    if err != nil {
+        fmt.Printf("Query error: %v\n", err)
+    } else {
+        fmt.Printf("\nQuery matched %d employees:\n", result.Total)
+        for _, emp := range result.Employees {
+            fmt.Printf("%d %s (%d, %s)\n", emp.ID, emp.Name, emp.Age, emp.Department)
+        }
+    }
+
+    // Fuzzy name search tolerates typos
+    result, _ = manager.Query().NameFuzzy("rajsh", 1).Run()
+    fmt.Printf("Fuzzy match for 'rajsh': %d result(s)\n", result.Total)
+
     // List IT department employees
     itEmployees, err := manager.ListByDepartment("IT")
     if err != nil {
@@ -180,9 +637,90 @@ func main() {
         }
     }
 
+    // Set up a sub-department and move someone into it
+    if err := manager.CreateDepartment("SUPPORT", IT_DEPT); err != nil {
+        fmt.Printf("Department error: %v\n", err)
+    }
+    if err := manager.TransferDepartment(1, "SUPPORT"); err != nil {
+        fmt.Printf("Transfer error: %v\n", err)
+    }
+
+    // Update, transfer and remove employees
+    if err := manager.UpdateEmployee(3, "Amar Bodke", 36, "IT"); err != nil {
+        fmt.Printf("Update error: %v\n", err)
+    }
+    if err := manager.TransferDepartment(2, "FINANCE"); err != nil {
+        fmt.Printf("Transfer error: %v\n", err)
+    }
+    if err := manager.RemoveEmployee(4, time.Now()); err != nil {
+        fmt.Printf("Remove error: %v\n", err)
+    }
+    if _, err := manager.SearchByID(4); err != nil {
+        fmt.Printf("Expected error after removal: %v\n", err)
+    }
+
+    // Build reporting lines and print the org chart
+    if err := manager.SetManager(3, 1); err != nil {
+        fmt.Printf("Reporting line error: %v\n", err)
+    }
+    if err := manager.SetManager(2, 3); err != nil {
+        fmt.Printf("Reporting line error: %v\n", err)
+    }
+    if err := manager.SetManager(1, 2); err != nil {
+        fmt.Printf("Expected error: %v\n", err)
+    }
+    fmt.Println("\nOrg chart:")
+    if err := manager.RenderOrgChartText(os.Stdout); err != nil {
+        fmt.Printf("Org chart error: %v\n", err)
+    }
+
+    // File, approve and reject leave, then record attendance
+    leave := NewLeaveTracker(manager, DefaultLeavePolicies())
+    monday := today().AddDate(0, 0, -int(today().Weekday())+1)
+    if request, err := leave.RequestLeave(5, LEAVE_CASUAL, monday, monday, "Family function"); err != nil {
+        fmt.Printf("Leave request error: %v\n", err)
+    } else if err := leave.ApproveLeave(request.ID, "Amit"); err != nil {
+        fmt.Printf("Leave approval error: %v\n", err)
+    }
+    if request, err := leave.RequestLeave(5, LEAVE_EARNED, monday.AddDate(0, 0, 7), monday.AddDate(0, 0, 97), "Long trip"); err != nil {
+        fmt.Printf("Expected error: %v\n", err)
+    } else if err := leave.RejectLeave(request.ID, "HR", "Too long"); err != nil {
+        fmt.Printf("Leave rejection error: %v\n", err)
+    }
+    if err := leave.RecordAttendance(5, monday, ATTENDANCE_PRESENT, ""); err != nil {
+        fmt.Printf("Expected error: %v\n", err)
+    }
+    if balance, err := leave.Balance(5, LEAVE_CASUAL, today()); err == nil {
+        fmt.Printf("Casual leave for ID 5: accrued %.1f, used %.1f, booked %.1f, available %.1f\n",
+            balance.Accrued, balance.Used, balance.Booked, balance.Available)
+    }
+
+    // Show the audit trail and rebuild the roster as it stood before anyone was updated
+    if history, err := manager.EmployeeHistory(4); err == nil {
+        fmt.Println("\nHistory of ID 4:")
+        for _, event := range history {
+            fmt.Printf("  #%d %s by %s, %d fields changed\n", event.Seq, event.Action, event.Actor, len(event.Changes))
+        }
+    }
+    if roster, err := manager.RosterAsOf(hiringDone); err == nil {
+        fmt.Println("Roster after hiring:")
+        for _, emp := range roster {
+            fmt.Printf("  %d %s (%s, %s)\n", emp.ID, emp.Name, emp.Department, emp.Status)
+        }
+    }
+
+    // Department analytics for the last 12 months
+    fmt.Println()
+    if from, to, err := analyticsPeriod("", ""); err == nil {
+        if report, err := manager.DepartmentAnalytics(from, to); err == nil {
+            report.WriteText(os.Stdout)
+        }
+    }
+
     // Count employees by department
     fmt.Printf("\nEmployee counts by department:\n")
     fmt.Printf("IT: %d\n", manager.CountByDepartment(IT_DEPT))
     fmt.Printf("HR: %d\n", manager.CountByDepartment(HR_DEPT))
     fmt.Printf("Finance: %d\n", manager.CountByDepartment(FIN_DEPT))
+    fmt.Printf("IT including sub-departments: %d\n", manager.CountByDepartmentTree(IT_DEPT))
 }
\ No newline at end of file
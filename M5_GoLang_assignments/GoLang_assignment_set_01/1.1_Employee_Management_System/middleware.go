@@ -0,0 +1,14 @@
+package main
+
+import (
+    "log"
+    "net/http"
+)
+
+// loggingMiddleware logs the method and path of every request
+func loggingMiddleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        log.Printf("%s %s", r.Method, r.URL.Path)
+        next.ServeHTTP(w, r)
+    })
+}
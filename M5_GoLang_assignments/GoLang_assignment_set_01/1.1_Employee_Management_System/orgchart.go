@@ -0,0 +1,248 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "sort"
+    "strings"
+)
+
+// SpanOfControl summarises how many direct reports each manager has
+type SpanOfControl struct {
+    Managers     int         `json:"managers"`
+    TotalReports int         `json:"total_reports"`
+    MinSpan      int         `json:"min_span"`
+    MaxSpan      int         `json:"max_span"`
+    AverageSpan  float64     `json:"average_span"`
+    MedianSpan   float64     `json:"median_span"`
+    Spans        map[int]int `json:"spans"`
+}
+
+// SetManager makes the employee with the given ID report to managerID.
+// A managerID of 0 removes the reporting line. Lines that would loop back
+// to the employee are rejected with ErrReportingCycle
+func (em *EmployeeManager) SetManager(id int, managerID int) error {
+    em.mu.Lock()
+    defer em.mu.Unlock()
+
+    if managerID != 0 {
+        if managerID == id {
+            return fmt.Errorf("employee %d cannot report to themselves: %w", id, ErrReportingCycle)
+        }
+        if _, err := em.searchByID(managerID, false); err != nil {
+            return fmt.Errorf("invalid manager: %w", err)
+        }
+
+        // Walk up from the new manager; reaching id means id would manage itself
+        for current, seen := managerID, map[int]bool{}; current != 0 && !seen[current]; {
+            if current == id {
+                return fmt.Errorf("employee %d cannot report to %d: %w", id, managerID, ErrReportingCycle)
+            }
+            seen[current] = true
+
+            pos := em.indexOf(current)
+            if pos < 0 {
+                break
+            }
+            current = em.employees[pos].ManagerID
+        }
+    }
+
+    return em.update(id, func(emp *Employee) error {
+        emp.ManagerID = managerID
+        return nil
+    })
+}
+
+// DirectReports returns the active employees who report directly to id
+func (em *EmployeeManager) DirectReports(id int) ([]*Employee, error) {
+    em.mu.RLock()
+    defer em.mu.RUnlock()
+
+    if _, err := em.searchByID(id, false); err != nil {
+        return nil, err
+    }
+    return em.directReports(id), nil
+}
+
+// Subtree returns every active employee below id in the reporting hierarchy, level by level
+func (em *EmployeeManager) Subtree(id int) ([]*Employee, error) {
+    em.mu.RLock()
+    defer em.mu.RUnlock()
+
+    if _, err := em.searchByID(id, false); err != nil {
+        return nil, err
+    }
+
+    subtree := make([]*Employee, 0)
+    seen := map[int]bool{id: true}
+    queue := []int{id}
+    for len(queue) > 0 {
+        for _, report := range em.directReports(queue[0]) {
+            if !seen[report.ID] {
+                seen[report.ID] = true
+                subtree = append(subtree, report)
+                queue = append(queue, report.ID)
+            }
+        }
+        queue = queue[1:]
+    }
+    return subtree, nil
+}
+
+// ChainOfCommand returns id's manager, their manager and so on up to the top of the organisation.
+// The chain stops at the first manager who is no longer active
+func (em *EmployeeManager) ChainOfCommand(id int) ([]*Employee, error) {
+    em.mu.RLock()
+    defer em.mu.RUnlock()
+
+    emp, err := em.searchByID(id, false)
+    if err != nil {
+        return nil, err
+    }
+
+    chain := make([]*Employee, 0)
+    seen := map[int]bool{id: true}
+    for current := emp.ManagerID; current != 0 && !seen[current]; {
+        manager, err := em.searchByID(current, false)
+        if err != nil {
+            break
+        }
+        seen[current] = true
+        chain = append(chain, manager)
+        current = manager.ManagerID
+    }
+    return chain, nil
+}
+
+// SpanOfControlStats counts the direct reports of every active employee who has at least one
+func (em *EmployeeManager) SpanOfControlStats() SpanOfControl {
+    em.mu.RLock()
+    defer em.mu.RUnlock()
+
+    stats := SpanOfControl{Spans: make(map[int]int)}
+    for _, emp := range em.employees {
+        if !emp.IsActive() {
+            continue
+        }
+        if span := len(em.directReports(emp.ID)); span > 0 {
+            stats.Spans[emp.ID] = span
+        }
+    }
+
+    if len(stats.Spans) == 0 {
+        return stats
+    }
+
+    spans := make([]int, 0, len(stats.Spans))
+    for _, span := range stats.Spans {
+        spans = append(spans, span)
+        stats.TotalReports += span
+    }
+    sort.Ints(spans)
+
+    stats.Managers = len(spans)
+    stats.MinSpan = spans[0]
+    stats.MaxSpan = spans[len(spans)-1]
+    stats.AverageSpan = float64(stats.TotalReports) / float64(stats.Managers)
+    if mid := len(spans) / 2; len(spans)%2 == 0 {
+        stats.MedianSpan = float64(spans[mid-1]+spans[mid]) / 2
+    } else {
+        stats.MedianSpan = float64(spans[mid])
+    }
+    return stats
+}
+
+// RenderOrgChartText writes the reporting hierarchy to w as an indented text tree
+func (em *EmployeeManager) RenderOrgChartText(w io.Writer) error {
+    em.mu.RLock()
+    defer em.mu.RUnlock()
+
+    var sb strings.Builder
+    seen := make(map[int]bool)
+
+    var walk func(emp *Employee, prefix string, last bool, root bool)
+    walk = func(emp *Employee, prefix string, last bool, root bool) {
+        seen[emp.ID] = true
+
+        childPrefix := ""
+        if !root {
+            branch := "├── "
+            childPrefix = prefix + "│   "
+            if last {
+                branch = "└── "
+                childPrefix = prefix + "    "
+            }
+            sb.WriteString(prefix + branch)
+        }
+        fmt.Fprintf(&sb, "%s (ID: %d, %s)\n", emp.Name, emp.ID, emp.Department)
+
+        reports := em.directReports(emp.ID)
+        for i, report := range reports {
+            if !seen[report.ID] {
+                walk(report, childPrefix, i == len(reports)-1, false)
+            }
+        }
+    }
+
+    for _, root := range em.orgChartRoots() {
+        walk(root, "", true, true)
+    }
+
+    _, err := io.WriteString(w, sb.String())
+    return err
+}
+
+// RenderOrgChartDOT writes the reporting hierarchy to w in Graphviz DOT format
+func (em *EmployeeManager) RenderOrgChartDOT(w io.Writer) error {
+    em.mu.RLock()
+    defer em.mu.RUnlock()
+
+    var sb strings.Builder
+    sb.WriteString("digraph OrgChart {\n")
+    sb.WriteString("    node [shape=box];\n")
+
+    for _, emp := range em.employees {
+        if emp.IsActive() {
+            fmt.Fprintf(&sb, "    e%d [label=%q];\n", emp.ID, fmt.Sprintf("%s\n%s", emp.Name, emp.Department))
+        }
+    }
+    for _, emp := range em.employees {
+        if emp.IsActive() && emp.ManagerID != 0 {
+            if manager, err := em.searchByID(emp.ManagerID, false); err == nil {
+                fmt.Fprintf(&sb, "    e%d -> e%d;\n", manager.ID, emp.ID)
+            }
+        }
+    }
+
+    sb.WriteString("}\n")
+    _, err := io.WriteString(w, sb.String())
+    return err
+}
+
+// directReports returns copies of the active employees reporting to id. Callers must hold em.mu
+func (em *EmployeeManager) directReports(id int) []*Employee {
+    reports := make([]*Employee, 0)
+    for _, i := range em.index.reportPositions(id) {
+        if em.employees[i].IsActive() {
+            emp := em.employees[i]
+            reports = append(reports, &emp)
+        }
+    }
+    return reports
+}
+
+// orgChartRoots returns the active employees with no active manager. Callers must hold em.mu
+func (em *EmployeeManager) orgChartRoots() []*Employee {
+    roots := make([]*Employee, 0)
+    for _, emp := range em.employees {
+        if !emp.IsActive() {
+            continue
+        }
+        if _, err := em.searchByID(emp.ManagerID, false); emp.ManagerID == 0 || err != nil {
+            root := emp
+            roots = append(roots, &root)
+        }
+    }
+    return roots
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+    "testing"
+)
+
+// newOrgChartTestManager returns a roster where 2 and 3 report to 1, 4 reports to 3 and 5 reports to nobody
+func newOrgChartTestManager(t *testing.T) *EmployeeManager {
+    t.Helper()
+    em := NewEmployeeManager()
+    names := []string{"Asha Rao", "Ravi Nair", "Meera Iyer", "Kiran Das", "Sneha Patil"}
+    for i, name := range names {
+        if err := em.AddEmployee(i+1, name, 30, IT_DEPT); err != nil {
+            t.Fatal(err)
+        }
+    }
+    for _, line := range [][2]int{{2, 1}, {3, 1}, {4, 3}} {
+        if err := em.SetManager(line[0], line[1]); err != nil {
+            t.Fatal(err)
+        }
+    }
+    return em
+}
+
+// employeeIDs lists the IDs of employees in order
+func employeeIDs(employees []*Employee) string {
+    ids := make([]int, 0, len(employees))
+    for _, emp := range employees {
+        ids = append(ids, emp.ID)
+    }
+    return fmt.Sprint(ids)
+}
+
+func TestSetManagerRejectsCycles(t *testing.T) {
+    tests := []struct {
+        name      string
+        id        int
+        managerID int
+        wantIs    error
+    }{
+        {"self", 1, 1, ErrReportingCycle},
+        {"direct report", 1, 3, ErrReportingCycle},
+        {"indirect report", 1, 4, ErrReportingCycle},
+        {"unknown manager", 5, 99, ErrNotFound},
+        {"sideways move", 4, 2, nil},
+        {"clear manager", 4, 0, nil},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            em := newOrgChartTestManager(t)
+            before, _ := em.SearchByID(tt.id)
+
+            err := em.SetManager(tt.id, tt.managerID)
+            if tt.wantIs == nil {
+                if err != nil {
+                    t.Fatalf("SetManager(%d, %d) = %v, want nil", tt.id, tt.managerID, err)
+                }
+                return
+            }
+            if !errors.Is(err, tt.wantIs) {
+                t.Fatalf("SetManager(%d, %d) = %v, want %v", tt.id, tt.managerID, err, tt.wantIs)
+            }
+            if after, _ := em.SearchByID(tt.id); after.ManagerID != before.ManagerID {
+                t.Errorf("rejected change moved employee %d from manager %d to %d", tt.id, before.ManagerID, after.ManagerID)
+            }
+        })
+    }
+}
+
+func TestReportingQueries(t *testing.T) {
+    em := newOrgChartTestManager(t)
+
+    direct, err := em.DirectReports(1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    subtree, err := em.Subtree(1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    chain, err := em.ChainOfCommand(4)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if got := employeeIDs(direct); got != "[2 3]" {
+        t.Errorf("DirectReports(1) = %s, want [2 3]", got)
+    }
+    if got := employeeIDs(subtree); got != "[2 3 4]" {
+        t.Errorf("Subtree(1) = %s, want [2 3 4]", got)
+    }
+    if got := employeeIDs(chain); got != "[3 1]" {
+        t.Errorf("ChainOfCommand(4) = %s, want [3 1]", got)
+    }
+
+    stats := em.SpanOfControlStats()
+    if stats.Managers != 2 || stats.TotalReports != 3 || stats.MinSpan != 1 || stats.MaxSpan != 2 || stats.MedianSpan != 1.5 {
+        t.Errorf("SpanOfControlStats() = %+v, want 2 managers, 3 reports, spans 1 to 2 and median 1.5", stats)
+    }
+}
+
+func TestRenderOrgChartText(t *testing.T) {
+    em := newOrgChartTestManager(t)
+
+    var sb strings.Builder
+    if err := em.RenderOrgChartText(&sb); err != nil {
+        t.Fatal(err)
+    }
+
+    want := "Asha Rao (ID: 1, IT)\n" +
+        "├── Ravi Nair (ID: 2, IT)\n" +
+        "└── Meera Iyer (ID: 3, IT)\n" +
+        "    └── Kiran Das (ID: 4, IT)\n" +
+        "Sneha Patil (ID: 5, IT)\n"
+    if sb.String() != want {
+        t.Errorf("RenderOrgChartText wrote\n%s\nwant\n%s", sb.String(), want)
+    }
+}
+
+func TestRenderOrgChartDOT(t *testing.T) {
+    em := newOrgChartTestManager(t)
+
+    var sb strings.Builder
+    if err := em.RenderOrgChartDOT(&sb); err != nil {
+        t.Fatal(err)
+    }
+
+    dot := sb.String()
+    if !strings.HasPrefix(dot, "digraph OrgChart {\n") || !strings.HasSuffix(dot, "}\n") {
+        t.Errorf("DOT output is not a digraph:\n%s", dot)
+    }
+    for _, edge := range []string{"e1 -> e2;", "e1 -> e3;", "e3 -> e4;"} {
+        if !strings.Contains(dot, edge) {
+            t.Errorf("DOT output is missing %q:\n%s", edge, dot)
+        }
+    }
+    if strings.Count(dot, "->") != 3 {
+        t.Errorf("DOT output has %d edges, want 3:\n%s", strings.Count(dot, "->"), dot)
+    }
+}
+
+func TestRemoveManagerWithReports(t *testing.T) {
+    em := newOrgChartTestManager(t)
+
+    if err := em.RemoveEmployee(3, today()); !errors.Is(err, ErrHasReports) {
+        t.Fatalf("RemoveEmployee(3) = %v, want ErrHasReports", err)
+    }
+    if _, err := em.SearchByID(3); err != nil {
+        t.Fatalf("rejected removal still terminated employee 3: %v", err)
+    }
+
+    if err := em.SetManager(4, 1); err != nil {
+        t.Fatal(err)
+    }
+    if err := em.RemoveEmployee(3, today()); err != nil {
+        t.Fatalf("RemoveEmployee(3) after moving their report = %v", err)
+    }
+    if direct, _ := em.DirectReports(1); employeeIDs(direct) != "[2 4]" {
+        t.Errorf("DirectReports(1) = %s, want [2 4]", employeeIDs(direct))
+    }
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+    "sort"
+    "strings"
+    "time"
+)
+
+// Fields accepted by EmployeeQuery.SortBy
+const (
+    SORT_BY_ID         = "id"
+    SORT_BY_NAME       = "name"
+    SORT_BY_AGE        = "age"
+    SORT_BY_DEPARTMENT = "department"
+    SORT_BY_HIRE_DATE  = "hire_date"
+    SORT_BY_SALARY     = "salary"
+    SORT_BY_TITLE      = "title"
+    SORT_BY_STATUS     = "status"
+)
+
+// employeeLess orders two employees by a single field
+var employeeLess = map[string]func(a, b *Employee) bool{
+    SORT_BY_ID:         func(a, b *Employee) bool { return a.ID < b.ID },
+    SORT_BY_NAME:       func(a, b *Employee) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) },
+    SORT_BY_AGE:        func(a, b *Employee) bool { return a.Age < b.Age },
+    SORT_BY_DEPARTMENT: func(a, b *Employee) bool { return a.Department < b.Department },
+    SORT_BY_HIRE_DATE:  func(a, b *Employee) bool { return a.HireDate.Before(b.HireDate) },
+    SORT_BY_SALARY:     func(a, b *Employee) bool { return a.Salary < b.Salary },
+    SORT_BY_TITLE:      func(a, b *Employee) bool { return strings.ToLower(a.Title) < strings.ToLower(b.Title) },
+    SORT_BY_STATUS:     func(a, b *Employee) bool { return a.Status < b.Status },
+}
+
+// EmployeeQuery combines search criteria over the roster. Build one with
+// EmployeeManager.Query, chain the criteria and call Run
+type EmployeeQuery struct {
+    manager           *EmployeeManager
+    nameContains      string
+    namePrefix        string
+    nameFuzzy         string
+    maxDistance       int
+    minAge            int
+    maxAge            int
+    departments       []string
+    hiredFrom         time.Time
+    hiredTo           time.Time
+    includeTerminated bool
+    sortField         string
+    descending        bool
+    offset            int
+    limit             int
+}
+
+// QueryResult is one page of matching employees plus the number of matches across all pages
+type QueryResult struct {
+    Employees []*Employee `json:"employees"`
+    Total     int         `json:"total"`
+}
+
+// Query starts a new search over the active roster, sorted by ID
+func (em *EmployeeManager) Query() *EmployeeQuery {
+    return &EmployeeQuery{
+        manager:   em,
+        sortField: SORT_BY_ID,
+    }
+}
+
+// NameContains keeps employees whose name contains s, ignoring case
+func (q *EmployeeQuery) NameContains(s string) *EmployeeQuery {
+    q.nameContains = strings.ToLower(strings.TrimSpace(s))
+    return q
+}
+
+// NamePrefix keeps employees whose first or last name starts with s, ignoring case
+func (q *EmployeeQuery) NamePrefix(s string) *EmployeeQuery {
+    q.namePrefix = strings.ToLower(strings.TrimSpace(s))
+    return q
+}
+
+// NameFuzzy keeps employees with a name, or a word in their name, within maxDistance
+// edits of s, so small typos still match
+func (q *EmployeeQuery) NameFuzzy(s string, maxDistance int) *EmployeeQuery {
+    q.nameFuzzy = strings.ToLower(strings.TrimSpace(s))
+    q.maxDistance = maxDistance
+    return q
+}
+
+// AgeBetween keeps employees aged min to max inclusive; a zero bound is open
+func (q *EmployeeQuery) AgeBetween(min int, max int) *EmployeeQuery {
+    q.minAge = min
+    q.maxAge = max
+    return q
+}
+
+// InDepartments keeps employees in any of the given departments. Naming a department
+// more than once, in any case, still returns each employee once
+func (q *EmployeeQuery) InDepartments(departments ...string) *EmployeeQuery {
+    for _, dept := range departments {
+        dept = normalizeDepartment(dept)
+        if !containsString(q.departments, dept) {
+            q.departments = append(q.departments, dept)
+        }
+    }
+    return q
+}
+
+// HiredBetween keeps employees hired from one date to another inclusive; a zero bound is open
+func (q *EmployeeQuery) HiredBetween(from time.Time, to time.Time) *EmployeeQuery {
+    q.hiredFrom = from
+    q.hiredTo = to
+    return q
+}
+
+// IncludeTerminated also searches employees who have left
+func (q *EmployeeQuery) IncludeTerminated() *EmployeeQuery {
+    q.includeTerminated = true
+    return q
+}
+
+// SortBy orders results by one of the SORT_BY_ fields; ties are broken by ID
+func (q *EmployeeQuery) SortBy(field string, descending bool) *EmployeeQuery {
+    q.sortField = strings.ToLower(field)
+    q.descending = descending
+    return q
+}
+
+// Offset skips the first n matches
+func (q *EmployeeQuery) Offset(n int) *EmployeeQuery {
+    q.offset = n
+    return q
+}
+
+// Limit returns at most n matches; zero means no limit
+func (q *EmployeeQuery) Limit(n int) *EmployeeQuery {
+    q.limit = n
+    return q
+}
+
+// Run executes the query. Finding nothing is not an error; the result is simply empty
+func (q *EmployeeQuery) Run() (QueryResult, error) {
+    result := QueryResult{Employees: make([]*Employee, 0)}
+
+    less, ok := employeeLess[q.sortField]
+    if !ok {
+        return result, &ValidationError{Field: "sort", Value: q.sortField, Err: ErrInvalidQuery}
+    }
+    if q.offset < 0 {
+        return result, &ValidationError{Field: "offset", Err: ErrInvalidQuery}
+    }
+    if q.limit < 0 {
+        return result, &ValidationError{Field: "limit", Err: ErrInvalidQuery}
+    }
+
+    em := q.manager
+    em.mu.RLock()
+    var matches []*Employee
+    for _, i := range q.candidates() {
+        if q.matches(&em.employees[i]) {
+            emp := em.employees[i]
+            matches = append(matches, &emp)
+        }
+    }
+    em.mu.RUnlock()
+
+    sort.SliceStable(matches, func(i, j int) bool {
+        a, b := matches[i], matches[j]
+        if q.descending {
+            a, b = b, a
+        }
+        if less(a, b) {
+            return true
+        }
+        if less(b, a) {
+            return false
+        }
+        return matches[i].ID < matches[j].ID
+    })
+
+    result.Total = len(matches)
+    if q.offset >= len(matches) {
+        return result, nil
+    }
+    matches = matches[q.offset:]
+    if q.limit > 0 && q.limit < len(matches) {
+        matches = matches[:q.limit]
+    }
+    result.Employees = matches
+    return result, nil
+}
+
+// candidates narrows the positions to check using the indexes where the criteria allow.
+// Callers must hold the manager's lock
+func (q *EmployeeQuery) candidates() []int {
+    em := q.manager
+    if len(q.departments) > 0 {
+        return em.index.departmentPositions(q.departments)
+    }
+    if positions, ok := em.index.nameCandidates(q.nameContains); ok {
+        return positions
+    }
+
+    all := make([]int, len(em.employees))
+    for i := range all {
+        all[i] = i
+    }
+    return all
+}
+
+// matches reports whether emp satisfies every criterion
+func (q *EmployeeQuery) matches(emp *Employee) bool {
+    if !q.includeTerminated && !emp.IsActive() {
+        return false
+    }
+
+    name := strings.ToLower(emp.Name)
+    if q.nameContains != "" && !strings.Contains(name, q.nameContains) {
+        return false
+    }
+    if q.namePrefix != "" && !hasWordPrefix(name, q.namePrefix) {
+        return false
+    }
+    if q.nameFuzzy != "" && !fuzzyMatch(name, q.nameFuzzy, q.maxDistance) {
+        return false
+    }
+
+    if q.minAge > 0 && emp.Age < q.minAge {
+        return false
+    }
+    if q.maxAge > 0 && emp.Age > q.maxAge {
+        return false
+    }
+    if len(q.departments) > 0 && !containsString(q.departments, emp.Department) {
+        return false
+    }
+    if !q.hiredFrom.IsZero() && emp.HireDate.Before(q.hiredFrom) {
+        return false
+    }
+    if !q.hiredTo.IsZero() && emp.HireDate.After(q.hiredTo) {
+        return false
+    }
+    return true
+}
+
+// hasWordPrefix reports whether name, or any word in it, starts with prefix
+func hasWordPrefix(name string, prefix string) bool {
+    if strings.HasPrefix(name, prefix) {
+        return true
+    }
+    for _, word := range strings.Fields(name) {
+        if strings.HasPrefix(word, prefix) {
+            return true
+        }
+    }
+    return false
+}
+
+// fuzzyMatch reports whether name, or any word in it, is within maxDistance edits of query
+func fuzzyMatch(name string, query string, maxDistance int) bool {
+    if levenshtein(name, query) <= maxDistance {
+        return true
+    }
+    for _, word := range strings.Fields(name) {
+        if levenshtein(word, query) <= maxDistance {
+            return true
+        }
+    }
+    return false
+}
+
+// levenshtein returns the number of single-rune insertions, deletions and
+// substitutions needed to turn a into b
+func levenshtein(a string, b string) int {
+    ra, rb := []rune(a), []rune(b)
+    prev := make([]int, len(rb)+1)
+    curr := make([]int, len(rb)+1)
+    for j := range prev {
+        prev[j] = j
+    }
+
+    for i := 1; i <= len(ra); i++ {
+        curr[0] = i
+        for j := 1; j <= len(rb); j++ {
+            cost := 1
+            if ra[i-1] == rb[j-1] {
+                cost = 0
+            }
+            curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+        }
+        prev, curr = curr, prev
+    }
+    return prev[len(rb)]
+}
+
+// containsString reports whether values contains s
+func containsString(values []string, s string) bool {
+    for _, v := range values {
+        if v == s {
+            return true
+        }
+    }
+    return false
+}
+
@@ -0,0 +1,81 @@
+package main
+
+import (
+    "errors"
+    "testing"
+    "time"
+)
+
+func TestValidationErrors(t *testing.T) {
+    tomorrow := today().AddDate(0, 0, 1)
+
+    tests := []struct {
+        name      string
+        change    func(emp *Employee)
+        wantIs    error
+        wantField string
+    }{
+        {"valid", func(emp *Employee) {}, nil, ""},
+        {"underage", func(emp *Employee) { emp.Age = 17 }, ErrUnderage, "age"},
+        {"adult on the boundary", func(emp *Employee) { emp.Age = 18 }, nil, ""},
+        {"unknown department", func(emp *Employee) { emp.Department = "OPS" }, ErrInvalidDepartment, "department"},
+        {"email without domain", func(emp *Employee) { emp.Email = "ravi@" }, ErrInvalidEmail, "email"},
+        {"email with display name", func(emp *Employee) { emp.Email = "Ravi <ravi@example.com>" }, ErrInvalidEmail, "email"},
+        {"email in use", func(emp *Employee) { emp.Email = "ASHA@example.com" }, ErrDuplicateEmail, "email"},
+        {"phone with letters", func(emp *Employee) { emp.Phone = "98765-ABCDE" }, ErrInvalidPhone, "phone"},
+        {"phone too short", func(emp *Employee) { emp.Phone = "12345" }, ErrInvalidPhone, "phone"},
+        {"phone with plus inside", func(emp *Employee) { emp.Phone = "91+9876543210" }, ErrInvalidPhone, "phone"},
+        {"formatted phone", func(emp *Employee) { emp.Phone = "+91 (98765) 43210" }, nil, ""},
+        {"future hire date", func(emp *Employee) { emp.HireDate = tomorrow }, ErrFutureHireDate, "hire_date"},
+        {"negative salary", func(emp *Employee) { emp.Salary = -1 }, ErrInvalidSalary, "salary"},
+        {"zero salary", func(emp *Employee) { emp.Salary = 0 }, nil, ""},
+        {"unknown status", func(emp *Employee) { emp.Status = "RETIRED" }, ErrInvalidStatus, "status"},
+        {"hired as terminated", func(emp *Employee) { emp.Status = STATUS_TERMINATED }, ErrInvalidStatus, "status"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            em := NewEmployeeManager()
+            if err := em.AddEmployeeRecord(Employee{ID: 1, Name: "Asha Rao", Age: 30, Department: IT_DEPT, Email: "asha@example.com"}); err != nil {
+                t.Fatal(err)
+            }
+
+            emp := Employee{ID: 2, Name: "Ravi Nair", Age: 28, Department: HR_DEPT, Salary: 50000,
+                HireDate: time.Date(2024, time.January, 15, 0, 0, 0, 0, time.Local)}
+            tt.change(&emp)
+            err := em.AddEmployeeRecord(emp)
+
+            if tt.wantIs == nil {
+                if err != nil {
+                    t.Fatalf("AddEmployeeRecord() = %v, want nil", err)
+                }
+                return
+            }
+            if !errors.Is(err, tt.wantIs) {
+                t.Fatalf("AddEmployeeRecord() = %v, want %v", err, tt.wantIs)
+            }
+            var validationErr *ValidationError
+            if !errors.As(err, &validationErr) || validationErr.Field != tt.wantField {
+                t.Errorf("AddEmployeeRecord() = %#v, want a ValidationError on %s", err, tt.wantField)
+            }
+            if _, err := em.SearchByIDIncludingTerminated(2); !errors.Is(err, ErrNotFound) {
+                t.Errorf("rejected employee was added to the roster")
+            }
+        })
+    }
+}
+
+func TestUpdateValidatesRecord(t *testing.T) {
+    em := NewEmployeeManager()
+    if err := em.AddEmployee(1, "Asha Rao", 30, IT_DEPT); err != nil {
+        t.Fatal(err)
+    }
+
+    err := em.UpdateEmployee(1, "Asha Rao", 16, IT_DEPT)
+    if !errors.Is(err, ErrUnderage) {
+        t.Fatalf("UpdateEmployee() with age 16 = %v, want ErrUnderage", err)
+    }
+    if emp, _ := em.SearchByID(1); emp.Age != 30 {
+        t.Errorf("rejected update changed the age to %d", emp.Age)
+    }
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+)
+
+// Account types
+const (
+    SAVINGS_ACCOUNT = "SAVINGS"
+    CURRENT_ACCOUNT = "CURRENT"
+    FIXED_DEPOSIT   = "FIXED_DEPOSIT"
+)
+
+// Bank ledger accounts used by month-end processing
+const (
+    INTEREST_EXPENSE_LEDGER = "INTEREST_EXPENSE"
+    FEE_INCOME_LEDGER       = "FEE_INCOME"
+)
+
+// Interest accrues daily at AnnualRateBP / 10000 / 365 of the balance
+const interestDenominator = 10000 * 365
+
+// AccountTerms are the rules an account is opened under. They are fixed for the life of the account
+type AccountTerms struct {
+    Type string
+    // AnnualRateBP is the interest rate in basis points: 400 is 4% a year
+    AnnualRateBP int64
+    // MinimumBalance is the average monthly balance below which MinimumBalancePenalty is charged
+    MinimumBalance        Money
+    MinimumBalancePenalty Money
+    // OverdraftLimit is how far below zero the balance may go
+    OverdraftLimit Money
+    // LockInDays is how long after opening withdrawals are refused
+    LockInDays int
+    // Limits are the withdrawal limits a new account starts with; they can be changed later
+    Limits WithdrawalLimits
+}
+
+// InterestAccrual is the interest earned by an account but not yet posted
+type InterestAccrual struct {
+    // Through is the last day interest has been accrued for, or zero before the first run
+    Through time.Time
+    // Numerator is the unposted interest in minor units times interestDenominator,
+    // so no fraction of a paisa is lost between postings
+    Numerator int64
+    // BalanceDays and Days give the average daily balance for the month so far
+    BalanceDays int64
+    Days        int
+}
+
+// DefaultAccountTerms returns the terms new accounts of each type are opened with
+func DefaultAccountTerms() map[string]AccountTerms {
+    return map[string]AccountTerms{
+        SAVINGS_ACCOUNT: {
+            Type:                  SAVINGS_ACCOUNT,
+            AnnualRateBP:          400,
+            MinimumBalance:        INR(100000),
+            MinimumBalancePenalty: INR(10000),
+            OverdraftLimit:        INR(0),
+            Limits:                WithdrawalLimits{PerTransaction: INR(5000000), Daily: INR(10000000)},
+        },
+        CURRENT_ACCOUNT: {
+            Type:                  CURRENT_ACCOUNT,
+            MinimumBalance:        INR(0),
+            MinimumBalancePenalty: INR(0),
+            OverdraftLimit:        INR(1000000),
+            Limits:                WithdrawalLimits{PerTransaction: INR(20000000), Daily: INR(50000000)},
+        },
+        FIXED_DEPOSIT: {
+            Type:                  FIXED_DEPOSIT,
+            AnnualRateBP:          700,
+            MinimumBalance:        INR(0),
+            MinimumBalancePenalty: INR(0),
+            OverdraftLimit:        INR(0),
+            LockInDays:            365,
+        },
+    }
+}
+
+// AccountTypes returns the terms of every type accounts can be opened as, ordered by type
+func (bs *BankSystem) AccountTypes() []AccountTerms {
+    bs.mu.RLock()
+    defer bs.mu.RUnlock()
+
+    types := make([]AccountTerms, 0, len(bs.terms))
+    for _, terms := range bs.terms {
+        types = append(types, terms)
+    }
+    sort.Slice(types, func(i, j int) bool { return types[i].Type < types[j].Type })
+    return types
+}
+
+// SetAccountTerms changes the terms accounts of a type are opened with from now on.
+// Existing accounts keep the terms they were opened under
+func (bs *BankSystem) SetAccountTerms(terms AccountTerms) error {
+    switch {
+    case terms.AnnualRateBP < 0:
+        return fmt.Errorf("interest rate cannot be negative")
+    case terms.MinimumBalance.Minor < 0 || terms.MinimumBalancePenalty.Minor < 0:
+        return fmt.Errorf("minimum balance and penalty cannot be negative")
+    case terms.OverdraftLimit.Minor < 0:
+        return fmt.Errorf("overdraft limit cannot be negative")
+    case terms.LockInDays < 0:
+        return fmt.Errorf("lock-in period cannot be negative")
+    case terms.Limits.PerTransaction.Minor < 0 || terms.Limits.Daily.Minor < 0:
+        return fmt.Errorf("withdrawal limits cannot be negative")
+    }
+
+    bs.mu.Lock()
+    defer bs.mu.Unlock()
+
+    terms.Type = strings.ToUpper(terms.Type)
+    bs.terms[terms.Type] = terms
+    return nil
+}
+
+// termsFor returns the terms a new account of the given type is opened with. Callers must hold bs.mu
+func (bs *BankSystem) termsFor(accountType string) (AccountTerms, error) {
+    terms, ok := bs.terms[strings.ToUpper(accountType)]
+    if !ok {
+        return AccountTerms{}, fmt.Errorf("%w %q", ErrUnknownAccountType, accountType)
+    }
+    return terms, nil
+}
+
+// AccountTerms returns the terms the account was opened under
+func (a *Account) AccountTerms() AccountTerms {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    return a.Terms
+}
+
+// LockedUntil returns when a fixed deposit's lock-in ends, or the zero time if it has none
+func (a *Account) LockedUntil() time.Time {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    return a.lockedUntil()
+}
+
+// lockedUntil returns when a fixed deposit's lock-in ends. Callers must hold a.mu
+func (a *Account) lockedUntil() time.Time {
+    if a.Terms.LockInDays == 0 {
+        return time.Time{}
+    }
+    return dateOnly(a.OpenedAt).AddDate(0, 0, a.Terms.LockInDays)
+}
+
+// checkDebit returns the balance left after taking amount from an account, or an error if its
+// terms do not allow it. Callers must hold a.mu
+func (bs *BankSystem) checkDebit(a *Account, amount Money) (Money, error) {
+    if until := a.lockedUntil(); time.Now().Before(until) {
+        return Money{}, fmt.Errorf("fixed deposit %d %w until %s", a.ID, ErrAccountLocked, until.Format("2006-01-02"))
+    }
+
+    current := bs.balanceOf(a.ID)
+    balance, err := current.Sub(amount)
+    if err != nil {
+        return Money{}, err
+    }
+    if balance.Minor < -a.Terms.OverdraftLimit.Minor {
+        if a.Terms.OverdraftLimit.IsPositive() {
+            return Money{}, fmt.Errorf("%w: overdraft limit of %v exceeded. Current balance: %v", ErrInsufficientBalance, a.Terms.OverdraftLimit, current)
+        }
+        return Money{}, fmt.Errorf("%w. Current balance: %v", ErrInsufficientBalance, current)
+    }
+    return balance, nil
+}
+
+// RunEndOfDay accrues interest on every open account for each day up to and including
+// through, and on the last day of each month posts the accrued interest and charges the
+// minimum balance penalty. Each day accrues on the account's closing balance that day as
+// dated in the journal, and month-end postings start from the balance on the last day of
+// the month, so days missed while the bank was down are caught up exactly.
+// through should be a day that has ended. It returns the transactions it posted
+func (bs *BankSystem) RunEndOfDay(through time.Time) ([]Transaction, error) {
+    through = dateOnly(through)
+    posted := make([]Transaction, 0)
+
+    for _, acc := range bs.ListAccounts() {
+        transactions, err := bs.accrueInterest(acc, through)
+        posted = append(posted, transactions...)
+        if err != nil {
+            return posted, fmt.Errorf("account %d: %w", acc.ID, err)
+        }
+    }
+    return posted, nil
+}
+
+// accrueInterest brings one account's interest up to date
+func (bs *BankSystem) accrueInterest(acc *Account, through time.Time) ([]Transaction, error) {
+    defer bs.maybeCheckpoint()
+
+    bs.mu.RLock()
+    defer bs.mu.RUnlock()
+
+    acc.mu.Lock()
+    defer acc.mu.Unlock()
+
+    posted := make([]Transaction, 0)
+    if acc.Closed {
+        return posted, nil
+    }
+
+    // The first run starts on the opening day, so money paid in that day earns interest for it
+    accrual := acc.Accrual
+    start := accrual.Through.AddDate(0, 0, 1)
+    if accrual.Through.IsZero() {
+        start = dateOnly(acc.OpenedAt)
+    }
+    if start.After(through) {
+        return posted, nil
+    }
+
+    // Month-end postings are dated the last day of the month, so each one is added to the
+    // closing balances after it to count its interest and charges from the next day on
+    closing := bs.journal.ClosingBalances(customerLedger(acc.ID), start, through)
+    pending := false
+    for i, day := 0, start; !day.After(through); i, day = i+1, day.AddDate(0, 0, 1) {
+        pending = true
+        balance := closing[i]
+        if balance.IsPositive() {
+            accrual.Numerator += balance.Minor * acc.Terms.AnnualRateBP
+        }
+        accrual.BalanceDays += balance.Minor
+        accrual.Days++
+        accrual.Through = day
+
+        if day.AddDate(0, 0, 1).Day() != 1 {
+            continue
+        }
+
+        // Last day of the month: post what has been earned and charged, and start afresh
+        record := bs.monthEndRecord(acc, &accrual, day, balance)
+        if err := bs.commit(record); err != nil {
+            return posted, err
+        }
+        monthEnd := acc.Transactions[len(acc.Transactions)-len(record.Entries):]
+        posted = append(posted, monthEnd...)
+        accrual = acc.Accrual
+        pending = false
+        for _, t := range monthEnd {
+            for j := i + 1; j < len(closing); j++ {
+                closing[j], _ = closing[j].Add(t.signedAmount())
+            }
+        }
+    }
+
+    if pending {
+        if err := bs.commit(walRecord{Op: WAL_ACCRUE, AccountID: acc.ID, Accrual: &accrual}); err != nil {
+            return posted, err
+        }
+    }
+    return posted, nil
+}
+
+// monthEndRecord builds the record that posts an account's interest for the month ending on
+// day and charges the minimum balance penalty, given the account's closing balance that day.
+// Later transactions may already be posted when catching up, so the current balance is not used.
+// It resets the monthly totals in accrual
+func (bs *BankSystem) monthEndRecord(acc *Account, accrual *InterestAccrual, day time.Time, balance Money) walRecord {
+    record := walRecord{Op: WAL_ACCRUE, AccountID: acc.ID, Timestamp: day.AddDate(0, 0, 1).Add(-time.Second)}
+    journal := &JournalEntry{Description: fmt.Sprintf("Month-end processing for %s", day.Format("January 2006"))}
+
+    interest := NewMoney(accrual.Numerator/interestDenominator, balance.Currency)
+    accrual.Numerator -= interest.Minor * interestDenominator
+    if interest.IsPositive() {
+        balance, _ = balance.Add(interest)
+        journal.Postings = append(journal.Postings, transfer(INTEREST_EXPENSE_LEDGER, customerLedger(acc.ID), interest)...)
+        record.Entries = append(record.Entries, walEntry{
+            AccountID:   acc.ID,
+            Transaction: Transaction{Type: INTEREST_TYPE, Amount: interest, BalanceAfter: balance, Description: "Interest credited"},
+        })
+    }
+
+    // The penalty never takes the account below zero
+    average := accrual.BalanceDays / int64(accrual.Days)
+    penalty := acc.Terms.MinimumBalancePenalty
+    if penalty.Minor > balance.Minor {
+        penalty = NewMoney(balance.Minor, balance.Currency)
+    }
+    if average < acc.Terms.MinimumBalance.Minor && penalty.IsPositive() {
+        balance, _ = balance.Sub(penalty)
+        journal.Postings = append(journal.Postings, transfer(customerLedger(acc.ID), FEE_INCOME_LEDGER, penalty)...)
+        record.Entries = append(record.Entries, walEntry{
+            AccountID: acc.ID,
+            Transaction: Transaction{
+                Type:         PENALTY_TYPE,
+                Amount:       penalty,
+                BalanceAfter: balance,
+                Description:  fmt.Sprintf("Minimum balance charge (average balance %v)", NewMoney(average, balance.Currency)),
+            },
+        })
+    }
+
+    if len(journal.Postings) > 0 {
+        record.Op = WAL_POST
+        record.Journal = journal
+    }
+    accrual.BalanceDays, accrual.Days = 0, 0
+    saved := *accrual
+    record.Accrual = &saved
+    return record
+}
+
+// AccruedInterest returns the interest an account has earned since it was last credited
+func (bs *BankSystem) AccruedInterest(id int) (Money, error) {
+    bs.mu.RLock()
+    defer bs.mu.RUnlock()
+
+    account, err := bs.findAnyAccount(id)
+    if err != nil {
+        return Money{}, err
+    }
+
+    account.mu.Lock()
+    defer account.mu.Unlock()
+    return NewMoney(account.Accrual.Numerator/interestDenominator, bs.journal.currency), nil
+}
+
+// formatRate formats a rate in basis points as a percentage
+func formatRate(bp int64) string {
+    return fmt.Sprintf("%d.%02d%%", bp/100, bp%100)
+}
+
+// dateOnly strips the time of day from t
+func dateOnly(t time.Time) time.Time {
+    return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
@@ -0,0 +1,588 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "log"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Machine-readable error codes returned in every error body
+const (
+    CODE_ACCOUNT_NOT_FOUND      = "ACCOUNT_NOT_FOUND"
+    CODE_INSUFFICIENT_BALANCE   = "INSUFFICIENT_BALANCE"
+    CODE_ACCOUNT_CLOSED         = "ACCOUNT_CLOSED"
+    CODE_ACCOUNT_LOCKED         = "ACCOUNT_LOCKED"
+    CODE_ACCOUNT_EXISTS         = "ACCOUNT_EXISTS"
+    CODE_IDEMPOTENCY_KEY_REUSED = "IDEMPOTENCY_KEY_REUSED"
+    CODE_LIMIT_EXCEEDED         = "WITHDRAWAL_LIMIT_EXCEEDED"
+    CODE_TRANSACTION_BLOCKED    = "TRANSACTION_BLOCKED"
+    CODE_FLAG_NOT_FOUND         = "FLAG_NOT_FOUND"
+    CODE_FLAG_REVIEWED          = "FLAG_ALREADY_REVIEWED"
+    CODE_INVALID_REQUEST        = "INVALID_REQUEST"
+    CODE_INTERNAL_ERROR         = "INTERNAL_ERROR"
+)
+
+// BankHandler exposes a BankSystem over HTTP with JSON payloads. Amounts are decimal
+// strings such as "1250.50", so they are never rounded on the way in or out
+type BankHandler struct {
+    bank        *BankSystem
+    idempotency *IdempotencyStore
+}
+
+// NewBankHandler creates a handler serving bank
+func NewBankHandler(bank *BankSystem) *BankHandler {
+    return &BankHandler{bank: bank, idempotency: NewIdempotencyStore(IDEMPOTENCY_TTL)}
+}
+
+// createAccountRequest is the JSON body of the create account endpoint
+type createAccountRequest struct {
+    // ID is optional; 0 opens the account under the next free ID
+    ID   int    `json:"id"`
+    Name string `json:"name"`
+    // Type defaults to a savings account
+    Type string `json:"type"`
+}
+
+// amountRequest is the JSON body of the deposit and withdraw endpoints
+type amountRequest struct {
+    Amount json.Number `json:"amount"`
+}
+
+// transferRequest is the JSON body of the transfer endpoint
+type transferRequest struct {
+    From   int         `json:"from"`
+    To     int         `json:"to"`
+    Amount json.Number `json:"amount"`
+}
+
+// limitsRequest is the JSON body of the withdrawal limits endpoint. A missing or zero
+// limit removes it
+type limitsRequest struct {
+    PerTransaction json.Number `json:"per_transaction"`
+    Daily          json.Number `json:"daily"`
+}
+
+// reviewRequest is the JSON body of the flag review endpoint
+type reviewRequest struct {
+    // Status is CLEARED or CONFIRMED_FRAUD
+    Status string `json:"status"`
+    Note   string `json:"note"`
+}
+
+// accountResponse describes an account
+type accountResponse struct {
+    ID                  int       `json:"id"`
+    Name                string    `json:"name"`
+    Type                string    `json:"type"`
+    Balance             string    `json:"balance"`
+    Currency            string    `json:"currency"`
+    InterestRate        string    `json:"interest_rate"`
+    OverdraftLimit      string    `json:"overdraft_limit,omitempty"`
+    LockedUntil         string    `json:"locked_until,omitempty"`
+    PerTransactionLimit string    `json:"per_transaction_limit,omitempty"`
+    DailyLimit          string    `json:"daily_limit,omitempty"`
+    WithdrawnToday      string    `json:"withdrawn_today"`
+    OpenedAt            time.Time `json:"opened_at"`
+    Closed              bool      `json:"closed"`
+}
+
+// transactionResponse describes one entry in an account's ledger
+type transactionResponse struct {
+    ID           int64     `json:"id"`
+    Type         string    `json:"type"`
+    Amount       string    `json:"amount"`
+    BalanceAfter string    `json:"balance_after"`
+    Currency     string    `json:"currency"`
+    Timestamp    time.Time `json:"timestamp"`
+    Description  string    `json:"description"`
+    Counterparty int       `json:"counterparty,omitempty"`
+    Reference    string    `json:"reference,omitempty"`
+}
+
+// flagResponse describes a transaction in the fraud review queue
+type flagResponse struct {
+    ID            int64      `json:"id"`
+    AccountID     int        `json:"account_id"`
+    TransactionID int64      `json:"transaction_id,omitempty"`
+    Type          string     `json:"type"`
+    Amount        string     `json:"amount"`
+    Currency      string     `json:"currency"`
+    Action        string     `json:"action"`
+    Reasons       []string   `json:"reasons"`
+    FlaggedAt     time.Time  `json:"flagged_at"`
+    Status        string     `json:"status"`
+    ReviewedAt    *time.Time `json:"reviewed_at,omitempty"`
+    Note          string     `json:"note,omitempty"`
+}
+
+// postingResponse is returned by the money-moving endpoints. Flag is set when the
+// transaction went through but was queued for fraud review
+type postingResponse struct {
+    Account     accountResponse     `json:"account"`
+    Transaction transactionResponse `json:"transaction"`
+    Flag        *flagResponse       `json:"flag,omitempty"`
+}
+
+// trialBalanceRow is one ledger account of the trial balance
+type trialBalanceRow struct {
+    Ledger  string `json:"ledger"`
+    Debits  string `json:"debits"`
+    Credits string `json:"credits"`
+    Balance string `json:"balance"`
+}
+
+// trialBalanceResponse is the JSON body of the trial balance endpoint
+type trialBalanceResponse struct {
+    Ledgers      []trialBalanceRow `json:"ledgers"`
+    TotalDebits  string            `json:"total_debits"`
+    TotalCredits string            `json:"total_credits"`
+    Balanced     bool              `json:"balanced"`
+}
+
+// errorResponse is the JSON body returned for every failed request
+type errorResponse struct {
+    Error string `json:"error"`
+    Code  string `json:"code"`
+}
+
+// RegisterRoutes adds the banking endpoints to mux
+func (h *BankHandler) RegisterRoutes(mux *http.ServeMux) {
+    mux.HandleFunc("GET /accounts", h.listAccounts)
+    mux.HandleFunc("POST /accounts", h.createAccount)
+    mux.HandleFunc("GET /accounts/{id}", h.getAccount)
+    mux.HandleFunc("POST /accounts/{id}/deposit", h.idempotency.idempotent(h.deposit))
+    mux.HandleFunc("POST /accounts/{id}/withdraw", h.idempotency.idempotent(h.withdraw))
+    mux.HandleFunc("GET /accounts/{id}/transactions", h.listTransactions)
+    mux.HandleFunc("PUT /accounts/{id}/limits", h.setLimits)
+    mux.HandleFunc("POST /transfers", h.idempotency.idempotent(h.transfer))
+    mux.HandleFunc("GET /flags", h.listFlags)
+    mux.HandleFunc("POST /flags/{id}/review", h.reviewFlag)
+    mux.HandleFunc("GET /trial-balance", h.trialBalance)
+}
+
+// List every account, including closed ones
+func (h *BankHandler) listAccounts(w http.ResponseWriter, r *http.Request) {
+    accounts := make([]accountResponse, 0)
+    for _, acc := range h.bank.ListAccounts() {
+        accounts = append(accounts, h.accountView(acc))
+    }
+    writeJSON(w, http.StatusOK, accounts)
+}
+
+// Open a new account
+func (h *BankHandler) createAccount(w http.ResponseWriter, r *http.Request) {
+    var req createAccountRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request payload", Code: CODE_INVALID_REQUEST})
+        return
+    }
+    if strings.TrimSpace(req.Name) == "" {
+        writeJSON(w, http.StatusBadRequest, errorResponse{Error: "name is required", Code: CODE_INVALID_REQUEST})
+        return
+    }
+    if req.ID < 0 {
+        writeJSON(w, http.StatusBadRequest, errorResponse{Error: "id must be positive", Code: CODE_INVALID_REQUEST})
+        return
+    }
+    if req.Type == "" {
+        req.Type = SAVINGS_ACCOUNT
+    }
+
+    account, err := h.bank.CreateAccount(req.ID, strings.TrimSpace(req.Name), req.Type)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    writeJSON(w, http.StatusCreated, h.accountView(account))
+}
+
+// Fetch a single account with its balance
+func (h *BankHandler) getAccount(w http.ResponseWriter, r *http.Request) {
+    account, ok := h.account(w, r)
+    if !ok {
+        return
+    }
+    writeJSON(w, http.StatusOK, h.accountView(account))
+}
+
+// Deposit cash into an account
+func (h *BankHandler) deposit(w http.ResponseWriter, r *http.Request) {
+    h.post(w, r, h.bank.Deposit)
+}
+
+// Withdraw cash from an account
+func (h *BankHandler) withdraw(w http.ResponseWriter, r *http.Request) {
+    h.post(w, r, h.bank.Withdraw)
+}
+
+// post runs a deposit or withdrawal against the {id} account
+func (h *BankHandler) post(w http.ResponseWriter, r *http.Request, operation func(int, Money) (Transaction, error)) {
+    account, ok := h.account(w, r)
+    if !ok {
+        return
+    }
+
+    var req amountRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request payload", Code: CODE_INVALID_REQUEST})
+        return
+    }
+    amount, ok := parseAmount(w, req.Amount)
+    if !ok {
+        return
+    }
+
+    t, err := operation(account.ID, amount)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    writeJSON(w, http.StatusOK, h.postingView(account, t))
+}
+
+// Move money between two accounts
+func (h *BankHandler) transfer(w http.ResponseWriter, r *http.Request) {
+    var req transferRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request payload", Code: CODE_INVALID_REQUEST})
+        return
+    }
+    amount, ok := parseAmount(w, req.Amount)
+    if !ok {
+        return
+    }
+
+    t, err := h.bank.Transfer(req.From, req.To, amount)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    account, err := h.bank.GetAccount(req.From)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    writeJSON(w, http.StatusOK, h.postingView(account, t))
+}
+
+// Replace an account's withdrawal limits
+func (h *BankHandler) setLimits(w http.ResponseWriter, r *http.Request) {
+    account, ok := h.account(w, r)
+    if !ok {
+        return
+    }
+
+    var req limitsRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request payload", Code: CODE_INVALID_REQUEST})
+        return
+    }
+    var limits WithdrawalLimits
+    var err error
+    if limits.PerTransaction, err = parseLimit(req.PerTransaction); err == nil {
+        limits.Daily, err = parseLimit(req.Daily)
+    }
+    if err != nil {
+        writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error(), Code: CODE_INVALID_REQUEST})
+        return
+    }
+
+    if err := h.bank.SetWithdrawalLimits(account.ID, limits); err != nil {
+        writeError(w, err)
+        return
+    }
+    writeJSON(w, http.StatusOK, h.accountView(account))
+}
+
+// List the fraud review queue, oldest first, optionally only flags with ?status=
+func (h *BankHandler) listFlags(w http.ResponseWriter, r *http.Request) {
+    flags := make([]flagResponse, 0)
+    for _, flag := range h.bank.FlaggedTransactions(r.URL.Query().Get("status")) {
+        flags = append(flags, flagView(flag))
+    }
+    writeJSON(w, http.StatusOK, flags)
+}
+
+// Record the decision on a flagged transaction
+func (h *BankHandler) reviewFlag(w http.ResponseWriter, r *http.Request) {
+    id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+    if err != nil {
+        writeJSON(w, http.StatusBadRequest, errorResponse{Error: "flag ID must be a number", Code: CODE_INVALID_REQUEST})
+        return
+    }
+
+    var req reviewRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request payload", Code: CODE_INVALID_REQUEST})
+        return
+    }
+
+    flag, err := h.bank.ReviewFlag(id, req.Status, strings.TrimSpace(req.Note))
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    writeJSON(w, http.StatusOK, flagView(flag))
+}
+
+// List an account's transactions, oldest first, filtered by ?from= and ?to= (YYYY-MM-DD),
+// ?type= (comma-separated) and ?min= and ?max= amounts
+func (h *BankHandler) listTransactions(w http.ResponseWriter, r *http.Request) {
+    account, ok := h.account(w, r)
+    if !ok {
+        return
+    }
+
+    filter, err := transactionFilter(r)
+    if err != nil {
+        writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error(), Code: CODE_INVALID_REQUEST})
+        return
+    }
+
+    transactions, err := h.bank.Transactions(account.ID, filter)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    views := make([]transactionResponse, 0, len(transactions))
+    for _, t := range transactions {
+        views = append(views, transactionView(t))
+    }
+    writeJSON(w, http.StatusOK, views)
+}
+
+// Report the trial balance of the journal
+func (h *BankHandler) trialBalance(w http.ResponseWriter, r *http.Request) {
+    tb, err := h.bank.TrialBalance()
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    response := trialBalanceResponse{
+        Ledgers:      make([]trialBalanceRow, 0, len(tb.Rows)),
+        TotalDebits:  tb.TotalDebits.Decimal(),
+        TotalCredits: tb.TotalCredits.Decimal(),
+        Balanced:     tb.Balanced(),
+    }
+    for _, row := range tb.Rows {
+        response.Ledgers = append(response.Ledgers, trialBalanceRow{
+            Ledger:  row.Ledger,
+            Debits:  row.Debits.Decimal(),
+            Credits: row.Credits.Decimal(),
+            Balance: row.Balance.Decimal(),
+        })
+    }
+    writeJSON(w, http.StatusOK, response)
+}
+
+// account looks up the {id} path variable, writing an error response if it is not a number
+// or no such account exists. Closed accounts are included
+func (h *BankHandler) account(w http.ResponseWriter, r *http.Request) (*Account, bool) {
+    id, err := strconv.Atoi(r.PathValue("id"))
+    if err != nil {
+        writeJSON(w, http.StatusBadRequest, errorResponse{Error: "account ID must be a number", Code: CODE_INVALID_REQUEST})
+        return nil, false
+    }
+
+    account, err := h.bank.GetAccount(id)
+    if err != nil {
+        writeError(w, err)
+        return nil, false
+    }
+    return account, true
+}
+
+// accountView describes an account as it stands now
+func (h *BankHandler) accountView(a *Account) accountResponse {
+    balance, _ := h.bank.Balance(a.ID)
+    terms := a.AccountTerms()
+    view := accountResponse{
+        ID:           a.ID,
+        Name:         a.Name,
+        Type:         terms.Type,
+        Balance:      balance.Decimal(),
+        Currency:     balance.Currency,
+        InterestRate: formatRate(terms.AnnualRateBP),
+        OpenedAt:     a.OpenedAt,
+        Closed:       a.IsClosed(),
+    }
+    if terms.OverdraftLimit.IsPositive() {
+        view.OverdraftLimit = terms.OverdraftLimit.Decimal()
+    }
+    if until := a.LockedUntil(); !until.IsZero() {
+        view.LockedUntil = until.Format("2006-01-02")
+    }
+    if today, err := h.bank.WithdrawnToday(a.ID); err == nil {
+        view.WithdrawnToday = today.Decimal()
+    }
+    limits := a.WithdrawalLimits()
+    if limits.PerTransaction.IsPositive() {
+        view.PerTransactionLimit = limits.PerTransaction.Decimal()
+    }
+    if limits.Daily.IsPositive() {
+        view.DailyLimit = limits.Daily.Decimal()
+    }
+    return view
+}
+
+// postingView describes a transaction just posted to an account, with its flag if it was
+// queued for review
+func (h *BankHandler) postingView(a *Account, t Transaction) postingResponse {
+    response := postingResponse{Account: h.accountView(a), Transaction: transactionView(t)}
+    if flag, ok := h.bank.FlagForTransaction(t.ID); ok {
+        view := flagView(flag)
+        response.Flag = &view
+    }
+    return response
+}
+
+// flagView describes a flagged transaction
+func flagView(f FlaggedTransaction) flagResponse {
+    view := flagResponse{
+        ID:            f.ID,
+        AccountID:     f.AccountID,
+        TransactionID: f.TransactionID,
+        Type:          f.Type,
+        Amount:        f.Amount.Decimal(),
+        Currency:      f.Amount.Currency,
+        Action:        f.Action,
+        Reasons:       f.Reasons,
+        FlaggedAt:     f.FlaggedAt,
+        Status:        f.Status,
+        Note:          f.Note,
+    }
+    if !f.ReviewedAt.IsZero() {
+        view.ReviewedAt = &f.ReviewedAt
+    }
+    return view
+}
+
+// transactionView describes a transaction
+func transactionView(t Transaction) transactionResponse {
+    return transactionResponse{
+        ID:           t.ID,
+        Type:         t.Type,
+        Amount:       t.Amount.Decimal(),
+        BalanceAfter: t.BalanceAfter.Decimal(),
+        Currency:     t.Amount.Currency,
+        Timestamp:    t.Timestamp,
+        Description:  t.Description,
+        Counterparty: t.Counterparty,
+        Reference:    t.Reference,
+    }
+}
+
+// parseAmount reads a request amount, writing a 400 response if it is missing or malformed
+func parseAmount(w http.ResponseWriter, value json.Number) (Money, bool) {
+    if value == "" {
+        writeJSON(w, http.StatusBadRequest, errorResponse{Error: "amount is required", Code: CODE_INVALID_REQUEST})
+        return Money{}, false
+    }
+    amount, err := ParseMoney(value.String(), CURRENCY_INR)
+    if err != nil {
+        writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error(), Code: CODE_INVALID_REQUEST})
+        return Money{}, false
+    }
+    return amount, true
+}
+
+// parseLimit reads a withdrawal limit, where a missing one means no limit
+func parseLimit(value json.Number) (Money, error) {
+    if value == "" {
+        return INR(0), nil
+    }
+    return ParseMoney(value.String(), CURRENCY_INR)
+}
+
+// transactionFilter reads the transaction search criteria from the query string
+func transactionFilter(r *http.Request) (TransactionFilter, error) {
+    var filter TransactionFilter
+    query := r.URL.Query()
+
+    if value := query.Get("from"); value != "" {
+        from, err := time.ParseInLocation("2006-01-02", value, time.Local)
+        if err != nil {
+            return filter, errors.New("from must be YYYY-MM-DD")
+        }
+        filter.From = from
+    }
+    if value := query.Get("to"); value != "" {
+        to, err := time.ParseInLocation("2006-01-02", value, time.Local)
+        if err != nil {
+            return filter, errors.New("to must be YYYY-MM-DD")
+        }
+        filter.To = to.AddDate(0, 0, 1).Add(-time.Nanosecond)
+    }
+    if value := query.Get("type"); value != "" {
+        filter.Types = strings.Split(value, ",")
+    }
+
+    var err error
+    if value := query.Get("min"); value != "" {
+        if filter.MinAmount, err = ParseMoney(value, CURRENCY_INR); err != nil {
+            return filter, err
+        }
+    }
+    if value := query.Get("max"); value != "" {
+        if filter.MaxAmount, err = ParseMoney(value, CURRENCY_INR); err != nil {
+            return filter, err
+        }
+    }
+    return filter, nil
+}
+
+// statusForError maps BankSystem errors to HTTP status codes and error codes
+func statusForError(err error) (int, string) {
+    switch {
+    case errors.Is(err, ErrAccountNotFound):
+        return http.StatusNotFound, CODE_ACCOUNT_NOT_FOUND
+    case errors.Is(err, ErrInsufficientBalance), errors.Is(err, ErrAccountOverdrawn):
+        return http.StatusUnprocessableEntity, CODE_INSUFFICIENT_BALANCE
+    case errors.Is(err, ErrAccountClosed):
+        return http.StatusConflict, CODE_ACCOUNT_CLOSED
+    case errors.Is(err, ErrAccountLocked):
+        return http.StatusConflict, CODE_ACCOUNT_LOCKED
+    case errors.Is(err, ErrDuplicateAccount):
+        return http.StatusConflict, CODE_ACCOUNT_EXISTS
+    case errors.Is(err, ErrWithdrawalLimitExceeded):
+        return http.StatusUnprocessableEntity, CODE_LIMIT_EXCEEDED
+    case errors.Is(err, ErrTransactionBlocked):
+        return http.StatusForbidden, CODE_TRANSACTION_BLOCKED
+    case errors.Is(err, ErrFlagNotFound):
+        return http.StatusNotFound, CODE_FLAG_NOT_FOUND
+    case errors.Is(err, ErrFlagReviewed):
+        return http.StatusConflict, CODE_FLAG_REVIEWED
+    case errors.Is(err, ErrIdempotencyKeyReused):
+        return http.StatusUnprocessableEntity, CODE_IDEMPOTENCY_KEY_REUSED
+    case errors.Is(err, ErrInvalidAmount), errors.Is(err, ErrSameAccount), errors.Is(err, ErrUnknownAccountType),
+        errors.Is(err, ErrInvalidReview):
+        return http.StatusBadRequest, CODE_INVALID_REQUEST
+    default:
+        return http.StatusInternalServerError, CODE_INTERNAL_ERROR
+    }
+}
+
+// writeError writes err as a JSON error body with the matching status code
+func writeError(w http.ResponseWriter, err error) {
+    status, code := statusForError(err)
+    writeJSON(w, status, errorResponse{Error: err.Error(), Code: code})
+}
+
+// writeJSON writes v as the JSON response body with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(v)
+}
+
+// loggingMiddleware logs the method and path of every request
+func loggingMiddleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        log.Printf("%s %s", r.Method, r.URL.Path)
+        next.ServeHTTP(w, r)
+    })
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+// newAPITestServer serves a bank whose account 1 holds Rs. 1000 and account 2 nothing
+func newAPITestServer(t *testing.T) (*BankSystem, *http.ServeMux) {
+    t.Helper()
+    bs := newTestBank(t, 100000, 0)
+    mux := http.NewServeMux()
+    NewBankHandler(bs).RegisterRoutes(mux)
+    return bs, mux
+}
+
+// apiRequest sends a request through mux, with an Idempotency-Key if key is set
+func apiRequest(mux *http.ServeMux, method string, path string, body string, key string) *httptest.ResponseRecorder {
+    r := httptest.NewRequest(method, path, strings.NewReader(body))
+    if key != "" {
+        r.Header.Set(IDEMPOTENCY_HEADER, key)
+    }
+    w := httptest.NewRecorder()
+    mux.ServeHTTP(w, r)
+    return w
+}
+
+// decodeBody decodes a JSON response body into v
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+    t.Helper()
+    if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+        t.Fatalf("decoding %q: %v", w.Body.String(), err)
+    }
+}
+
+func TestAPIStatusCodes(t *testing.T) {
+    tests := []struct {
+        name     string
+        method   string
+        path     string
+        body     string
+        want     int
+        wantCode string
+    }{
+        {"get", "GET", "/accounts/1", "", http.StatusOK, ""},
+        {"get unknown", "GET", "/accounts/99", "", http.StatusNotFound, CODE_ACCOUNT_NOT_FOUND},
+        {"get bad id", "GET", "/accounts/abc", "", http.StatusBadRequest, CODE_INVALID_REQUEST},
+        {"create", "POST", "/accounts", `{"name":"Meera Iyer"}`, http.StatusCreated, ""},
+        {"create bad body", "POST", "/accounts", `{"name":`, http.StatusBadRequest, CODE_INVALID_REQUEST},
+        {"create without name", "POST", "/accounts", `{"name":"  "}`, http.StatusBadRequest, CODE_INVALID_REQUEST},
+        {"create duplicate id", "POST", "/accounts", `{"id":1,"name":"Meera Iyer"}`, http.StatusConflict, CODE_ACCOUNT_EXISTS},
+        {"create unknown type", "POST", "/accounts", `{"name":"Meera Iyer","type":"CRYPTO"}`, http.StatusBadRequest, CODE_INVALID_REQUEST},
+        {"deposit to unknown account", "POST", "/accounts/99/deposit", `{"amount":"10"}`, http.StatusNotFound, CODE_ACCOUNT_NOT_FOUND},
+        {"withdraw more than the balance", "POST", "/accounts/1/withdraw", `{"amount":"1000.01"}`, http.StatusUnprocessableEntity, CODE_INSUFFICIENT_BALANCE},
+        {"transfer from unknown account", "POST", "/transfers", `{"from":99,"to":1,"amount":"10"}`, http.StatusNotFound, CODE_ACCOUNT_NOT_FOUND},
+        {"transfer more than the balance", "POST", "/transfers", `{"from":2,"to":1,"amount":"10"}`, http.StatusUnprocessableEntity, CODE_INSUFFICIENT_BALANCE},
+        {"transfer to itself", "POST", "/transfers", `{"from":1,"to":1,"amount":"10"}`, http.StatusBadRequest, CODE_INVALID_REQUEST},
+        {"amount missing", "POST", "/accounts/1/deposit", `{}`, http.StatusBadRequest, CODE_INVALID_REQUEST},
+        {"amount not a number", "POST", "/accounts/1/deposit", `{"amount":"ten"}`, http.StatusBadRequest, CODE_INVALID_REQUEST},
+        {"amount with three decimals", "POST", "/accounts/1/deposit", `{"amount":"10.005"}`, http.StatusBadRequest, CODE_INVALID_REQUEST},
+        {"amount zero", "POST", "/accounts/1/withdraw", `{"amount":"0"}`, http.StatusBadRequest, CODE_INVALID_REQUEST},
+        {"amount negative", "POST", "/transfers", `{"from":1,"to":2,"amount":"-5"}`, http.StatusBadRequest, CODE_INVALID_REQUEST},
+        {"deposit overflowing the balance", "POST", "/accounts/1/deposit", `{"amount":"92233720368547758.07"}`, http.StatusBadRequest, CODE_INVALID_REQUEST},
+        {"transactions", "GET", "/accounts/1/transactions?from=2024-01-01&type=deposit&min=1", "", http.StatusOK, ""},
+        {"transactions bad from", "GET", "/accounts/1/transactions?from=01-01-2024", "", http.StatusBadRequest, CODE_INVALID_REQUEST},
+        {"transactions bad to", "GET", "/accounts/1/transactions?to=yesterday", "", http.StatusBadRequest, CODE_INVALID_REQUEST},
+        {"transactions bad min", "GET", "/accounts/1/transactions?min=abc", "", http.StatusBadRequest, CODE_INVALID_REQUEST},
+        {"transactions bad max", "GET", "/accounts/1/transactions?max=1.001", "", http.StatusBadRequest, CODE_INVALID_REQUEST},
+        {"limits bad amount", "PUT", "/accounts/1/limits", `{"daily":"1.001"}`, http.StatusBadRequest, CODE_INVALID_REQUEST},
+        {"limits negative", "PUT", "/accounts/1/limits", `{"daily":"-5"}`, http.StatusBadRequest, CODE_INVALID_REQUEST},
+        {"review unknown flag", "POST", "/flags/9/review", `{"status":"CLEARED"}`, http.StatusNotFound, CODE_FLAG_NOT_FOUND},
+        {"trial balance", "GET", "/trial-balance", "", http.StatusOK, ""},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, mux := newAPITestServer(t)
+            w := apiRequest(mux, tt.method, tt.path, tt.body, "")
+            if w.Code != tt.want {
+                t.Fatalf("%s %s = %d, want %d: %s", tt.method, tt.path, w.Code, tt.want, w.Body.String())
+            }
+            if tt.wantCode == "" {
+                return
+            }
+            var body errorResponse
+            decodeBody(t, w, &body)
+            if body.Code != tt.wantCode || body.Error == "" {
+                t.Errorf("%s %s returned %+v, want code %s with a message", tt.method, tt.path, body, tt.wantCode)
+            }
+        })
+    }
+}
+
+func TestAPICreateAndGetAccount(t *testing.T) {
+    _, mux := newAPITestServer(t)
+
+    w := apiRequest(mux, "POST", "/accounts", `{"name":" Meera Iyer ","type":"current"}`, "")
+    if w.Code != http.StatusCreated {
+        t.Fatalf("create = %d: %s", w.Code, w.Body.String())
+    }
+    var created accountResponse
+    decodeBody(t, w, &created)
+    if created.ID != 3 || created.Name != "Meera Iyer" || created.Type != CURRENT_ACCOUNT ||
+        created.Balance != "0.00" || created.Currency != CURRENCY_INR || created.OverdraftLimit != "10000.00" || created.Closed {
+        t.Errorf("created account = %+v, want current account 3 for Meera Iyer with nothing in it", created)
+    }
+
+    w = apiRequest(mux, "GET", "/accounts/3", "", "")
+    var fetched accountResponse
+    decodeBody(t, w, &fetched)
+    if w.Code != http.StatusOK || fetched != created {
+        t.Errorf("GET /accounts/3 = %d %+v, want %+v", w.Code, fetched, created)
+    }
+
+    w = apiRequest(mux, "GET", "/accounts", "", "")
+    var all []accountResponse
+    decodeBody(t, w, &all)
+    if len(all) != 3 || all[0].Balance != "1000.00" {
+        t.Errorf("GET /accounts = %+v, want 3 accounts starting with account 1 holding 1000.00", all)
+    }
+}
+
+func TestAPIPostings(t *testing.T) {
+    bs, mux := newAPITestServer(t)
+
+    w := apiRequest(mux, "POST", "/accounts/1/deposit", `{"amount":"250.50"}`, "")
+    var deposit postingResponse
+    decodeBody(t, w, &deposit)
+    if w.Code != http.StatusOK || deposit.Transaction.Type != DEPOSIT_TYPE || deposit.Transaction.Amount != "250.50" ||
+        deposit.Transaction.BalanceAfter != "1250.50" || deposit.Account.Balance != "1250.50" {
+        t.Errorf("deposit = %d %+v, want 250.50 deposited leaving 1250.50", w.Code, deposit)
+    }
+
+    // Amounts may be sent as JSON numbers as well as strings
+    w = apiRequest(mux, "POST", "/accounts/1/withdraw", `{"amount":50}`, "")
+    var withdrawal postingResponse
+    decodeBody(t, w, &withdrawal)
+    if w.Code != http.StatusOK || withdrawal.Transaction.Type != WITHDRAW_TYPE || withdrawal.Transaction.BalanceAfter != "1200.50" {
+        t.Errorf("withdrawal = %d %+v, want 50.00 withdrawn leaving 1200.50", w.Code, withdrawal)
+    }
+
+    w = apiRequest(mux, "POST", "/transfers", `{"from":1,"to":2,"amount":"200.25"}`, "")
+    var transfer postingResponse
+    decodeBody(t, w, &transfer)
+    if w.Code != http.StatusOK || transfer.Transaction.Type != TRANSFER_OUT_TYPE || transfer.Transaction.Counterparty != 2 ||
+        !strings.HasPrefix(transfer.Transaction.Reference, "TRF") || transfer.Account.ID != 1 || transfer.Account.Balance != "1000.25" {
+        t.Errorf("transfer = %d %+v, want 200.25 sent to account 2 leaving 1000.25", w.Code, transfer)
+    }
+    wantBalances(t, bs, 100025, 20025)
+
+    w = apiRequest(mux, "GET", "/accounts/2/transactions?type=transfer_in", "", "")
+    var received []transactionResponse
+    decodeBody(t, w, &received)
+    if len(received) != 1 || received[0].Reference != transfer.Transaction.Reference || received[0].Amount != "200.25" {
+        t.Errorf("account 2 transfers in = %+v, want the one carrying %s", received, transfer.Transaction.Reference)
+    }
+
+    w = apiRequest(mux, "GET", "/accounts/1/transactions?min=100&max=300", "", "")
+    var between []transactionResponse
+    decodeBody(t, w, &between)
+    if len(between) != 2 || between[0].Amount != "250.50" || between[1].Amount != "200.25" {
+        t.Errorf("account 1 transactions from 100 to 300 = %+v, want the deposit and the transfer", between)
+    }
+}
+
+func TestAPIIdempotencyKey(t *testing.T) {
+    bs, mux := newAPITestServer(t)
+
+    first := apiRequest(mux, "POST", "/transfers", `{"from":1,"to":2,"amount":"100"}`, "transfer-1")
+    replay := apiRequest(mux, "POST", "/transfers", `{"from":1,"to":2,"amount":"100"}`, "transfer-1")
+    if first.Code != http.StatusOK || replay.Code != http.StatusOK {
+        t.Fatalf("transfer and its replay = %d and %d: %s", first.Code, replay.Code, replay.Body.String())
+    }
+    if replay.Body.String() != first.Body.String() || replay.Header().Get("Idempotent-Replayed") != "true" {
+        t.Errorf("replay = %s (replayed %q), want the original response %s",
+            replay.Body.String(), replay.Header().Get("Idempotent-Replayed"), first.Body.String())
+    }
+    wantBalances(t, bs, 90000, 10000)
+
+    // The same key for a different body is refused without moving money
+    w := apiRequest(mux, "POST", "/transfers", `{"from":1,"to":2,"amount":"500"}`, "transfer-1")
+    var body errorResponse
+    decodeBody(t, w, &body)
+    if w.Code != http.StatusUnprocessableEntity || body.Code != CODE_IDEMPOTENCY_KEY_REUSED {
+        t.Errorf("reused key = %d %+v, want %d %s", w.Code, body, http.StatusUnprocessableEntity, CODE_IDEMPOTENCY_KEY_REUSED)
+    }
+    // So is the same body sent to another endpoint
+    w = apiRequest(mux, "POST", "/accounts/1/deposit", `{"from":1,"to":2,"amount":"100"}`, "transfer-1")
+    if w.Code != http.StatusUnprocessableEntity {
+        t.Errorf("key reused on another endpoint = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+    }
+    wantBalances(t, bs, 90000, 10000)
+
+    // Rejections are replayed too, rather than tried again
+    failed := apiRequest(mux, "POST", "/accounts/2/withdraw", `{"amount":"500"}`, "withdraw-1")
+    if _, err := bs.Deposit(2, INR(100000)); err != nil {
+        t.Fatal(err)
+    }
+    retried := apiRequest(mux, "POST", "/accounts/2/withdraw", `{"amount":"500"}`, "withdraw-1")
+    if failed.Code != http.StatusUnprocessableEntity || retried.Code != failed.Code || retried.Body.String() != failed.Body.String() {
+        t.Errorf("retried withdrawal = %d %s, want the original %d %s", retried.Code, retried.Body.String(), failed.Code, failed.Body.String())
+    }
+    wantBalances(t, bs, 90000, 110000)
+
+    // Without a key every request is performed
+    apiRequest(mux, "POST", "/accounts/1/deposit", `{"amount":"1"}`, "")
+    apiRequest(mux, "POST", "/accounts/1/deposit", `{"amount":"1"}`, "")
+    wantBalances(t, bs, 90200, 110000)
+}
@@ -0,0 +1,23 @@
+package main
+
+import "errors"
+
+// Sentinel errors wrapped by BankSystem so callers can use errors.Is
+var (
+    ErrAccountNotFound         = errors.New("not found")
+    ErrDuplicateAccount        = errors.New("already exists")
+    ErrAccountClosed           = errors.New("is closed")
+    ErrAccountLocked           = errors.New("is locked in")
+    ErrAccountOverdrawn        = errors.New("is overdrawn")
+    ErrInsufficientBalance     = errors.New("insufficient balance")
+    ErrInvalidAmount           = errors.New("amount must be greater than zero")
+    ErrSameAccount             = errors.New("cannot transfer to the same account")
+    ErrUnknownAccountType      = errors.New("unknown account type")
+    ErrTransactionLog          = errors.New("failed to write transaction log")
+    ErrIdempotencyKeyReused    = errors.New("idempotency key was already used for a different request")
+    ErrWithdrawalLimitExceeded = errors.New("withdrawal limit exceeded")
+    ErrTransactionBlocked      = errors.New("transaction blocked for review")
+    ErrFlagNotFound            = errors.New("not found")
+    ErrFlagReviewed            = errors.New("was already reviewed")
+    ErrInvalidReview           = errors.New("invalid review decision")
+)
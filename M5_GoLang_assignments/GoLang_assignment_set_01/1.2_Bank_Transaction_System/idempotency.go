@@ -0,0 +1,177 @@
+package main
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "encoding/hex"
+    "io"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// Header a client sets to make a money-moving request safe to retry
+const IDEMPOTENCY_HEADER = "Idempotency-Key"
+
+// How long the response to an idempotent request is remembered
+const IDEMPOTENCY_TTL = 24 * time.Hour
+
+// IdempotencyStore remembers the response to each request made with an Idempotency-Key, so a
+// retried request gets the original response instead of moving the money a second time.
+// Responses are kept in memory, so keys are forgotten when the server restarts
+type IdempotencyStore struct {
+    mu      sync.Mutex
+    ttl     time.Duration
+    entries map[string]*idempotentResponse
+}
+
+// idempotentResponse is the response to one key. done is closed once the first request
+// with the key has finished; stored is false if it failed and the key was released
+type idempotentResponse struct {
+    fingerprint string
+    created     time.Time
+    done        chan struct{}
+    stored      bool
+    status      int
+    header      http.Header
+    body        []byte
+}
+
+// responseRecorder captures a response so it can be stored before it is sent
+type responseRecorder struct {
+    header http.Header
+    status int
+    body   bytes.Buffer
+}
+
+// NewIdempotencyStore creates a store that remembers responses for ttl
+func NewIdempotencyStore(ttl time.Duration) *IdempotencyStore {
+    return &IdempotencyStore{ttl: ttl, entries: make(map[string]*idempotentResponse)}
+}
+
+// begin looks up key. If no request has used it, it is reserved and the caller must call
+// finish; otherwise the existing entry is returned to wait on. Reusing a key for a
+// different request is an error
+func (s *IdempotencyStore) begin(key string, fingerprint string) (*idempotentResponse, bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    now := time.Now()
+    for k, entry := range s.entries {
+        if entry.stored && now.Sub(entry.created) > s.ttl {
+            delete(s.entries, k)
+        }
+    }
+
+    if entry, ok := s.entries[key]; ok {
+        if entry.fingerprint != fingerprint {
+            return nil, false, ErrIdempotencyKeyReused
+        }
+        return entry, false, nil
+    }
+
+    entry := &idempotentResponse{fingerprint: fingerprint, created: now, done: make(chan struct{})}
+    s.entries[key] = entry
+    return entry, true, nil
+}
+
+// finish stores the response to a reserved key. Server errors are not stored, so the
+// client can retry them
+func (s *IdempotencyStore) finish(key string, entry *idempotentResponse, rec *responseRecorder) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if rec.status >= http.StatusInternalServerError {
+        delete(s.entries, key)
+    } else {
+        entry.stored = true
+        entry.status = rec.status
+        entry.header = rec.header.Clone()
+        entry.body = append([]byte(nil), rec.body.Bytes()...)
+    }
+    close(entry.done)
+}
+
+// idempotent wraps a handler so requests carrying an Idempotency-Key are performed at most
+// once. A retry with the same key and body gets the stored response with an
+// Idempotent-Replayed header; a retry while the first is still running waits for it
+func (s *IdempotencyStore) idempotent(next http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        key := r.Header.Get(IDEMPOTENCY_HEADER)
+        if key == "" {
+            next(w, r)
+            return
+        }
+
+        body, err := io.ReadAll(r.Body)
+        if err != nil {
+            writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request payload", Code: CODE_INVALID_REQUEST})
+            return
+        }
+        r.Body = io.NopCloser(bytes.NewReader(body))
+        sum := sha256.Sum256(body)
+        fingerprint := r.Method + " " + r.URL.Path + " " + hex.EncodeToString(sum[:])
+
+        for {
+            entry, owner, err := s.begin(key, fingerprint)
+            if err != nil {
+                writeError(w, err)
+                return
+            }
+
+            if owner {
+                s.serve(key, entry, next, w, r)
+                return
+            }
+
+            <-entry.done
+            if entry.stored {
+                w.Header().Set("Idempotent-Replayed", "true")
+                replay := &responseRecorder{status: entry.status}
+                replay.body.Write(entry.body)
+                replay.writeTo(w, entry.header)
+                return
+            }
+            // The first attempt failed and released the key, so try again
+        }
+    }
+}
+
+// serve runs next for the request holding a reserved key and stores its response. If next
+// panics the key is released as if it had failed, so retries waiting on it are not stuck
+func (s *IdempotencyStore) serve(key string, entry *idempotentResponse, next http.HandlerFunc, w http.ResponseWriter, r *http.Request) {
+    rec := &responseRecorder{header: make(http.Header), status: http.StatusOK}
+    completed := false
+    defer func() {
+        if !completed {
+            rec.status = http.StatusInternalServerError
+            s.finish(key, entry, rec)
+        }
+    }()
+
+    next(rec, r)
+    completed = true
+    s.finish(key, entry, rec)
+    rec.writeTo(w, rec.header)
+}
+
+func (rec *responseRecorder) Header() http.Header {
+    return rec.header
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+    return rec.body.Write(b)
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+    rec.status = status
+}
+
+// writeTo sends the recorded response to w with the given headers
+func (rec *responseRecorder) writeTo(w http.ResponseWriter, header http.Header) {
+    for name, values := range header {
+        w.Header()[name] = values
+    }
+    w.WriteHeader(rec.status)
+    w.Write(rec.body.Bytes())
+}
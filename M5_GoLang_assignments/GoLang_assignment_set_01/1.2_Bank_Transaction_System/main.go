@@ -2,11 +2,19 @@ package main
 
 import (
     "bufio"
+    "context"
     "errors"
+    "flag"
     "fmt"
+    "log"
+    "net/http"
     "os"
+    "os/signal"
     "strconv"
     "strings"
+    "sync"
+    "sync/atomic"
+    "syscall"
     "time"
 )
 
@@ -14,126 +22,584 @@ import (
 const (
     DEPOSIT          = 1
     WITHDRAW         = 2
-    CHECK_BALANCE    = 3
-    VIEW_HISTORY     = 4
-    EXIT            = 5
+    TRANSFER         = 3
+    CHECK_BALANCE    = 4
+    VIEW_HISTORY     = 5
+    MINI_STATEMENT   = 6
+    FULL_STATEMENT   = 7
+    SEARCH_HISTORY   = 8
+    CREATE_ACCOUNT   = 9
+    SWITCH_ACCOUNT   = 10
+    LIST_ACCOUNTS    = 11
+    CLOSE_ACCOUNT    = 12
+    TRIAL_BALANCE    = 13
+    END_OF_DAY       = 14
+    SET_LIMITS       = 15
+    REVIEW_FLAGS     = 16
+    EXIT            = 17
 )
 
+// Number of transactions shown on a mini statement
+const MINI_STATEMENT_SIZE = 5
+
 // Transaction types
 const (
-    DEPOSIT_TYPE    = "DEPOSIT"
-    WITHDRAW_TYPE   = "WITHDRAW"
+    DEPOSIT_TYPE        = "DEPOSIT"
+    WITHDRAW_TYPE       = "WITHDRAW"
+    TRANSFER_OUT_TYPE   = "TRANSFER_OUT"
+    TRANSFER_IN_TYPE    = "TRANSFER_IN"
+    INTEREST_TYPE       = "INTEREST"
+    PENALTY_TYPE        = "PENALTY"
 )
 
-// Account represents a bank account
+// Account represents a bank account. Its balance is held in the journal; mu guards
+// Transactions, Accrual, Limits and Closed, and serialises every posting to the account.
+// mu is only taken while holding BankSystem.mu, and several at once in ID order
 type Account struct {
+    mu              sync.Mutex
     ID              int
     Name            string
-    Balance         float64
-    Transactions    []string
+    Terms           AccountTerms
+    OpenedAt        time.Time
+    Accrual         InterestAccrual
+    Limits          WithdrawalLimits
+    Transactions    []Transaction
+    Closed          bool
 }
 
-// BankSystem manages all bank operations
+// BankSystem manages all bank operations and is safe for concurrent use. mu guards
+// accounts, terms and fraudRules: every operation holds it for reading, and adding an
+// account, reviewing a flag or taking a snapshot holds it for writing. flagMu guards flags
 type BankSystem struct {
+    mu              sync.RWMutex
     accounts        []*Account
     scanner         *bufio.Scanner
+    journal         *Journal
+    // terms are the terms new accounts are opened with, by account type
+    terms           map[string]AccountTerms
+    // fraudRules screen every withdrawal and outgoing transfer
+    fraudRules      []FraudRule
+    flagMu          sync.Mutex
+    // flags is the review queue of flagged and blocked withdrawals, oldest first
+    flags           []FlaggedTransaction
+    lastTransferID     atomic.Int64
+    lastTransactionID  atomic.Int64
+    lastJournalID      atomic.Int64
+    lastFlagID         atomic.Int64
+    // wal is nil for a purely in-memory bank
+    wal             *WriteAheadLog
+    dataDir         string
+    snapshotEvery   int
 }
 
 // NewBankSystem creates a new instance of BankSystem
 func NewBankSystem() *BankSystem {
     return &BankSystem{
-        accounts: make([]*Account, 0),
-        scanner:  bufio.NewScanner(os.Stdin),
+        accounts:   make([]*Account, 0),
+        scanner:    bufio.NewScanner(os.Stdin),
+        journal:    NewJournal(CURRENCY_INR),
+        terms:      DefaultAccountTerms(),
+        fraudRules: DefaultFraudRules(),
+        flags:      make([]FlaggedTransaction, 0),
     }
 }
 
-// CreateAccount creates a new bank account
-func (bs *BankSystem) CreateAccount(id int, name string) (*Account, error) {
+// CreateAccount opens a new bank account of the given type under that type's current terms.
+// An id of 0 opens it under the next free ID
+func (bs *BankSystem) CreateAccount(id int, name string, accountType string) (*Account, error) {
+    defer bs.maybeCheckpoint()
+
+    bs.mu.Lock()
+    defer bs.mu.Unlock()
+
+    terms, err := bs.termsFor(accountType)
+    if err != nil {
+        return nil, err
+    }
+
+    if id == 0 {
+        id = bs.nextAccountID()
+    }
+
     // Check for duplicate ID
     for _, acc := range bs.accounts {
         if acc.ID == id {
-            return nil, fmt.Errorf("account with ID %d already exists", id)
+            return nil, fmt.Errorf("account with ID %d %w", id, ErrDuplicateAccount)
         }
     }
 
-    account := &Account{
-        ID:           id,
-        Name:         name,
-        Balance:      0,
-        Transactions: make([]string, 0),
+    if err := bs.commit(walRecord{Op: WAL_CREATE_ACCOUNT, AccountID: id, Name: name, Terms: &terms}); err != nil {
+        return nil, err
+    }
+    return bs.findAnyAccount(id)
+}
+
+// NextAccountID returns the lowest ID greater than every existing account's
+func (bs *BankSystem) NextAccountID() int {
+    bs.mu.RLock()
+    defer bs.mu.RUnlock()
+    return bs.nextAccountID()
+}
+
+// nextAccountID returns the next free account ID. Callers must hold bs.mu
+func (bs *BankSystem) nextAccountID() int {
+    next := 1
+    for _, acc := range bs.accounts {
+        if acc.ID >= next {
+            next = acc.ID + 1
+        }
     }
-    
-    bs.accounts = append(bs.accounts, account)
-    return account, nil
+    return next
 }
 
-// FindAccount finds an account by ID
+// FindAccount finds an open account by ID
 func (bs *BankSystem) FindAccount(id int) (*Account, error) {
+    bs.mu.RLock()
+    defer bs.mu.RUnlock()
+    return bs.findOpenAccount(id)
+}
+
+// GetAccount finds an account by ID, including closed ones
+func (bs *BankSystem) GetAccount(id int) (*Account, error) {
+    bs.mu.RLock()
+    defer bs.mu.RUnlock()
+    return bs.findAnyAccount(id)
+}
+
+// findOpenAccount finds an open account by ID. Callers must hold bs.mu
+func (bs *BankSystem) findOpenAccount(id int) (*Account, error) {
+    acc, err := bs.findAnyAccount(id)
+    if err != nil {
+        return nil, err
+    }
+
+    acc.mu.Lock()
+    closed := acc.Closed
+    acc.mu.Unlock()
+
+    if closed {
+        return nil, fmt.Errorf("account with ID %d %w", id, ErrAccountClosed)
+    }
+    return acc, nil
+}
+
+// findAnyAccount finds an account by ID, including closed ones. Callers must hold bs.mu
+func (bs *BankSystem) findAnyAccount(id int) (*Account, error) {
     for _, acc := range bs.accounts {
         if acc.ID == id {
             return acc, nil
         }
     }
-    return nil, fmt.Errorf("account with ID %d not found", id)
+    return nil, fmt.Errorf("account with ID %d %w", id, ErrAccountNotFound)
+}
+
+// ListAccounts returns every account, including closed ones, in the order they were opened
+func (bs *BankSystem) ListAccounts() []*Account {
+    bs.mu.RLock()
+    defer bs.mu.RUnlock()
+    return append([]*Account(nil), bs.accounts...)
 }
 
-// Deposit adds money to an account
-func (bs *BankSystem) Deposit(id int, amount float64) error {
-    if amount <= 0 {
-        return errors.New("deposit amount must be greater than zero")
+// IsClosed reports whether the account has been closed
+func (a *Account) IsClosed() bool {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    return a.Closed
+}
+
+// Balance returns the balance of an account, open or closed, as derived from the journal
+func (bs *BankSystem) Balance(id int) (Money, error) {
+    bs.mu.RLock()
+    defer bs.mu.RUnlock()
+
+    if _, err := bs.findAnyAccount(id); err != nil {
+        return Money{}, err
     }
+    return bs.balanceOf(id), nil
+}
 
-    account, err := bs.FindAccount(id)
+// balanceOf returns the journal balance of an account
+func (bs *BankSystem) balanceOf(id int) Money {
+    return bs.journal.Balance(customerLedger(id))
+}
+
+// TrialBalance totals the journal by ledger account to show the books balance
+func (bs *BankSystem) TrialBalance() (*TrialBalance, error) {
+    return bs.journal.TrialBalance()
+}
+
+// CloseAccount closes an account, paying out any remaining balance as a final withdrawal.
+// Closed accounts keep their history but accept no further transactions
+func (bs *BankSystem) CloseAccount(id int) (Money, error) {
+    defer bs.maybeCheckpoint()
+
+    bs.mu.RLock()
+    defer bs.mu.RUnlock()
+
+    account, err := bs.findOpenAccount(id)
     if err != nil {
-        return err
+        return Money{}, err
     }
 
-    account.Balance += amount
-    transaction := fmt.Sprintf("%s: + Rs. %.2f (Balance: Rs.%.2f) - %s",
-        DEPOSIT_TYPE, amount, account.Balance, time.Now().Format("2006-01-02 15:04:05"))
-    account.Transactions = append(account.Transactions, transaction)
-    
-    return nil
+    account.mu.Lock()
+    defer account.mu.Unlock()
+    if account.Closed {
+        return Money{}, fmt.Errorf("account with ID %d %w", id, ErrAccountClosed)
+    }
+
+    if until := account.lockedUntil(); time.Now().Before(until) {
+        return Money{}, fmt.Errorf("fixed deposit %d %w until %s", id, ErrAccountLocked, until.Format("2006-01-02"))
+    }
+
+    // The payout and the closure are logged as one record so recovery never sees half of it
+    record := walRecord{Op: WAL_CLOSE_ACCOUNT, AccountID: id}
+    payout := bs.balanceOf(id)
+    if payout.Minor < 0 {
+        return Money{}, fmt.Errorf("account %d %w by %v", id, ErrAccountOverdrawn, payout.Neg())
+    }
+    if payout.IsPositive() {
+        record.Journal = &JournalEntry{Description: "Account closure payout", Postings: transfer(customerLedger(id), CASH_LEDGER, payout)}
+        record.Entries = append(record.Entries, walEntry{
+            AccountID:   id,
+            Transaction: Transaction{Type: WITHDRAW_TYPE, Amount: payout, BalanceAfter: NewMoney(0, payout.Currency), Description: "Account closure payout"},
+        })
+    }
+    if err := bs.commit(record); err != nil {
+        return Money{}, err
+    }
+    return payout, nil
 }
 
-// Withdraw removes money from an account
-func (bs *BankSystem) Withdraw(id int, amount float64) error {
-    if amount <= 0 {
-        return errors.New("withdrawal amount must be greater than zero")
+// Deposit adds money to an account and returns the transaction recorded
+func (bs *BankSystem) Deposit(id int, amount Money) (Transaction, error) {
+    defer bs.maybeCheckpoint()
+
+    if !amount.IsPositive() {
+        return Transaction{}, fmt.Errorf("deposit %w", ErrInvalidAmount)
     }
 
-    account, err := bs.FindAccount(id)
+    bs.mu.RLock()
+    defer bs.mu.RUnlock()
+
+    account, err := bs.findOpenAccount(id)
+    if err != nil {
+        return Transaction{}, err
+    }
+
+    account.mu.Lock()
+    defer account.mu.Unlock()
+    if account.Closed {
+        return Transaction{}, fmt.Errorf("account with ID %d %w", id, ErrAccountClosed)
+    }
+
+    balance, err := bs.balanceOf(id).Add(amount)
+    if err != nil {
+        return Transaction{}, err
+    }
+
+    // Cash comes into the vault and the bank owes it to the customer
+    journal := JournalEntry{Description: "Cash deposit", Postings: transfer(CASH_LEDGER, customerLedger(id), amount)}
+    return bs.commitPosting(account, postRecord(journal, walEntry{
+        AccountID:   id,
+        Transaction: Transaction{Type: DEPOSIT_TYPE, Amount: amount, BalanceAfter: balance, Description: "Cash deposit"},
+    }))
+}
+
+// Withdraw removes money from an account and returns the transaction recorded
+func (bs *BankSystem) Withdraw(id int, amount Money) (Transaction, error) {
+    defer bs.maybeCheckpoint()
+
+    if !amount.IsPositive() {
+        return Transaction{}, fmt.Errorf("withdrawal %w", ErrInvalidAmount)
+    }
+
+    bs.mu.RLock()
+    defer bs.mu.RUnlock()
+
+    account, err := bs.findOpenAccount(id)
+    if err != nil {
+        return Transaction{}, err
+    }
+
+    account.mu.Lock()
+    defer account.mu.Unlock()
+    if account.Closed {
+        return Transaction{}, fmt.Errorf("account with ID %d %w", id, ErrAccountClosed)
+    }
+
+    balance, err := bs.checkDebit(account, amount)
+    if err != nil {
+        return Transaction{}, err
+    }
+    flag, err := bs.screenDebit(account, WITHDRAW_TYPE, amount)
+    if err != nil {
+        return Transaction{}, err
+    }
+
+    journal := JournalEntry{Description: "Cash withdrawal", Postings: transfer(customerLedger(id), CASH_LEDGER, amount)}
+    record := postRecord(journal, walEntry{
+        AccountID:   id,
+        Transaction: Transaction{Type: WITHDRAW_TYPE, Amount: amount, BalanceAfter: balance, Description: "Cash withdrawal"},
+    })
+    record.Flag = flag
+    return bs.commitPosting(account, record)
+}
+
+// Transfer moves money from one account to another. Either both accounts are updated
+// or neither is, and each records an entry carrying the same transfer reference. It returns
+// the entry recorded on the paying account
+func (bs *BankSystem) Transfer(fromID int, toID int, amount Money) (Transaction, error) {
+    defer bs.maybeCheckpoint()
+
+    if !amount.IsPositive() {
+        return Transaction{}, fmt.Errorf("transfer %w", ErrInvalidAmount)
+    }
+    if fromID == toID {
+        return Transaction{}, ErrSameAccount
+    }
+
+    bs.mu.RLock()
+    defer bs.mu.RUnlock()
+
+    from, err := bs.findOpenAccount(fromID)
     if err != nil {
+        return Transaction{}, err
+    }
+    to, err := bs.findOpenAccount(toID)
+    if err != nil {
+        return Transaction{}, err
+    }
+
+    // Lock in ID order so two opposite transfers can never wait on each other
+    first, second := from, to
+    if second.ID < first.ID {
+        first, second = second, first
+    }
+    first.mu.Lock()
+    defer first.mu.Unlock()
+    second.mu.Lock()
+    defer second.mu.Unlock()
+
+    // Check everything before touching either balance
+    if from.Closed {
+        return Transaction{}, fmt.Errorf("account with ID %d %w", from.ID, ErrAccountClosed)
+    }
+    if to.Closed {
+        return Transaction{}, fmt.Errorf("account with ID %d %w", to.ID, ErrAccountClosed)
+    }
+    fromBalance, err := bs.checkDebit(from, amount)
+    if err != nil {
+        return Transaction{}, err
+    }
+    flag, err := bs.screenDebit(from, TRANSFER_OUT_TYPE, amount)
+    if err != nil {
+        return Transaction{}, err
+    }
+    toBalance, err := bs.balanceOf(to.ID).Add(amount)
+    if err != nil {
+        return Transaction{}, err
+    }
+
+    // Both legs go into one log record, so recovery replays both or neither
+    reference := fmt.Sprintf("TRF%06d", bs.lastTransferID.Add(1))
+    journal := JournalEntry{
+        Description: fmt.Sprintf("Transfer from account %d to account %d", from.ID, to.ID),
+        Reference:   reference,
+        Postings:    transfer(customerLedger(from.ID), customerLedger(to.ID), amount),
+    }
+    record := postRecord(journal,
+        walEntry{
+            AccountID: from.ID,
+            Transaction: Transaction{
+                Type:         TRANSFER_OUT_TYPE,
+                Amount:       amount,
+                BalanceAfter: fromBalance,
+                Description:  fmt.Sprintf("Transfer to account %d", to.ID),
+                Counterparty: to.ID,
+                Reference:    reference,
+            },
+        },
+        walEntry{
+            AccountID: to.ID,
+            Transaction: Transaction{
+                Type:         TRANSFER_IN_TYPE,
+                Amount:       amount,
+                BalanceAfter: toBalance,
+                Description:  fmt.Sprintf("Transfer from account %d", from.ID),
+                Counterparty: from.ID,
+                Reference:    reference,
+            },
+        },
+    )
+    record.Flag = flag
+    return bs.commitPosting(from, record)
+}
+
+// commitPosting commits a record posting to account and returns the transaction it
+// recorded there. Callers must hold the locks commit requires
+func (bs *BankSystem) commitPosting(account *Account, record walRecord) (Transaction, error) {
+    if err := bs.commit(record); err != nil {
+        return Transaction{}, err
+    }
+    return account.Transactions[len(account.Transactions)-1], nil
+}
+
+// commit makes the record durable in the write-ahead log, stamped with IDs and times, and
+// then applies it. A record apply would reject is refused before it reaches the log, since
+// recovery would reject it too and the bank could never be reopened. Callers must hold bs.mu,
+// for writing if the record adds an account, and the lock of every account the record touches
+func (bs *BankSystem) commit(record walRecord) error {
+    if err := bs.check(record); err != nil {
         return err
     }
+    if bs.wal == nil {
+        bs.assignIDs(&record)
+    } else if err := bs.wal.Append(&record, bs.assignIDs); err != nil {
+        return fmt.Errorf("%w: %v", ErrTransactionLog, err)
+    }
+    return bs.apply(record)
+}
+
+// assignIDs stamps a new record's journal entry, transactions and flag with IDs and times.
+// The log calls it under its own lock, so IDs rise in the same order as sequence numbers
+func (bs *BankSystem) assignIDs(record *walRecord) {
+    if record.Timestamp.IsZero() {
+        record.Timestamp = time.Now()
+    }
+    if record.Journal != nil {
+        record.Journal.ID = bs.lastJournalID.Add(1)
+        record.Journal.Timestamp = record.Timestamp
+    }
+    for i := range record.Entries {
+        t := &record.Entries[i].Transaction
+        t.ID = bs.lastTransactionID.Add(1)
+        t.Timestamp = record.Timestamp
+        if record.Journal != nil {
+            t.JournalID = record.Journal.ID
+        }
+    }
+    // A new flag is linked to the entry on the account it screened, which is always the first
+    if record.Flag != nil && record.Op != WAL_REVIEW_FLAG {
+        record.Flag.ID = bs.lastFlagID.Add(1)
+        record.Flag.FlaggedAt = record.Timestamp
+        if len(record.Entries) > 0 {
+            record.Flag.TransactionID = record.Entries[0].Transaction.ID
+        }
+    }
+}
 
-    if account.Balance < amount {
-        return fmt.Errorf("insufficient balance. Current balance: Rs.%.2f", account.Balance)
+// check returns the error apply would fail with, without changing anything. Callers must
+// hold the locks commit requires
+func (bs *BankSystem) check(record walRecord) error {
+    if record.Op == WAL_CREATE_ACCOUNT {
+        return nil
     }
 
-    account.Balance -= amount
-    transaction := fmt.Sprintf("%s: -Rs.%.2f (Balance: Rs.%.2f) - %s",
-        WITHDRAW_TYPE, amount, account.Balance, time.Now().Format("2006-01-02 15:04:05"))
-    account.Transactions = append(account.Transactions, transaction)
-    
+    if record.Journal != nil {
+        if err := bs.journal.validate(*record.Journal); err != nil {
+            return fmt.Errorf("journal entry: %w", err)
+        }
+    }
+    for _, entry := range record.Entries {
+        if _, err := bs.findAnyAccount(entry.AccountID); err != nil {
+            return err
+        }
+    }
+    if record.Accrual != nil || record.Limits != nil || record.Op == WAL_CLOSE_ACCOUNT {
+        if _, err := bs.findAnyAccount(record.AccountID); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// apply performs a logged change in memory. It is used both for new changes, under the locks
+// commit requires, and during recovery
+func (bs *BankSystem) apply(record walRecord) error {
+    if record.Op == WAL_CREATE_ACCOUNT {
+        terms := bs.terms[SAVINGS_ACCOUNT]
+        if record.Terms != nil {
+            terms = *record.Terms
+        }
+        bs.accounts = append(bs.accounts, &Account{
+            ID:           record.AccountID,
+            Name:         record.Name,
+            Terms:        terms,
+            OpenedAt:     record.Timestamp,
+            Limits:       terms.Limits,
+            Transactions: make([]Transaction, 0),
+        })
+        return nil
+    }
+
+    // Balances move only through the journal; the account ledgers record what the customer sees
+    if record.Journal != nil {
+        if err := bs.journal.Post(*record.Journal); err != nil {
+            return err
+        }
+    }
+    for _, entry := range record.Entries {
+        account, err := bs.findAnyAccount(entry.AccountID)
+        if err != nil {
+            return err
+        }
+        account.Transactions = append(account.Transactions, entry.Transaction)
+    }
+
+    if record.Accrual != nil {
+        account, err := bs.findAnyAccount(record.AccountID)
+        if err != nil {
+            return err
+        }
+        account.Accrual = *record.Accrual
+    }
+
+    if record.Limits != nil {
+        account, err := bs.findAnyAccount(record.AccountID)
+        if err != nil {
+            return err
+        }
+        account.Limits = *record.Limits
+    }
+
+    if record.Flag != nil {
+        bs.applyFlag(record)
+    }
+
+    if record.Op == WAL_CLOSE_ACCOUNT {
+        account, err := bs.findAnyAccount(record.AccountID)
+        if err != nil {
+            return err
+        }
+        account.Closed = true
+    }
     return nil
 }
 
 // DisplayTransactionHistory shows all transactions for an account
 func (bs *BankSystem) DisplayTransactionHistory(id int) error {
+    return bs.displayTransactions(id, TransactionFilter{})
+}
+
+// displayTransactions shows the transactions of an account that match filter
+func (bs *BankSystem) displayTransactions(id int, filter TransactionFilter) error {
     account, err := bs.FindAccount(id)
     if err != nil {
         return err
     }
+    transactions, err := bs.Transactions(id, filter)
+    if err != nil {
+        return err
+    }
 
-    if len(account.Transactions) == 0 {
+    if len(transactions) == 0 {
         fmt.Println("No transactions found.")
         return nil
     }
 
     fmt.Printf("\nTransaction History for Account %d (%s):\n", account.ID, account.Name)
     fmt.Println("----------------------------------------")
-    for _, transaction := range account.Transactions {
+    for _, transaction := range transactions {
         fmt.Println(transaction)
     }
     return nil
@@ -145,24 +611,281 @@ func (bs *BankSystem) readInput() string {
     return strings.TrimSpace(bs.scanner.Text())
 }
 
+// readDateRange asks for an optional start and end date. The end date covers the whole day
+func (bs *BankSystem) readDateRange() (time.Time, time.Time, error) {
+    var from, to time.Time
+
+    fmt.Print("From date (YYYY-MM-DD, blank for any): ")
+    if input := bs.readInput(); input != "" {
+        parsed, err := time.ParseInLocation("2006-01-02", input, time.Local)
+        if err != nil {
+            return from, to, fmt.Errorf("invalid date %q", input)
+        }
+        from = parsed
+    }
+
+    fmt.Print("To date (YYYY-MM-DD, blank for any): ")
+    if input := bs.readInput(); input != "" {
+        parsed, err := time.ParseInLocation("2006-01-02", input, time.Local)
+        if err != nil {
+            return from, to, fmt.Errorf("invalid date %q", input)
+        }
+        to = parsed.AddDate(0, 0, 1).Add(-time.Nanosecond)
+    }
+    return from, to, nil
+}
+
+// catchUpInterest runs interest processing for any days the bank was not running
+func (bs *BankSystem) catchUpInterest() {
+    if posted, err := bs.RunEndOfDay(time.Now().AddDate(0, 0, -1)); err != nil {
+        fmt.Printf("Error processing interest: %v\n", err)
+    } else if len(posted) > 0 {
+        fmt.Printf("Posted %d interest and charge transaction(s) since the last run\n", len(posted))
+    }
+}
+
+// startServer serves the banking API on addr until the process is interrupted, then
+// stops accepting requests, lets those in flight finish and saves the bank. End-of-day
+// processing runs for the day just gone shortly after each midnight while it serves
+func startServer(bs *BankSystem, addr string) error {
+    mux := http.NewServeMux()
+    NewBankHandler(bs).RegisterRoutes(mux)
+    server := &http.Server{Addr: addr, Handler: loggingMiddleware(mux)}
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+
+    endOfDay := make(chan struct{})
+    go func() {
+        defer close(endOfDay)
+        bs.runEndOfDayDaily(ctx)
+    }()
+    // closeBank waits for any end-of-day run in progress before saving the bank
+    closeBank := func() error {
+        stop()
+        <-endOfDay
+        return bs.Close()
+    }
+
+    errs := make(chan error, 1)
+    go func() {
+        log.Printf("Server is running on http://localhost%s", addr)
+        errs <- server.ListenAndServe()
+    }()
+
+    select {
+    case err := <-errs:
+        closeBank()
+        return err
+    case <-ctx.Done():
+    }
+
+    log.Println("Shutting down")
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+    if err := server.Shutdown(shutdownCtx); err != nil {
+        closeBank()
+        return err
+    }
+    return closeBank()
+}
+
+// runEndOfDayDaily runs end-of-day processing for the previous day after each midnight
+// until ctx is done
+func (bs *BankSystem) runEndOfDayDaily(ctx context.Context) {
+    for {
+        now := time.Now()
+        timer := time.NewTimer(dateOnly(now).AddDate(0, 0, 1).Sub(now))
+        select {
+        case <-ctx.Done():
+            timer.Stop()
+            return
+        case <-timer.C:
+        }
+
+        if posted, err := bs.RunEndOfDay(time.Now().AddDate(0, 0, -1)); err != nil {
+            log.Printf("End-of-day processing failed: %v", err)
+        } else {
+            log.Printf("End-of-day processing posted %d interest and charge transaction(s)", len(posted))
+        }
+    }
+}
+
+// readAccountType asks which type of account to open
+func (bs *BankSystem) readAccountType() (string, error) {
+    types := bs.AccountTypes()
+    fmt.Println("Account types:")
+    for i, terms := range types {
+        fmt.Printf("%d. %s (%s a year)\n", i+1, terms.Type, formatRate(terms.AnnualRateBP))
+    }
+    fmt.Print("Select account type: ")
+
+    choice, err := strconv.Atoi(bs.readInput())
+    if err != nil || choice < 1 || choice > len(types) {
+        return "", errors.New("invalid account type")
+    }
+    return types[choice-1].Type, nil
+}
+
+// readTransactionFilter asks for the criteria of a transaction search; blank answers match anything
+func (bs *BankSystem) readTransactionFilter() (TransactionFilter, error) {
+    var filter TransactionFilter
+    var err error
+
+    filter.From, filter.To, err = bs.readDateRange()
+    if err != nil {
+        return filter, err
+    }
+
+    fmt.Printf("Type (%s, %s, %s, %s, blank for any): ", DEPOSIT_TYPE, WITHDRAW_TYPE, TRANSFER_IN_TYPE, TRANSFER_OUT_TYPE)
+    if input := bs.readInput(); input != "" {
+        filter.Types = []string{strings.ToUpper(input)}
+    }
+
+    fmt.Print("Minimum amount (blank for any): Rs. ")
+    if input := bs.readInput(); input != "" {
+        if filter.MinAmount, err = ParseMoney(input, CURRENCY_INR); err != nil {
+            return filter, err
+        }
+    }
+
+    fmt.Print("Maximum amount (blank for any): Rs. ")
+    if input := bs.readInput(); input != "" {
+        if filter.MaxAmount, err = ParseMoney(input, CURRENCY_INR); err != nil {
+            return filter, err
+        }
+    }
+    return filter, nil
+}
+
+// readWithdrawalLimits asks for an account's new withdrawal limits, keeping the current
+// ones where the answer is blank
+func (bs *BankSystem) readWithdrawalLimits(account *Account) (WithdrawalLimits, error) {
+    bs.mu.RLock()
+    account.mu.Lock()
+    limits := account.Limits
+    account.mu.Unlock()
+    bs.mu.RUnlock()
+
+    var err error
+    fmt.Printf("Per-transaction limit (currently %s; blank to keep, 0 for none): Rs. ", formatLimit(limits.PerTransaction))
+    if input := bs.readInput(); input != "" {
+        if limits.PerTransaction, err = ParseMoney(input, CURRENCY_INR); err != nil {
+            return limits, err
+        }
+    }
+    fmt.Printf("Daily limit (currently %s; blank to keep, 0 for none): Rs. ", formatLimit(limits.Daily))
+    if input := bs.readInput(); input != "" {
+        if limits.Daily, err = ParseMoney(input, CURRENCY_INR); err != nil {
+            return limits, err
+        }
+    }
+    return limits, nil
+}
+
+// reviewFlags lists the pending flagged transactions and records a decision on one
+func (bs *BankSystem) reviewFlags() error {
+    pending := bs.FlaggedTransactions(FLAG_PENDING)
+    if len(pending) == 0 {
+        fmt.Println("No flagged transactions awaiting review.")
+        return nil
+    }
+
+    fmt.Println("\nFlagged Transactions:")
+    fmt.Println("----------------------------------------")
+    for _, flag := range pending {
+        fmt.Println(flag)
+    }
+
+    fmt.Print("Enter flag ID to review (blank to go back): ")
+    input := bs.readInput()
+    if input == "" {
+        return nil
+    }
+    id, err := strconv.ParseInt(input, 10, 64)
+    if err != nil {
+        return fmt.Errorf("invalid flag ID %q", input)
+    }
+
+    fmt.Print("Decision (c = genuine, clear it; f = confirm fraud): ")
+    var status string
+    switch strings.ToLower(bs.readInput()) {
+    case "c":
+        status = FLAG_CLEARED
+    case "f":
+        status = FLAG_CONFIRMED
+    default:
+        return ErrInvalidReview
+    }
+    fmt.Print("Note: ")
+    note := bs.readInput()
+
+    flag, err := bs.ReviewFlag(id, status, note)
+    if err != nil {
+        return err
+    }
+    fmt.Printf("Flag %d marked %s\n", flag.ID, flag.Status)
+    return nil
+}
+
+// formatLimit formats a withdrawal limit, where zero means none
+func formatLimit(limit Money) string {
+    if !limit.IsPositive() {
+        return "no limit"
+    }
+    return limit.String()
+}
+
 // RunMenu starts the interactive menu system
 func (bs *BankSystem) RunMenu() {
     fmt.Println("Welcome to the Bank Transaction System!")
-    
-    // Creating a sample account for testing
-    account, err := bs.CreateAccount(1, "Amit kumar")
-    if err != nil {
-        fmt.Printf("Error creating account: %v\n", err)
-        return
+    bs.catchUpInterest()
+
+    // Every account operation applies to the selected account; 0 means none is selected
+    selected := 0
+    for _, acc := range bs.ListAccounts() {
+        if _, err := bs.FindAccount(acc.ID); err == nil {
+            selected = acc.ID
+            break
+        }
+    }
+
+    // Creating a sample account for testing when the bank is new
+    if len(bs.ListAccounts()) == 0 {
+        account, err := bs.CreateAccount(1, "Amit kumar", SAVINGS_ACCOUNT)
+        if err != nil {
+            fmt.Printf("Error creating account: %v\n", err)
+            return
+        }
+        fmt.Printf("Created account for %s (ID: %d)\n\n", account.Name, account.ID)
+        selected = account.ID
     }
-    fmt.Printf("Created account for %s (ID: %d)\n\n", account.Name, account.ID)
 
     for {
-        fmt.Println("\nPlease select an option:")
+        if current, err := bs.FindAccount(selected); err == nil {
+            fmt.Printf("\nCurrent account: %d (%s)\n", current.ID, current.Name)
+        } else {
+            selected = 0
+            fmt.Println("\nNo account selected.")
+        }
+
+        fmt.Println("Please select an option:")
         fmt.Printf("%d. Deposit\n", DEPOSIT)
         fmt.Printf("%d. Withdraw\n", WITHDRAW)
+        fmt.Printf("%d. Transfer\n", TRANSFER)
         fmt.Printf("%d. Check Balance\n", CHECK_BALANCE)
         fmt.Printf("%d. View Transaction History\n", VIEW_HISTORY)
+        fmt.Printf("%d. Mini Statement\n", MINI_STATEMENT)
+        fmt.Printf("%d. Full Statement\n", FULL_STATEMENT)
+        fmt.Printf("%d. Search Transactions\n", SEARCH_HISTORY)
+        fmt.Printf("%d. Create Account\n", CREATE_ACCOUNT)
+        fmt.Printf("%d. Switch Account\n", SWITCH_ACCOUNT)
+        fmt.Printf("%d. List Accounts\n", LIST_ACCOUNTS)
+        fmt.Printf("%d. Close Account\n", CLOSE_ACCOUNT)
+        fmt.Printf("%d. Trial Balance\n", TRIAL_BALANCE)
+        fmt.Printf("%d. Run Interest Processing\n", END_OF_DAY)
+        fmt.Printf("%d. Withdrawal Limits\n", SET_LIMITS)
+        fmt.Printf("%d. Review Flagged Transactions\n", REVIEW_FLAGS)
         fmt.Printf("%d. Exit\n", EXIT)
         
         choice, err := strconv.Atoi(bs.readInput())
@@ -171,49 +894,241 @@ func (bs *BankSystem) RunMenu() {
             continue
         }
 
+        switch choice {
+        case DEPOSIT, WITHDRAW, TRANSFER, CHECK_BALANCE, VIEW_HISTORY, MINI_STATEMENT, FULL_STATEMENT, SEARCH_HISTORY, CLOSE_ACCOUNT, SET_LIMITS:
+            if selected == 0 {
+                fmt.Println("Please create or switch to an account first.")
+                continue
+            }
+        }
+
         switch choice {
         case DEPOSIT:
-            fmt.Print("Enter amount to deposit: Rs.")
-            amount, err := strconv.ParseFloat(bs.readInput(), 64)
+            fmt.Print("Enter amount to deposit: Rs. ")
+            amount, err := ParseMoney(bs.readInput(), CURRENCY_INR)
             if err != nil {
-                fmt.Println("Invalid amount.")
+                fmt.Printf("Error: %v\n", err)
                 continue
             }
             
-            if err := bs.Deposit(1, amount); err != nil {
+            if _, err := bs.Deposit(selected, amount); err != nil {
                 fmt.Printf("Error: %v\n", err)
             } else {
-                fmt.Printf("Successfully deposited Rs.%.2f\n", amount)
+                fmt.Printf("Successfully deposited %v\n", amount)
             }
 
         case WITHDRAW:
-            fmt.Print("Enter amount to withdraw: Rs.")
-            amount, err := strconv.ParseFloat(bs.readInput(), 64)
+            fmt.Print("Enter amount to withdraw: Rs. ")
+            amount, err := ParseMoney(bs.readInput(), CURRENCY_INR)
             if err != nil {
-                fmt.Println("Invalid amount.")
+                fmt.Printf("Error: %v\n", err)
                 continue
             }
             
-            if err := bs.Withdraw(1, amount); err != nil {
+            if _, err := bs.Withdraw(selected, amount); err != nil {
+                fmt.Printf("Error: %v\n", err)
+            } else {
+                fmt.Printf("Successfully withdrew %v\n", amount)
+            }
+
+        case TRANSFER:
+            fmt.Print("Enter destination account ID: ")
+            toID, err := strconv.Atoi(bs.readInput())
+            if err != nil {
+                fmt.Println("Invalid account ID.")
+                continue
+            }
+            fmt.Print("Enter amount to transfer: Rs. ")
+            amount, err := ParseMoney(bs.readInput(), CURRENCY_INR)
+            if err != nil {
+                fmt.Printf("Error: %v\n", err)
+                continue
+            }
+
+            if _, err := bs.Transfer(selected, toID, amount); err != nil {
                 fmt.Printf("Error: %v\n", err)
             } else {
-                fmt.Printf("Successfully withdrew Rs. %.2f\n", amount)
+                fmt.Printf("Successfully transferred %v to account %d\n", amount, toID)
             }
 
         case CHECK_BALANCE:
-            account, err := bs.FindAccount(1)
+            account, err := bs.FindAccount(selected)
             if err != nil {
                 fmt.Printf("Error: %v\n", err)
             } else {
-                fmt.Printf("Current balance: Rs. %.2f\n", account.Balance)
+                terms, limits := account.AccountTerms(), account.WithdrawalLimits()
+                fmt.Printf("Current balance: %v\n", bs.balanceOf(account.ID))
+                fmt.Printf("Account type: %s at %s a year\n", terms.Type, formatRate(terms.AnnualRateBP))
+                if interest, err := bs.AccruedInterest(account.ID); err == nil && interest.IsPositive() {
+                    fmt.Printf("Interest accrued, to be credited at month end: %v\n", interest)
+                }
+                if terms.OverdraftLimit.IsPositive() {
+                    fmt.Printf("Overdraft limit: %v\n", terms.OverdraftLimit)
+                }
+                if until := account.LockedUntil(); !until.IsZero() {
+                    fmt.Printf("Locked in until: %s\n", until.Format("2006-01-02"))
+                }
+                if today, err := bs.WithdrawnToday(account.ID); err == nil && limits.Daily.IsPositive() {
+                    fmt.Printf("Withdrawn today: %v of a daily limit of %v\n", today, limits.Daily)
+                }
             }
 
         case VIEW_HISTORY:
-            if err := bs.DisplayTransactionHistory(1); err != nil {
+            if err := bs.DisplayTransactionHistory(selected); err != nil {
+                fmt.Printf("Error: %v\n", err)
+            }
+
+        case MINI_STATEMENT:
+            if err := bs.WriteMiniStatement(os.Stdout, selected, MINI_STATEMENT_SIZE); err != nil {
+                fmt.Printf("Error: %v\n", err)
+            }
+
+        case FULL_STATEMENT:
+            from, to, err := bs.readDateRange()
+            if err != nil {
+                fmt.Printf("Error: %v\n", err)
+                continue
+            }
+            if err := bs.WriteStatement(os.Stdout, selected, from, to); err != nil {
+                fmt.Printf("Error: %v\n", err)
+            }
+
+        case SEARCH_HISTORY:
+            filter, err := bs.readTransactionFilter()
+            if err != nil {
+                fmt.Printf("Error: %v\n", err)
+                continue
+            }
+            if err := bs.displayTransactions(selected, filter); err != nil {
+                fmt.Printf("Error: %v\n", err)
+            }
+
+        case CREATE_ACCOUNT:
+            fmt.Print("Enter account holder name: ")
+            name := bs.readInput()
+            if name == "" {
+                fmt.Println("Name cannot be empty.")
+                continue
+            }
+
+            accountType, err := bs.readAccountType()
+            if err != nil {
+                fmt.Printf("Error: %v\n", err)
+                continue
+            }
+
+            account, err := bs.CreateAccount(bs.NextAccountID(), name, accountType)
+            if err != nil {
+                fmt.Printf("Error: %v\n", err)
+            } else {
+                selected = account.ID
+                fmt.Printf("Created %s account for %s (ID: %d)\n", account.AccountTerms().Type, account.Name, account.ID)
+            }
+
+        case SWITCH_ACCOUNT:
+            fmt.Print("Enter account ID: ")
+            id, err := strconv.Atoi(bs.readInput())
+            if err != nil {
+                fmt.Println("Invalid account ID.")
+                continue
+            }
+
+            account, err := bs.FindAccount(id)
+            if err != nil {
+                fmt.Printf("Error: %v\n", err)
+            } else {
+                selected = account.ID
+                fmt.Printf("Switched to account %d (%s)\n", account.ID, account.Name)
+            }
+
+        case LIST_ACCOUNTS:
+            if len(bs.ListAccounts()) == 0 {
+                fmt.Println("No accounts found.")
+                continue
+            }
+            fmt.Println("\nAccounts:")
+            fmt.Println("----------------------------------------")
+            for _, acc := range bs.ListAccounts() {
+                status := "Open"
+                if acc.IsClosed() {
+                    status = "Closed"
+                }
+                fmt.Printf("%d. %s - %s - %v (%s)\n", acc.ID, acc.Name, acc.AccountTerms().Type, bs.balanceOf(acc.ID), status)
+            }
+
+        case CLOSE_ACCOUNT:
+            fmt.Printf("Close account %d? (y/n): ", selected)
+            if !strings.EqualFold(bs.readInput(), "y") {
+                fmt.Println("Account not closed.")
+                continue
+            }
+
+            payout, err := bs.CloseAccount(selected)
+            if err != nil {
+                fmt.Printf("Error: %v\n", err)
+            } else {
+                fmt.Printf("Account %d closed. Paid out %v\n", selected, payout)
+                selected = 0
+            }
+
+        case TRIAL_BALANCE:
+            tb, err := bs.TrialBalance()
+            if err == nil {
+                err = tb.WriteText(os.Stdout)
+            }
+            if err != nil {
+                fmt.Printf("Error: %v\n", err)
+            }
+
+        case END_OF_DAY:
+            fmt.Print("Process through date (YYYY-MM-DD, blank for yesterday): ")
+            through := time.Now().AddDate(0, 0, -1)
+            if input := bs.readInput(); input != "" {
+                parsed, err := time.ParseInLocation("2006-01-02", input, time.Local)
+                if err != nil {
+                    fmt.Printf("Invalid date %q\n", input)
+                    continue
+                }
+                through = parsed
+            }
+
+            posted, err := bs.RunEndOfDay(through)
+            for _, t := range posted {
+                fmt.Println(t)
+            }
+            if err != nil {
+                fmt.Printf("Error: %v\n", err)
+            } else {
+                fmt.Printf("Interest processed through %s; %d transaction(s) posted\n", through.Format("2006-01-02"), len(posted))
+            }
+
+        case SET_LIMITS:
+            account, err := bs.FindAccount(selected)
+            if err != nil {
+                fmt.Printf("Error: %v\n", err)
+                continue
+            }
+            limits, err := bs.readWithdrawalLimits(account)
+            if err != nil {
+                fmt.Printf("Error: %v\n", err)
+                continue
+            }
+            if err := bs.SetWithdrawalLimits(selected, limits); err != nil {
+                fmt.Printf("Error: %v\n", err)
+            } else {
+                fmt.Printf("Withdrawal limits for account %d: %s per transaction, %s a day\n",
+                    selected, formatLimit(limits.PerTransaction), formatLimit(limits.Daily))
+            }
+
+        case REVIEW_FLAGS:
+            if err := bs.reviewFlags(); err != nil {
                 fmt.Printf("Error: %v\n", err)
             }
 
         case EXIT:
+            if err := bs.Close(); err != nil {
+                fmt.Printf("Error saving bank data: %v\n", err)
+            }
             fmt.Println("Thank you for using the Bank Transaction System!")
             return
 
@@ -224,6 +1139,35 @@ func (bs *BankSystem) RunMenu() {
 }
 
 func main() {
-    bankSystem := NewBankSystem()
+    dataDir := flag.String("data", "bank_data", "directory for the transaction log and snapshots; empty keeps everything in memory")
+    snapshotEvery := flag.Int("snapshot-every", DEFAULT_SNAPSHOT_EVERY, "number of logged changes between snapshots")
+    serveAddr := flag.String("serve", "", "serve the HTTP API on this address (e.g. :8080) instead of running the menu")
+    flag.Parse()
+
+    var bankSystem *BankSystem
+    if *dataDir == "" {
+        bankSystem = NewBankSystem()
+    } else {
+        var report *RecoveryReport
+        var err error
+        bankSystem, report, err = OpenBankSystem(*dataDir, *snapshotEvery)
+        if err != nil {
+            fmt.Printf("Error loading bank data: %v\n", err)
+            os.Exit(1)
+        }
+        fmt.Printf("Recovered %d account(s) from %s: snapshot at record %d, %d record(s) replayed\n",
+            len(bankSystem.ListAccounts()), *dataDir, report.SnapshotLSN, report.RecordsReplayed)
+        if report.TornBytes > 0 {
+            fmt.Printf("Discarded an incomplete last record (%d bytes) left by an interrupted write\n", report.TornBytes)
+        }
+    }
+
+    if *serveAddr != "" {
+        bankSystem.catchUpInterest()
+        if err := startServer(bankSystem, *serveAddr); err != nil {
+            log.Fatal(err)
+        }
+        return
+    }
     bankSystem.RunMenu()
 }
\ No newline at end of file
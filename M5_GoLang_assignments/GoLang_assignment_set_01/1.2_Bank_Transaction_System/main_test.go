@@ -0,0 +1,221 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "testing"
+)
+
+// newTestBank returns an in-memory bank with one savings account per opening balance, given
+// in paise and numbered from 1. Fraud rules and withdrawal limits are off so tests can move
+// money freely
+func newTestBank(t *testing.T, balances ...int64) *BankSystem {
+    t.Helper()
+    bs := NewBankSystem()
+    bs.SetFraudRules()
+    for i, balance := range balances {
+        id := i + 1
+        if _, err := bs.CreateAccount(id, fmt.Sprintf("Customer %d", id), SAVINGS_ACCOUNT); err != nil {
+            t.Fatal(err)
+        }
+        if err := bs.SetWithdrawalLimits(id, WithdrawalLimits{}); err != nil {
+            t.Fatal(err)
+        }
+        if balance > 0 {
+            if _, err := bs.Deposit(id, INR(balance)); err != nil {
+                t.Fatal(err)
+            }
+        }
+    }
+    return bs
+}
+
+// wantBalances fails the test unless the accounts numbered from 1 hold the given balances in paise
+func wantBalances(t *testing.T, bs *BankSystem, balances ...int64) {
+    t.Helper()
+    for i, want := range balances {
+        got, err := bs.Balance(i + 1)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if got != INR(want) {
+            t.Errorf("account %d holds %v, want %v", i+1, got, INR(want))
+        }
+    }
+}
+
+func TestTransferRejections(t *testing.T) {
+    bs := newTestBank(t, 50000, 10000, 0)
+    if _, err := bs.CloseAccount(3); err != nil {
+        t.Fatal(err)
+    }
+
+    tests := []struct {
+        name     string
+        from, to int
+        amount   Money
+        wantErr  error
+    }{
+        {"to itself", 1, 1, INR(100), ErrSameAccount},
+        {"more than the balance", 2, 1, INR(10001), ErrInsufficientBalance},
+        {"zero", 1, 2, INR(0), ErrInvalidAmount},
+        {"negative", 1, 2, INR(-100), ErrInvalidAmount},
+        {"from an unknown account", 9, 1, INR(100), ErrAccountNotFound},
+        {"to an unknown account", 1, 9, INR(100), ErrAccountNotFound},
+        {"to a closed account", 1, 3, INR(100), ErrAccountClosed},
+        {"from a closed account", 3, 1, INR(100), ErrAccountClosed},
+    }
+
+    for _, tt := range tests {
+        if _, err := bs.Transfer(tt.from, tt.to, tt.amount); !errors.Is(err, tt.wantErr) {
+            t.Errorf("transfer %s = %v, want %v", tt.name, err, tt.wantErr)
+        }
+    }
+
+    // Nothing was moved and neither side recorded anything
+    wantBalances(t, bs, 50000, 10000)
+    for id := 1; id <= 2; id++ {
+        if transactions, _ := bs.Transactions(id, TransactionFilter{}); len(transactions) != 1 {
+            t.Errorf("account %d has %d transactions, want only its opening deposit", id, len(transactions))
+        }
+    }
+}
+
+func TestTransferLinksBothLegs(t *testing.T) {
+    bs := newTestBank(t, 50000, 10000)
+
+    out, err := bs.Transfer(1, 2, INR(12550))
+    if err != nil {
+        t.Fatal(err)
+    }
+    second, err := bs.Transfer(2, 1, INR(50))
+    if err != nil {
+        t.Fatal(err)
+    }
+    wantBalances(t, bs, 37500, 22500)
+
+    in, err := bs.Transactions(2, TransactionFilter{Types: []string{TRANSFER_IN_TYPE}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(in) != 1 {
+        t.Fatalf("account 2 received %d transfers, want 1", len(in))
+    }
+
+    if out.Type != TRANSFER_OUT_TYPE || out.Counterparty != 2 || out.BalanceAfter != INR(37450) {
+        t.Errorf("outgoing leg = %+v, want a TRANSFER_OUT to account 2 leaving Rs. 374.50", out)
+    }
+    if in[0].Counterparty != 1 || in[0].Amount != out.Amount || in[0].BalanceAfter != INR(22550) {
+        t.Errorf("incoming leg = %+v, want Rs. 125.50 from account 1 leaving Rs. 225.50", in[0])
+    }
+    if out.Reference == "" || in[0].Reference != out.Reference || in[0].JournalID != out.JournalID {
+        t.Errorf("legs have references %q and %q and journal entries %d and %d, want them shared",
+            out.Reference, in[0].Reference, out.JournalID, in[0].JournalID)
+    }
+    if out.ID == in[0].ID {
+        t.Errorf("both legs have transaction ID %d, want one each", out.ID)
+    }
+    if second.Reference == out.Reference {
+        t.Errorf("two transfers share the reference %q", out.Reference)
+    }
+}
+
+func TestCreateAccounts(t *testing.T) {
+    bs := newTestBank(t)
+
+    first, err := bs.CreateAccount(0, "Asha Rao", SAVINGS_ACCOUNT)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := bs.CreateAccount(5, "Ravi Nair", "current"); err != nil {
+        t.Fatal(err)
+    }
+    next, err := bs.CreateAccount(0, "Meera Iyer", FIXED_DEPOSIT)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if first.ID != 1 || next.ID != 6 {
+        t.Errorf("accounts opened without an ID got %d and %d, want 1 and 6", first.ID, next.ID)
+    }
+
+    if _, err := bs.CreateAccount(5, "Kiran Das", SAVINGS_ACCOUNT); !errors.Is(err, ErrDuplicateAccount) {
+        t.Errorf("reusing ID 5 = %v, want ErrDuplicateAccount", err)
+    }
+    if _, err := bs.CreateAccount(0, "Kiran Das", "PIGGY_BANK"); !errors.Is(err, ErrUnknownAccountType) {
+        t.Errorf("opening an unknown type = %v, want ErrUnknownAccountType", err)
+    }
+
+    listed := make([]string, 0)
+    for _, acc := range bs.ListAccounts() {
+        listed = append(listed, fmt.Sprintf("%d %s", acc.ID, acc.AccountTerms().Type))
+    }
+    if got := fmt.Sprint(listed); got != "[1 SAVINGS 5 CURRENT 6 FIXED_DEPOSIT]" {
+        t.Errorf("ListAccounts = %s, want accounts 1, 5 and 6 in opening order with their types", got)
+    }
+}
+
+func TestCloseAccount(t *testing.T) {
+    bs := newTestBank(t, 50000, 0)
+
+    payout, err := bs.CloseAccount(1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if payout != INR(50000) {
+        t.Errorf("closing paid out %v, want Rs. 500.00", payout)
+    }
+    wantBalances(t, bs, 0)
+
+    // The closed account keeps its history and ends on the payout
+    history, err := bs.Transactions(1, TransactionFilter{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if last := history[len(history)-1]; len(history) != 2 || last.Type != WITHDRAW_TYPE || last.Amount != payout {
+        t.Errorf("history after closing = %+v, want the deposit and a payout withdrawal", history)
+    }
+    if account, err := bs.GetAccount(1); err != nil || !account.IsClosed() {
+        t.Errorf("GetAccount of a closed account = %v, %v; want the closed account", account, err)
+    }
+
+    closed := []struct {
+        name string
+        err  error
+    }{
+        {"find", discardAccount(bs.FindAccount(1))},
+        {"deposit", discard(bs.Deposit(1, INR(100)))},
+        {"withdraw", discard(bs.Withdraw(1, INR(100)))},
+        {"transfer in", discard(bs.Transfer(2, 1, INR(100)))},
+        {"close again", discardMoney(bs.CloseAccount(1))},
+    }
+    for _, tt := range closed {
+        if !errors.Is(tt.err, ErrAccountClosed) {
+            t.Errorf("%s on a closed account = %v, want ErrAccountClosed", tt.name, tt.err)
+        }
+    }
+
+    // An overdrawn account must be brought back to zero before it can be closed
+    overdrawn, err := bs.CreateAccount(0, "Ravi Nair", CURRENT_ACCOUNT)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := bs.Withdraw(overdrawn.ID, INR(2500)); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := bs.CloseAccount(overdrawn.ID); !errors.Is(err, ErrAccountOverdrawn) {
+        t.Errorf("closing an overdrawn account = %v, want ErrAccountOverdrawn", err)
+    }
+    if _, err := bs.CloseAccount(9); !errors.Is(err, ErrAccountNotFound) {
+        t.Errorf("closing an unknown account = %v, want ErrAccountNotFound", err)
+    }
+}
+
+// discardAccount drops the account from a lookup's result, keeping its error
+func discardAccount(_ *Account, err error) error {
+    return err
+}
+
+// discardMoney drops the amount from an operation's result, keeping its error
+func discardMoney(_ Money, err error) error {
+    return err
+}
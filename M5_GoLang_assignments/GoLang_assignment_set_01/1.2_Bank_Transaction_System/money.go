@@ -0,0 +1,158 @@
+package main
+
+import (
+    "fmt"
+    "math"
+    "strings"
+)
+
+// Currency codes
+const (
+    CURRENCY_INR = "INR"
+)
+
+// currencySymbols maps currency codes to the prefix used when formatting amounts
+var currencySymbols = map[string]string{
+    CURRENCY_INR: "Rs.",
+}
+
+// Money is an exact amount of a currency, held as a whole number of its minor unit (paise for INR)
+type Money struct {
+    Minor    int64
+    Currency string
+}
+
+// NewMoney returns minor units of currency
+func NewMoney(minor int64, currency string) Money {
+    return Money{Minor: minor, Currency: currency}
+}
+
+// INR returns an amount in rupees given in paise
+func INR(paise int64) Money {
+    return NewMoney(paise, CURRENCY_INR)
+}
+
+// ParseMoney reads an amount typed by a user, such as "100", "1,250.5" or "Rs. 99.99".
+// At most two decimal places are allowed, so no amount is ever rounded
+func ParseMoney(input string, currency string) (Money, error) {
+    s := strings.TrimSpace(input)
+    if symbol, ok := currencySymbols[currency]; ok {
+        s = strings.TrimSpace(strings.TrimPrefix(s, symbol))
+    }
+    s = strings.TrimSpace(strings.TrimPrefix(s, currency))
+    s = strings.ReplaceAll(s, ",", "")
+
+    negative := strings.HasPrefix(s, "-")
+    s = strings.TrimPrefix(s, "-")
+
+    whole, fraction, hasPoint := strings.Cut(s, ".")
+    if whole == "" && fraction == "" || hasPoint && fraction == "" || len(fraction) > 2 {
+        return Money{}, fmt.Errorf("invalid amount %q: use digits with at most two decimal places", input)
+    }
+    fraction += strings.Repeat("0", 2-len(fraction))
+
+    var minor int64
+    for _, r := range whole + fraction {
+        if r < '0' || r > '9' {
+            return Money{}, fmt.Errorf("invalid amount %q: use digits with at most two decimal places", input)
+        }
+        if minor > (math.MaxInt64-int64(r-'0'))/10 {
+            return Money{}, fmt.Errorf("invalid amount %q: too large", input)
+        }
+        minor = minor*10 + int64(r-'0')
+    }
+
+    if negative {
+        minor = -minor
+    }
+    return NewMoney(minor, currency), nil
+}
+
+// Add returns m + other. Both must be in the same currency
+func (m Money) Add(other Money) (Money, error) {
+    if err := m.checkCurrency(other); err != nil {
+        return Money{}, err
+    }
+    if (other.Minor > 0 && m.Minor > math.MaxInt64-other.Minor) || (other.Minor < 0 && m.Minor < math.MinInt64-other.Minor) {
+        return Money{}, fmt.Errorf("amount out of range: %w", ErrInvalidAmount)
+    }
+    return NewMoney(m.Minor+other.Minor, m.Currency), nil
+}
+
+// Sub returns m - other. Both must be in the same currency
+func (m Money) Sub(other Money) (Money, error) {
+    if other.Minor == math.MinInt64 {
+        return Money{}, fmt.Errorf("amount out of range: %w", ErrInvalidAmount)
+    }
+    return m.Add(other.Neg())
+}
+
+// Neg returns -m
+func (m Money) Neg() Money {
+    return NewMoney(-m.Minor, m.Currency)
+}
+
+// Cmp compares m with other, returning -1, 0 or +1. Amounts in different currencies
+// cannot be compared and return an error
+func (m Money) Cmp(other Money) (int, error) {
+    if err := m.checkCurrency(other); err != nil {
+        return 0, err
+    }
+    switch {
+    case m.Minor < other.Minor:
+        return -1, nil
+    case m.Minor > other.Minor:
+        return 1, nil
+    default:
+        return 0, nil
+    }
+}
+
+// IsPositive reports whether m is greater than zero
+func (m Money) IsPositive() bool {
+    return m.Minor > 0
+}
+
+// IsZero reports whether m is zero
+func (m Money) IsZero() bool {
+    return m.Minor == 0
+}
+
+// String formats m as "Rs. 1234.50", or with the currency code for currencies without a symbol
+func (m Money) String() string {
+    symbol, ok := currencySymbols[m.Currency]
+    if !ok {
+        symbol = m.Currency
+    }
+
+    decimal := m.Decimal()
+    if magnitude, negative := strings.CutPrefix(decimal, "-"); negative {
+        return "-" + symbol + " " + magnitude
+    }
+    return symbol + " " + decimal
+}
+
+// Decimal formats m as a plain decimal number such as "1234.50", for machine-readable output
+func (m Money) Decimal() string {
+    sign := ""
+    minor := m.Minor
+    if minor < 0 {
+        sign = "-"
+    }
+    whole, fraction := minor/100, minor%100
+    if whole < 0 {
+        whole = -whole
+    }
+    if fraction < 0 {
+        fraction = -fraction
+    }
+    return fmt.Sprintf("%s%d.%02d", sign, whole, fraction)
+}
+
+// checkCurrency reports an error if other is in a different currency from m
+func (m Money) checkCurrency(other Money) error {
+    if m.Currency != other.Currency {
+        return fmt.Errorf("currency mismatch: %s and %s", m.Currency, other.Currency)
+    }
+    return nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "sort"
+    "strings"
+    "text/tabwriter"
+    "time"
+)
+
+// Transaction is one entry in an account's ledger
+type Transaction struct {
+    ID           int64
+    Type         string
+    Amount       Money
+    BalanceAfter Money
+    Timestamp    time.Time
+    Description  string
+    // Counterparty is the account on the other side of a transfer, or 0
+    Counterparty int
+    // Reference links the two entries of a transfer
+    Reference string
+    // JournalID is the journal entry that moved the money
+    JournalID int64
+}
+
+// TransactionFilter selects transactions from an account's ledger.
+// Zero values leave a criterion open
+type TransactionFilter struct {
+    From      time.Time
+    To        time.Time
+    Types     []string
+    MinAmount Money
+    MaxAmount Money
+}
+
+// IsCredit reports whether the transaction added money to the account
+func (t Transaction) IsCredit() bool {
+    return t.Type == DEPOSIT_TYPE || t.Type == TRANSFER_IN_TYPE || t.Type == INTEREST_TYPE
+}
+
+// signedAmount returns the amount the transaction changed the balance by
+func (t Transaction) signedAmount() Money {
+    if t.IsCredit() {
+        return t.Amount
+    }
+    return t.Amount.Neg()
+}
+
+// String renders the transaction as a single history line
+func (t Transaction) String() string {
+    sign := "-"
+    if t.IsCredit() {
+        sign = "+"
+    }
+
+    line := fmt.Sprintf("%s: %s%v (Balance: %v) - %s", t.Type, sign, t.Amount, t.BalanceAfter, t.Timestamp.Format("2006-01-02 15:04:05"))
+    if t.Description != "" {
+        line += " - " + t.Description
+    }
+    if t.Reference != "" {
+        line += " [" + t.Reference + "]"
+    }
+    return line
+}
+
+// matches reports whether t satisfies every criterion of the filter
+func (f TransactionFilter) matches(t Transaction) bool {
+    if !f.From.IsZero() && t.Timestamp.Before(f.From) {
+        return false
+    }
+    if !f.To.IsZero() && t.Timestamp.After(f.To) {
+        return false
+    }
+    if len(f.Types) > 0 {
+        found := false
+        for _, txType := range f.Types {
+            if strings.EqualFold(txType, t.Type) {
+                found = true
+                break
+            }
+        }
+        if !found {
+            return false
+        }
+    }
+    if f.MinAmount.IsPositive() && t.Amount.Minor < f.MinAmount.Minor {
+        return false
+    }
+    if f.MaxAmount.IsPositive() && t.Amount.Minor > f.MaxAmount.Minor {
+        return false
+    }
+    return true
+}
+
+// Transactions returns the transactions of an account, open or closed, that match filter, oldest first
+func (bs *BankSystem) Transactions(id int, filter TransactionFilter) ([]Transaction, error) {
+    bs.mu.RLock()
+    defer bs.mu.RUnlock()
+
+    account, err := bs.findAnyAccount(id)
+    if err != nil {
+        return nil, err
+    }
+
+    account.mu.Lock()
+    defer account.mu.Unlock()
+
+    matches := make([]Transaction, 0)
+    for _, t := range account.Transactions {
+        if filter.matches(t) {
+            matches = append(matches, t)
+        }
+    }
+    return matches, nil
+}
+
+// WriteMiniStatement writes an account's last n transactions and current balance to w
+func (bs *BankSystem) WriteMiniStatement(w io.Writer, id int, n int) error {
+    bs.mu.RLock()
+    defer bs.mu.RUnlock()
+
+    account, err := bs.findAnyAccount(id)
+    if err != nil {
+        return err
+    }
+
+    account.mu.Lock()
+    recent := account.Transactions
+    if len(recent) > n {
+        recent = recent[len(recent)-n:]
+    }
+    recent = append([]Transaction(nil), recent...)
+    balance := bs.balanceOf(account.ID)
+    account.mu.Unlock()
+
+    fmt.Fprintf(w, "\nMini Statement for Account %d (%s):\n", account.ID, account.Name)
+    fmt.Fprintln(w, "----------------------------------------")
+    if len(recent) == 0 {
+        fmt.Fprintln(w, "No transactions found.")
+    }
+    for i := len(recent) - 1; i >= 0; i-- {
+        t := recent[i]
+        sign := "-"
+        if t.IsCredit() {
+            sign = "+"
+        }
+        fmt.Fprintf(w, "%s  %-13s %s%v\n", t.Timestamp.Format("2006-01-02 15:04"), t.Type, sign, t.Amount)
+    }
+    _, err = fmt.Fprintf(w, "Available balance: %v\n", balance)
+    return err
+}
+
+// WriteStatement writes a full statement of an account between two times to w, with the opening
+// balance, every transaction as a debit or credit in date order, the totals and the closing
+// balance. Zero times leave the period open
+func (bs *BankSystem) WriteStatement(w io.Writer, id int, from time.Time, to time.Time) error {
+    bs.mu.RLock()
+    defer bs.mu.RUnlock()
+
+    account, err := bs.findAnyAccount(id)
+    if err != nil {
+        return err
+    }
+
+    account.mu.Lock()
+    all := append([]Transaction(nil), account.Transactions...)
+    currency := bs.journal.currency
+    account.mu.Unlock()
+
+    // Month-end postings are dated the last day of the month but can be recorded after later
+    // transactions, so the ledger is put in date order and balances are worked out from the
+    // amounts rather than taken from each entry's BalanceAfter
+    sort.SliceStable(all, func(i, j int) bool { return all[i].Timestamp.Before(all[j].Timestamp) })
+
+    opening := NewMoney(0, currency)
+    period := make([]Transaction, 0)
+    for _, t := range all {
+        switch {
+        case !from.IsZero() && t.Timestamp.Before(from):
+            opening, _ = opening.Add(t.signedAmount())
+        case to.IsZero() || !t.Timestamp.After(to):
+            period = append(period, t)
+        }
+    }
+
+    closing := opening
+    balances := make([]Money, len(period))
+    debits, credits := NewMoney(0, currency), NewMoney(0, currency)
+    for i, t := range period {
+        closing, _ = closing.Add(t.signedAmount())
+        balances[i] = closing
+        if t.IsCredit() {
+            credits, _ = credits.Add(t.Amount)
+        } else {
+            debits, _ = debits.Add(t.Amount)
+        }
+    }
+
+    fmt.Fprintf(w, "\nStatement for Account %d (%s)\n", account.ID, account.Name)
+    fmt.Fprintf(w, "Period: %s to %s\n", statementDate(from, "beginning"), statementDate(to, "today"))
+    fmt.Fprintf(w, "Opening balance: %v\n", opening)
+
+    tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(tw, "ID\tDATE\tTYPE\tDESCRIPTION\tDEBIT\tCREDIT\tBALANCE")
+    for i, t := range period {
+        debit, credit := "", ""
+        if t.IsCredit() {
+            credit = t.Amount.String()
+        } else {
+            debit = t.Amount.String()
+        }
+        fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%v\n",
+            t.ID, t.Timestamp.Format("2006-01-02 15:04:05"), t.Type, t.Description, debit, credit, balances[i])
+    }
+    if err := tw.Flush(); err != nil {
+        return err
+    }
+
+    _, err = fmt.Fprintf(w, "Total debits: %v  Total credits: %v\nClosing balance: %v\n", debits, credits, closing)
+    return err
+}
+
+// statementDate formats one end of a statement period, using label for an open end
+func statementDate(t time.Time, label string) string {
+    if t.IsZero() {
+        return label
+    }
+    return t.Format("2006-01-02")
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "strings"
+    "testing"
+    "time"
+)
+
+// statementDay returns a time on the given day of March 2024
+func statementDay(day int) time.Time {
+    return time.Date(2024, time.March, day, 10, 0, 0, 0, time.Local)
+}
+
+// newStatementTestBank returns a bank whose account 1 took a Rs. 1000 deposit on 1 March, a
+// Rs. 200 withdrawal on 5 March, a Rs. 300 transfer to account 2 on 10 March and a Rs. 50
+// deposit on 20 March
+func newStatementTestBank(t *testing.T) *BankSystem {
+    t.Helper()
+    bs := newTestBank(t, 100000, 0)
+    if _, err := bs.Withdraw(1, INR(20000)); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := bs.Transfer(1, 2, INR(30000)); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := bs.Deposit(1, INR(5000)); err != nil {
+        t.Fatal(err)
+    }
+
+    account, err := bs.GetAccount(1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    account.mu.Lock()
+    defer account.mu.Unlock()
+    for i, day := range []int{1, 5, 10, 20} {
+        account.Transactions[i].Timestamp = statementDay(day)
+    }
+    return bs
+}
+
+// transactionAmounts lists the amounts of transactions in paise
+func transactionAmounts(transactions []Transaction) string {
+    amounts := make([]int64, len(transactions))
+    for i, t := range transactions {
+        amounts[i] = t.Amount.Minor
+    }
+    return fmt.Sprint(amounts)
+}
+
+func TestTransactionFilter(t *testing.T) {
+    bs := newStatementTestBank(t)
+
+    tests := []struct {
+        name   string
+        filter TransactionFilter
+        want   string
+    }{
+        {"everything", TransactionFilter{}, "[100000 20000 30000 5000]"},
+        {"from a day with a transaction", TransactionFilter{From: statementDay(5)}, "[20000 30000 5000]"},
+        {"up to a day with a transaction", TransactionFilter{To: statementDay(10)}, "[100000 20000 30000]"},
+        {"date range", TransactionFilter{From: statementDay(2), To: statementDay(15)}, "[20000 30000]"},
+        {"empty date range", TransactionFilter{From: statementDay(11), To: statementDay(19)}, "[]"},
+        {"type in any case", TransactionFilter{Types: []string{"deposit"}}, "[100000 5000]"},
+        {"several types", TransactionFilter{Types: []string{WITHDRAW_TYPE, TRANSFER_OUT_TYPE}}, "[20000 30000]"},
+        {"minimum amount", TransactionFilter{MinAmount: INR(20000)}, "[100000 20000 30000]"},
+        {"maximum amount", TransactionFilter{MaxAmount: INR(20000)}, "[20000 5000]"},
+        {"amount range", TransactionFilter{MinAmount: INR(10000), MaxAmount: INR(50000)}, "[20000 30000]"},
+        {"type and amount", TransactionFilter{Types: []string{DEPOSIT_TYPE}, MinAmount: INR(10000)}, "[100000]"},
+    }
+
+    for _, tt := range tests {
+        got, err := bs.Transactions(1, tt.filter)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if amounts := transactionAmounts(got); amounts != tt.want {
+            t.Errorf("%s: got %s, want %s", tt.name, amounts, tt.want)
+        }
+    }
+
+    if _, err := bs.Transactions(9, TransactionFilter{}); !errors.Is(err, ErrAccountNotFound) {
+        t.Errorf("Transactions of an unknown account = %v, want ErrAccountNotFound", err)
+    }
+}
+
+func TestWriteStatement(t *testing.T) {
+    bs := newStatementTestBank(t)
+
+    tests := []struct {
+        name     string
+        from, to time.Time
+        want     []string
+        wantNot  []string
+    }{
+        {
+            "whole history", time.Time{}, time.Time{},
+            []string{"Period: beginning to today", "Opening balance: Rs. 0.00", "2024-03-01", "2024-03-20",
+                "Total debits: Rs. 500.00  Total credits: Rs. 1050.00", "Closing balance: Rs. 550.00"},
+            nil,
+        },
+        {
+            "period", statementDay(2), statementDay(15),
+            []string{"Period: 2024-03-02 to 2024-03-15", "Opening balance: Rs. 1000.00", "Transfer to account 2",
+                "Total debits: Rs. 500.00  Total credits: Rs. 0.00", "Closing balance: Rs. 500.00"},
+            []string{"2024-03-01", "2024-03-20"},
+        },
+        {
+            "nothing in the period", statementDay(11), statementDay(19),
+            []string{"Opening balance: Rs. 500.00", "Total debits: Rs. 0.00  Total credits: Rs. 0.00", "Closing balance: Rs. 500.00"},
+            []string{"TRANSFER_OUT"},
+        },
+    }
+
+    for _, tt := range tests {
+        var out bytes.Buffer
+        if err := bs.WriteStatement(&out, 1, tt.from, tt.to); err != nil {
+            t.Fatal(err)
+        }
+        for _, want := range tt.want {
+            if !strings.Contains(out.String(), want) {
+                t.Errorf("%s: statement\n%s\nwant it to contain %q", tt.name, out.String(), want)
+            }
+        }
+        for _, unwanted := range tt.wantNot {
+            if strings.Contains(out.String(), unwanted) {
+                t.Errorf("%s: statement\n%s\nshould not contain %q", tt.name, out.String(), unwanted)
+            }
+        }
+    }
+}
+
+func TestWriteStatementBackdatedInterest(t *testing.T) {
+    bs := newStatementTestBank(t)
+    account, err := bs.GetAccount(1)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // An April deposit is recorded before March's interest, which end of day posts late
+    // but dates to the end of March with March's closing balance
+    depositOn(t, bs, account, INR(10000), time.Date(2024, time.April, 2, 10, 0, 0, 0, time.Local))
+    bs.mu.RLock()
+    account.mu.Lock()
+    interest := postRecord(JournalEntry{Description: "Month-end processing for March 2024", Postings: transfer(INTEREST_EXPENSE_LEDGER, customerLedger(1), INR(500))},
+        walEntry{AccountID: 1, Transaction: Transaction{Type: INTEREST_TYPE, Amount: INR(500), BalanceAfter: INR(55500), Description: "Interest credited"}})
+    interest.Timestamp = time.Date(2024, time.March, 31, 23, 59, 59, 0, time.Local)
+    err = bs.commit(interest)
+    account.mu.Unlock()
+    bs.mu.RUnlock()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    tests := []struct {
+        name     string
+        from, to time.Time
+        want     []string
+    }{
+        {"whole history", time.Time{}, time.Time{}, []string{"Total credits: Rs. 1155.00", "Closing balance: Rs. 655.00"}},
+        {"march", statementDay(1), statementDay(31).Add(14 * time.Hour), []string{"Interest credited", "Closing balance: Rs. 555.00"}},
+        {"april", time.Date(2024, time.April, 1, 0, 0, 0, 0, time.Local), time.Time{}, []string{"Opening balance: Rs. 555.00", "Closing balance: Rs. 655.00"}},
+    }
+
+    for _, tt := range tests {
+        var out bytes.Buffer
+        if err := bs.WriteStatement(&out, 1, tt.from, tt.to); err != nil {
+            t.Fatal(err)
+        }
+        for _, want := range tt.want {
+            if !strings.Contains(out.String(), want) {
+                t.Errorf("%s: statement\n%s\nwant it to contain %q", tt.name, out.String(), want)
+            }
+        }
+    }
+
+    // The interest is listed before the April deposit, with the running balance on each line
+    var out bytes.Buffer
+    if err := bs.WriteStatement(&out, 1, time.Time{}, time.Time{}); err != nil {
+        t.Fatal(err)
+    }
+    printed := out.String()
+    interestAt, depositAt := strings.Index(printed, "INTEREST"), strings.Index(printed, "2024-04-02")
+    if interestAt < 0 || depositAt < 0 || interestAt > depositAt {
+        t.Errorf("statement\n%s\nwant the March interest before the April deposit", printed)
+    }
+    for _, line := range strings.Split(printed, "\n") {
+        if strings.Contains(line, "2024-04-02") && !strings.HasSuffix(strings.TrimSpace(line), "Rs. 655.00") {
+            t.Errorf("April deposit line %q, want a balance of Rs. 655.00", line)
+        }
+    }
+}
+
+func TestWriteMiniStatement(t *testing.T) {
+    bs := newStatementTestBank(t)
+
+    var out bytes.Buffer
+    if err := bs.WriteMiniStatement(&out, 1, 2); err != nil {
+        t.Fatal(err)
+    }
+    printed := out.String()
+    deposit, transfer := strings.Index(printed, "+Rs. 50.00"), strings.Index(printed, "-Rs. 300.00")
+    if deposit < 0 || transfer < 0 || deposit > transfer {
+        t.Errorf("mini statement\n%s\nwant the last deposit and then the transfer, newest first", printed)
+    }
+    if strings.Contains(printed, "Rs. 200.00") || !strings.Contains(printed, "Available balance: Rs. 550.00") {
+        t.Errorf("mini statement\n%s\nwant only the last 2 transactions and a balance of Rs. 550.00", printed)
+    }
+}
@@ -0,0 +1,509 @@
+package main
+
+import (
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "hash/crc32"
+    "io"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Write-ahead log record operations
+const (
+    WAL_CREATE_ACCOUNT = "CREATE_ACCOUNT"
+    WAL_POST           = "POST"
+    WAL_CLOSE_ACCOUNT  = "CLOSE_ACCOUNT"
+    WAL_ACCRUE         = "ACCRUE"
+    WAL_SET_LIMITS     = "SET_LIMITS"
+    WAL_FLAG           = "FLAG"
+    WAL_REVIEW_FLAG    = "REVIEW_FLAG"
+)
+
+// File names inside the data directory
+const (
+    WAL_FILE      = "bank.wal"
+    SNAPSHOT_FILE = "snapshot.json"
+)
+
+// Number of log records written between snapshots unless configured otherwise
+const DEFAULT_SNAPSHOT_EVERY = 100
+
+// Each record is framed by a header holding the payload length and its CRC-32
+const walHeaderSize = 8
+
+// walEntry is one transaction posted to one account
+type walEntry struct {
+    AccountID   int
+    Transaction Transaction
+}
+
+// walRecord is one atomic change to the bank. Every entry of a record is replayed, or none is
+type walRecord struct {
+    LSN       uint64
+    Op        string
+    Timestamp time.Time
+    AccountID int           `json:",omitempty"`
+    Name      string        `json:",omitempty"`
+    Terms     *AccountTerms `json:",omitempty"`
+    // Journal moves the money; Entries are the matching lines on each account's ledger
+    Journal   *JournalEntry `json:",omitempty"`
+    Entries   []walEntry    `json:",omitempty"`
+    // Accrual replaces the account's interest accrual state
+    Accrual   *InterestAccrual `json:",omitempty"`
+    // Limits replaces the account's withdrawal limits
+    Limits    *WithdrawalLimits `json:",omitempty"`
+    // Flag adds a withdrawal to the review queue, or for WAL_REVIEW_FLAG records the decision on one
+    Flag      *FlaggedTransaction `json:",omitempty"`
+}
+
+// postRecord returns a record posting a journal entry and the account ledger lines it produces
+func postRecord(journal JournalEntry, entries ...walEntry) walRecord {
+    return walRecord{Op: WAL_POST, Journal: &journal, Entries: entries}
+}
+
+// WriteAheadLog is an append-only file of records. A record is only acknowledged once it has
+// been synced to disk, so every acknowledged change survives a crash
+type WriteAheadLog struct {
+    mu              sync.Mutex
+    file            *os.File
+    size            int64
+    lastLSN         uint64
+    sinceCheckpoint int
+}
+
+// RecoveryReport describes how a bank was rebuilt from its data directory
+type RecoveryReport struct {
+    SnapshotLSN     uint64
+    RecordsReplayed int
+    // TornBytes is the length of an incomplete last record that was discarded
+    TornBytes int64
+}
+
+// snapshot is the full state of the bank as of a log sequence number
+type snapshot struct {
+    LSN               uint64
+    LastTransactionID int64
+    LastTransferID    int64
+    LastJournalID     int64
+    LastFlagID        int64
+    Accounts          []snapshotAccount
+    Journal           []JournalEntry
+    Flags             []FlaggedTransaction
+}
+
+// snapshotAccount is the saved state of one account
+type snapshotAccount struct {
+    ID           int
+    Name         string
+    Terms        AccountTerms
+    OpenedAt     time.Time
+    Accrual      InterestAccrual
+    Limits       WithdrawalLimits
+    Transactions []Transaction
+    Closed       bool
+}
+
+// openWriteAheadLog opens or creates the log at path and reads back every complete record.
+// An incomplete last record, left by a crash part-way through a write, is cut off and its length
+// returned. Damage anywhere before the last record is reported as an error
+func openWriteAheadLog(path string) (*WriteAheadLog, []walRecord, int64, error) {
+    file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+    if err != nil {
+        return nil, nil, 0, err
+    }
+
+    data, err := io.ReadAll(file)
+    if err != nil {
+        file.Close()
+        return nil, nil, 0, err
+    }
+
+    records, valid, err := parseWAL(data)
+    if err != nil {
+        file.Close()
+        return nil, nil, 0, fmt.Errorf("%s: %w", path, err)
+    }
+
+    torn := int64(len(data)) - valid
+    if torn > 0 {
+        if err := file.Truncate(valid); err != nil {
+            file.Close()
+            return nil, nil, 0, err
+        }
+        if err := file.Sync(); err != nil {
+            file.Close()
+            return nil, nil, 0, err
+        }
+    }
+    if _, err := file.Seek(valid, io.SeekStart); err != nil {
+        file.Close()
+        return nil, nil, 0, err
+    }
+
+    wal := &WriteAheadLog{file: file, size: valid, sinceCheckpoint: len(records)}
+    if len(records) > 0 {
+        wal.lastLSN = records[len(records)-1].LSN
+    }
+    return wal, records, torn, nil
+}
+
+// parseWAL decodes the records in data and returns them with the length of the valid prefix.
+// A record that is short or fails its checksum is torn if nothing follows it. Only the last
+// record can be torn, so one whose length runs past a complete record further on is corrupt
+func parseWAL(data []byte) ([]walRecord, int64, error) {
+    records := make([]walRecord, 0)
+    var offset int64
+    var lastLSN uint64
+
+    for offset < int64(len(data)) {
+        rest := data[offset:]
+        if len(rest) < walHeaderSize {
+            return records, offset, nil
+        }
+        length := int64(binary.BigEndian.Uint32(rest[0:4]))
+        checksum := binary.BigEndian.Uint32(rest[4:8])
+        end := walHeaderSize + length
+        if end > int64(len(rest)) {
+            if next := nextFrame(rest[walHeaderSize:]); next >= 0 {
+                return nil, 0, fmt.Errorf("corrupt record length at offset %d: a record follows at offset %d",
+                    offset, offset+walHeaderSize+int64(next))
+            }
+            return records, offset, nil
+        }
+
+        payload := rest[walHeaderSize:end]
+        var record walRecord
+        err := json.Unmarshal(payload, &record)
+        if crc32.ChecksumIEEE(payload) != checksum || err != nil {
+            if end == int64(len(rest)) {
+                return records, offset, nil
+            }
+            return nil, 0, fmt.Errorf("corrupt record at offset %d", offset)
+        }
+        if record.LSN <= lastLSN {
+            return nil, 0, fmt.Errorf("record at offset %d is out of sequence (LSN %d after %d)", offset, record.LSN, lastLSN)
+        }
+
+        records = append(records, record)
+        lastLSN = record.LSN
+        offset += end
+    }
+    return records, offset, nil
+}
+
+// nextFrame returns the position of the first complete record in data with a valid checksum,
+// or -1 if there is none. Records hold JSON, so only positions where an object starts are tried
+func nextFrame(data []byte) int {
+    for i := 0; i+walHeaderSize < len(data); i++ {
+        if data[i+walHeaderSize] != '{' {
+            continue
+        }
+        end := walHeaderSize + int64(binary.BigEndian.Uint32(data[i:i+4]))
+        if end > int64(len(data)-i) {
+            continue
+        }
+        if crc32.ChecksumIEEE(data[i+walHeaderSize:i+int(end)]) == binary.BigEndian.Uint32(data[i+4:i+8]) {
+            return i
+        }
+    }
+    return -1
+}
+
+// Append gives record the next sequence number, lets stamp give it any other IDs while the
+// log is held so they are in sequence order too, and writes it durably. If the write fails
+// the log is cut back so no partial record is left behind
+func (w *WriteAheadLog) Append(record *walRecord, stamp func(*walRecord)) error {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+
+    record.LSN = w.lastLSN + 1
+    if stamp != nil {
+        stamp(record)
+    }
+    payload, err := json.Marshal(record)
+    if err != nil {
+        return err
+    }
+
+    frame := make([]byte, walHeaderSize+len(payload))
+    binary.BigEndian.PutUint32(frame[0:4], uint32(len(payload)))
+    binary.BigEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(payload))
+    copy(frame[walHeaderSize:], payload)
+
+    if _, err := w.file.Write(frame); err != nil {
+        w.rewind()
+        return err
+    }
+    if err := w.file.Sync(); err != nil {
+        w.rewind()
+        return err
+    }
+
+    w.size += int64(len(frame))
+    w.lastLSN = record.LSN
+    w.sinceCheckpoint++
+    return nil
+}
+
+// rewind cuts the file back to the end of the last complete record. Callers must hold w.mu
+func (w *WriteAheadLog) rewind() {
+    w.file.Truncate(w.size)
+    w.file.Seek(w.size, io.SeekStart)
+}
+
+// LastLSN returns the sequence number of the last record written
+func (w *WriteAheadLog) LastLSN() uint64 {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    return w.lastLSN
+}
+
+// reset empties the log once its records are covered by a snapshot. Sequence numbers carry on
+func (w *WriteAheadLog) reset() error {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+
+    if err := w.file.Truncate(0); err != nil {
+        return err
+    }
+    if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+        return err
+    }
+    if err := w.file.Sync(); err != nil {
+        return err
+    }
+    w.size = 0
+    w.sinceCheckpoint = 0
+    return nil
+}
+
+// Close closes the log file
+func (w *WriteAheadLog) Close() error {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    return w.file.Close()
+}
+
+// OpenBankSystem rebuilds a bank from the snapshot and write-ahead log in dir, creating
+// the directory if needed. From then on every change is logged before it is applied, and
+// a snapshot is taken after every snapshotEvery records
+func OpenBankSystem(dir string, snapshotEvery int) (*BankSystem, *RecoveryReport, error) {
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return nil, nil, err
+    }
+
+    bs := NewBankSystem()
+    bs.dataDir = dir
+    bs.snapshotEvery = snapshotEvery
+    if bs.snapshotEvery <= 0 {
+        bs.snapshotEvery = DEFAULT_SNAPSHOT_EVERY
+    }
+    report := &RecoveryReport{}
+
+    snap, err := readSnapshot(filepath.Join(dir, SNAPSHOT_FILE))
+    if err != nil {
+        return nil, nil, err
+    }
+    if snap != nil {
+        report.SnapshotLSN = snap.LSN
+        bs.lastTransactionID.Store(snap.LastTransactionID)
+        bs.lastTransferID.Store(snap.LastTransferID)
+        bs.lastJournalID.Store(snap.LastJournalID)
+        bs.lastFlagID.Store(snap.LastFlagID)
+        bs.flags = append(bs.flags, snap.Flags...)
+        for _, saved := range snap.Accounts {
+            bs.accounts = append(bs.accounts, &Account{
+                ID:           saved.ID,
+                Name:         saved.Name,
+                Terms:        saved.Terms,
+                OpenedAt:     saved.OpenedAt,
+                Accrual:      saved.Accrual,
+                Limits:       saved.Limits,
+                Transactions: append(make([]Transaction, 0), saved.Transactions...),
+                Closed:       saved.Closed,
+            })
+        }
+        for _, entry := range snap.Journal {
+            if err := bs.journal.Post(entry); err != nil {
+                return nil, nil, fmt.Errorf("%s: %w", SNAPSHOT_FILE, err)
+            }
+        }
+    }
+
+    wal, records, torn, err := openWriteAheadLog(filepath.Join(dir, WAL_FILE))
+    if err != nil {
+        return nil, nil, err
+    }
+    report.TornBytes = torn
+
+    for _, record := range records {
+        // Records already in the snapshot are left over from a checkpoint interrupted
+        // between writing the snapshot and emptying the log
+        if record.LSN <= report.SnapshotLSN {
+            continue
+        }
+        if err := bs.apply(record); err != nil {
+            wal.Close()
+            return nil, nil, fmt.Errorf("replaying record %d: %w", record.LSN, err)
+        }
+        bs.restoreCounters(record)
+        report.RecordsReplayed++
+    }
+
+    if wal.lastLSN < report.SnapshotLSN {
+        wal.lastLSN = report.SnapshotLSN
+    }
+    bs.wal = wal
+    return bs, report, nil
+}
+
+// restoreCounters advances the ID counters past those used by a replayed record
+func (bs *BankSystem) restoreCounters(record walRecord) {
+    if record.Journal != nil && record.Journal.ID > bs.lastJournalID.Load() {
+        bs.lastJournalID.Store(record.Journal.ID)
+    }
+    if record.Flag != nil && record.Flag.ID > bs.lastFlagID.Load() {
+        bs.lastFlagID.Store(record.Flag.ID)
+    }
+    for _, entry := range record.Entries {
+        if entry.Transaction.ID > bs.lastTransactionID.Load() {
+            bs.lastTransactionID.Store(entry.Transaction.ID)
+        }
+        if number, ok := strings.CutPrefix(entry.Transaction.Reference, "TRF"); ok {
+            if id, err := strconv.ParseInt(number, 10, 64); err == nil && id > bs.lastTransferID.Load() {
+                bs.lastTransferID.Store(id)
+            }
+        }
+    }
+}
+
+// readSnapshot loads the snapshot at path, or returns nil if none has been taken yet
+func readSnapshot(path string) (*snapshot, error) {
+    data, err := os.ReadFile(path)
+    if errors.Is(err, os.ErrNotExist) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    var snap snapshot
+    if err := json.Unmarshal(data, &snap); err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    return &snap, nil
+}
+
+// Checkpoint saves a snapshot of every account and empties the write-ahead log. The snapshot
+// is written to a temporary file and renamed into place, so a crash leaves either the old
+// snapshot with the full log or the new one
+func (bs *BankSystem) Checkpoint() error {
+    if bs.wal == nil {
+        return nil
+    }
+
+    // Every change holds bs.mu for reading, so holding it for writing stops them all and
+    // the snapshot matches the log exactly
+    bs.mu.Lock()
+    defer bs.mu.Unlock()
+
+    snap := snapshot{
+        LSN:               bs.wal.LastLSN(),
+        LastTransactionID: bs.lastTransactionID.Load(),
+        LastTransferID:    bs.lastTransferID.Load(),
+        LastJournalID:     bs.lastJournalID.Load(),
+        LastFlagID:        bs.lastFlagID.Load(),
+        Accounts:          make([]snapshotAccount, 0, len(bs.accounts)),
+        Journal:           bs.journal.Entries(),
+        Flags:             bs.FlaggedTransactions(""),
+    }
+    for _, acc := range bs.accounts {
+        snap.Accounts = append(snap.Accounts, snapshotAccount{
+            ID:           acc.ID,
+            Name:         acc.Name,
+            Terms:        acc.Terms,
+            OpenedAt:     acc.OpenedAt,
+            Accrual:      acc.Accrual,
+            Limits:       acc.Limits,
+            Transactions: acc.Transactions,
+            Closed:       acc.Closed,
+        })
+    }
+
+    data, err := json.MarshalIndent(snap, "", "  ")
+    if err != nil {
+        return err
+    }
+    if err := writeFileSync(filepath.Join(bs.dataDir, SNAPSHOT_FILE), data); err != nil {
+        return err
+    }
+    return bs.wal.reset()
+}
+
+// maybeCheckpoint takes a snapshot once enough records have been logged since the last one.
+// It is deferred by every operation, so it runs after the operation has released its locks
+func (bs *BankSystem) maybeCheckpoint() {
+    if bs.wal == nil {
+        return
+    }
+
+    bs.wal.mu.Lock()
+    due := bs.wal.sinceCheckpoint >= bs.snapshotEvery
+    bs.wal.mu.Unlock()
+
+    if due {
+        if err := bs.Checkpoint(); err != nil {
+            fmt.Fprintf(os.Stderr, "Warning: snapshot failed: %v\n", err)
+        }
+    }
+}
+
+// Close takes a final snapshot and closes the write-ahead log
+func (bs *BankSystem) Close() error {
+    if bs.wal == nil {
+        return nil
+    }
+    if err := bs.Checkpoint(); err != nil {
+        bs.wal.Close()
+        return err
+    }
+    return bs.wal.Close()
+}
+
+// writeFileSync replaces the file at path with data, syncing it and its directory so
+// the new contents survive a crash
+func writeFileSync(path string, data []byte) error {
+    temp := path + ".tmp"
+    file, err := os.Create(temp)
+    if err != nil {
+        return err
+    }
+    if _, err := file.Write(data); err != nil {
+        file.Close()
+        return err
+    }
+    if err := file.Sync(); err != nil {
+        file.Close()
+        return err
+    }
+    if err := file.Close(); err != nil {
+        return err
+    }
+    if err := os.Rename(temp, path); err != nil {
+        return err
+    }
+
+    dir, err := os.Open(filepath.Dir(path))
+    if err != nil {
+        return err
+    }
+    defer dir.Close()
+    return dir.Sync()
+}